@@ -0,0 +1,366 @@
+package fingerprint
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// autoAttachTypes типы целей, к которым применяется fingerprint в режиме auto-attach
+var autoAttachTypes = map[string]bool{
+	"page":           true, // новые вкладки и попапы (window.open)
+	"iframe":         true, // out-of-process iframe
+	"worker":         true, // Dedicated Worker
+	"shared_worker":  true,
+	"service_worker": true,
+}
+
+// Фильтры Target.setAutoAttach: вкладка подключается к своим
+// out-of-process iframe и Dedicated Worker'ам, браузер - к новым
+// вкладкам, попапам, Shared и Service Worker'ам.
+// Подключенные цели ждут Runtime.runIfWaitingForDebugger, поэтому
+// фильтры не должны пропускать цели, которые некому снять с паузы.
+var (
+	sessionAutoAttachFilter = target.Filter{{Type: "iframe"}, {Type: "worker"}}
+	browserAutoAttachFilter = target.Filter{{Type: "page"}, {Type: "shared_worker"}, {Type: "service_worker"}}
+)
+
+// autoAttachTimeout ограничивает команды, которые StopAutoAttach
+// отправляет после отмены контекстов
+const autoAttachTimeout = 5 * time.Second
+
+// isWorkerTarget сообщает, является ли цель воркером
+func isWorkerTarget(targetType string) bool {
+	return targetType == "worker" || targetType == "shared_worker" || targetType == "service_worker"
+}
+
+// autoAttachRegistry общее для всех инжекторов состояние auto-attach.
+// Auto-attach на уровне браузера ставит на паузу каждую новую вкладку
+// браузера, поэтому каждую из них снимает с паузы ровно один инжектор:
+// владелец или, если владельца нет, первый получивший событие.
+var autoAttachRegistry = struct {
+	sync.Mutex
+	// targets обработанные цели и их владельцы; nil - цель без владельца
+	targets map[target.ID]*Injector
+	// contexts контексты браузера и инжекторы, первыми включившие в них режим
+	contexts map[cdp.BrowserContextID]*Injector
+	// browsers число инжекторов, включивших auto-attach в браузере
+	browsers map[*chromedp.Browser]int
+}{
+	targets:  make(map[target.ID]*Injector),
+	contexts: make(map[cdp.BrowserContextID]*Injector),
+	browsers: make(map[*chromedp.Browser]int),
+}
+
+// EnableAutoAttach включает применение fingerprint ко всем целям, которые
+// порождает текущая вкладка: новым вкладкам, попапам, out-of-process iframe,
+// Dedicated, Shared и Service Worker'ам.
+// Новые цели подключаются на паузе (waitForDebuggerOnStart): переопределения
+// CDP и скрипт применяются до первого документа и первых запросов цели,
+// после чего она продолжает работу. Попап принадлежит инжектору вкладки,
+// которая его открыла, новая вкладка, Shared и Service Worker - инжектору,
+// первым включившему режим в их контексте браузера; чужие цели, в том числе
+// из других контекстов браузера, только снимаются с паузы.
+// Для вложенных вкладок режим включается рекурсивно.
+// Остановить прослушивание можно через StopAutoAttach.
+func (inj *Injector) EnableAutoAttach(ctx context.Context) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		c := chromedp.FromContext(ctx)
+		if c == nil || c.Target == nil {
+			return chromedp.ErrInvalidContext
+		}
+		info, err := target.GetTargetInfo().Do(ctx)
+		if err != nil {
+			return err
+		}
+		inj.claimRoot(info)
+
+		if err := inj.enableSessionAutoAttach(ctx); err != nil {
+			return err
+		}
+		return inj.enableBrowserAutoAttach(ctx, c.Browser)
+	})
+}
+
+// enableSessionAutoAttach подключается к out-of-process iframe и
+// Dedicated Worker'ам текущей вкладки
+func (inj *Injector) enableSessionAutoAttach(ctx context.Context) error {
+	lctx, cancel := context.WithCancel(ctx)
+	inj.addStop(cancel)
+	c := chromedp.FromContext(ctx)
+	go func() {
+		<-lctx.Done()
+		// Иначе новые iframe остались бы на паузе. Настройка chromedp
+		// по умолчанию: без паузы и без фильтра.
+		sctx, stop := context.WithTimeout(context.Background(), autoAttachTimeout)
+		defer stop()
+		_ = target.SetAutoAttach(true, false).WithFlatten(true).Do(cdp.WithExecutor(sctx, c.Target))
+	}()
+
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		e, ok := ev.(*target.EventAttachedToTarget)
+		if ok && autoAttachTypes[e.TargetInfo.Type] && inj.claimChild(e.TargetInfo.TargetID) {
+			// Слушатель не должен блокировать обработку событий
+			go inj.attachTarget(ctx, e.TargetInfo, e.WaitingForDebugger)
+		}
+	})
+
+	return target.SetAutoAttach(true, true).
+		WithFlatten(true).
+		WithFilter(sessionAutoAttachFilter).
+		Do(ctx)
+}
+
+// enableBrowserAutoAttach подключается к новым вкладкам, попапам, Shared
+// и Service Worker'ам. Target.setAutoAttach текущей вкладки не сообщает
+// о них, поэтому режим включается для всего браузера, один раз для
+// каждого инжектора.
+func (inj *Injector) enableBrowserAutoAttach(ctx context.Context, browser *chromedp.Browser) error {
+	inj.mu.Lock()
+	if inj.browsers[browser] {
+		inj.mu.Unlock()
+		return nil
+	}
+	if inj.browsers == nil {
+		inj.browsers = make(map[*chromedp.Browser]bool)
+	}
+	inj.browsers[browser] = true
+	inj.mu.Unlock()
+
+	bctx := cdp.WithExecutor(ctx, browser)
+	lctx, cancel := context.WithCancel(ctx)
+	inj.addStop(cancel)
+	autoAttachRegistry.Lock()
+	autoAttachRegistry.browsers[browser]++
+	autoAttachRegistry.Unlock()
+	go func() {
+		<-lctx.Done()
+		inj.mu.Lock()
+		delete(inj.browsers, browser)
+		inj.mu.Unlock()
+		// Иначе цели инжектора и новые вкладки остались бы на паузе
+		if inj.releaseBrowser(browser) {
+			sctx, stop := context.WithTimeout(context.Background(), autoAttachTimeout)
+			defer stop()
+			_ = target.SetAutoAttach(false, false).Do(cdp.WithExecutor(sctx, browser))
+		}
+	}()
+
+	chromedp.ListenBrowser(lctx, func(ev interface{}) {
+		// Без паузы сообщается о целях, к которым подключились явно,
+		// и о вкладках, открытых до включения режима
+		e, ok := ev.(*target.EventAttachedToTarget)
+		if !ok || !e.WaitingForDebugger || !autoAttachTypes[e.TargetInfo.Type] {
+			return
+		}
+		owned, ok := inj.claimTarget(e.TargetInfo)
+		switch {
+		case owned:
+			go inj.attachTarget(ctx, e.TargetInfo, true)
+		case ok:
+			go func() {
+				if err := resumeTarget(bctx, e.TargetInfo.TargetID); err != nil {
+					inj.errorf("failed to resume %s target %s: %v", e.TargetInfo.Type, e.TargetInfo.TargetID, err)
+				}
+			}()
+		}
+	})
+
+	return target.SetAutoAttach(true, true).
+		WithFlatten(true).
+		WithFilter(browserAutoAttachFilter).
+		Do(bctx)
+}
+
+// StopAutoAttach прекращает применение fingerprint к новым целям и
+// возвращает вкладкам и браузеру настройки auto-attach chromedp.
+// То же происходит при отмене контекста, в котором был включен режим.
+// Уже открытые вкладки и iframe сохраняют примененный fingerprint.
+func (inj *Injector) StopAutoAttach() {
+	inj.mu.Lock()
+	stops := inj.stops
+	inj.stops = nil
+	inj.mu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// releaseBrowser освобождает цели и контексты браузера, закрепленные за
+// инжектором, чтобы их снимали с паузы другие инжекторы. Возвращает true,
+// если в браузере не осталось инжекторов с auto-attach.
+func (inj *Injector) releaseBrowser(browser *chromedp.Browser) bool {
+	autoAttachRegistry.Lock()
+	defer autoAttachRegistry.Unlock()
+
+	for id, owner := range autoAttachRegistry.targets {
+		if owner == inj {
+			delete(autoAttachRegistry.targets, id)
+		}
+	}
+	for id, owner := range autoAttachRegistry.contexts {
+		if owner == inj {
+			delete(autoAttachRegistry.contexts, id)
+		}
+	}
+	autoAttachRegistry.browsers[browser]--
+	if autoAttachRegistry.browsers[browser] > 0 {
+		return false
+	}
+	delete(autoAttachRegistry.browsers, browser)
+	return true
+}
+
+// addStop добавляет функцию, которую выполнит StopAutoAttach
+func (inj *Injector) addStop(stop context.CancelFunc) {
+	inj.mu.Lock()
+	inj.stops = append(inj.stops, stop)
+	inj.mu.Unlock()
+}
+
+// claimRoot отмечает вкладку, в которой включен режим, как свою и
+// закрепляет за инжектором ее контекст браузера, если он свободен
+func (inj *Injector) claimRoot(info *target.Info) {
+	autoAttachRegistry.Lock()
+	defer autoAttachRegistry.Unlock()
+
+	if autoAttachRegistry.targets[info.TargetID] == nil {
+		autoAttachRegistry.targets[info.TargetID] = inj
+	}
+	if autoAttachRegistry.contexts[info.BrowserContextID] == nil {
+		autoAttachRegistry.contexts[info.BrowserContextID] = inj
+	}
+}
+
+// claimChild отмечает дочернюю цель вкладки инжектора как обработанную.
+// Возвращает false, если цель уже была обработана.
+func (inj *Injector) claimChild(id target.ID) bool {
+	autoAttachRegistry.Lock()
+	defer autoAttachRegistry.Unlock()
+
+	if _, ok := autoAttachRegistry.targets[id]; ok {
+		return false
+	}
+	autoAttachRegistry.targets[id] = inj
+	return true
+}
+
+// claimTarget отмечает новую цель браузера как обработанную и определяет
+// ее владельца: попап принадлежит инжектору вкладки-opener, остальные
+// цели, в том числе Shared и Service Worker'ы, - инжектору контекста
+// браузера. Возвращает ok=false, если цель
+// уже обработана или принадлежит другому инжектору, и owned=true, если
+// она принадлежит этому.
+func (inj *Injector) claimTarget(info *target.Info) (owned, ok bool) {
+	autoAttachRegistry.Lock()
+	defer autoAttachRegistry.Unlock()
+
+	if _, seen := autoAttachRegistry.targets[info.TargetID]; seen {
+		return false, false
+	}
+	owner := autoAttachRegistry.targets[info.OpenerID]
+	if owner == nil {
+		owner = autoAttachRegistry.contexts[info.BrowserContextID]
+	}
+	if owner != nil && owner != inj {
+		return false, false
+	}
+	autoAttachRegistry.targets[info.TargetID] = owner
+	return owner == inj, true
+}
+
+// forgetTarget удаляет закрытую цель из списка обработанных
+func (inj *Injector) forgetTarget(id target.ID) {
+	autoAttachRegistry.Lock()
+	delete(autoAttachRegistry.targets, id)
+	autoAttachRegistry.Unlock()
+
+	inj.mu.Lock()
+	delete(inj.scripts, id)
+	inj.mu.Unlock()
+}
+
+// attachTarget подключается к цели отдельной сессией, применяет fingerprint
+// и снимает цель с паузы. Контекст цели живет, пока цель не закрыта или
+// не отменен родительский контекст.
+func (inj *Injector) attachTarget(parent context.Context, info *target.Info, waiting bool) {
+	tctx, cancel := chromedp.NewContext(parent, chromedp.WithTargetID(info.TargetID))
+
+	chromedp.ListenBrowser(tctx, func(ev interface{}) {
+		if e, ok := ev.(*target.EventTargetDestroyed); ok && e.TargetID == info.TargetID {
+			inj.forgetTarget(info.TargetID)
+			go cancel()
+		}
+	})
+
+	if err := chromedp.Run(tctx, inj.applyTarget(info.Type)); err != nil {
+		inj.errorf("failed to apply fingerprint to %s target %s: %v", info.Type, info.TargetID, err)
+	}
+	// Цель снимается с паузы и после ошибки, иначе она не загрузится
+	if waiting {
+		if err := chromedp.Run(tctx, runtime.RunIfWaitingForDebugger()); err != nil {
+			inj.errorf("failed to resume %s target %s: %v", info.Type, info.TargetID, err)
+		}
+	}
+}
+
+// sendMessageToTargetParams параметры Target.sendMessageToTarget,
+// которого нет в cdproto
+type sendMessageToTargetParams struct {
+	Message   string           `json:"message"`
+	SessionID target.SessionID `json:"sessionId"`
+}
+
+// resumeTarget снимает с паузы цель без владельца. chromedp не может
+// отправить команду в сессию auto-attach, а контекст chromedp при отмене
+// закрывает цель, поэтому команда передается через временную сессию
+// без flatten.
+func resumeTarget(ctx context.Context, id target.ID) error {
+	sessionID, err := target.AttachToTarget(id).Do(ctx)
+	if err != nil {
+		return err
+	}
+	err = cdp.Execute(ctx, "Target.sendMessageToTarget", &sendMessageToTargetParams{
+		Message:   `{"id":1,"method":"Runtime.runIfWaitingForDebugger"}`,
+		SessionID: sessionID,
+	}, nil)
+	if detachErr := target.DetachFromTarget().WithSessionID(sessionID).Do(ctx); err == nil {
+		err = detachErr
+	}
+	return err
+}
+
+// applyTarget возвращает действия для подключенной цели.
+// Метрики устройства и touch-эмуляция для iframe наследуются от страницы.
+// Воркер подключается на паузе, поэтому скрипт выполняется до кода
+// верхнего уровня воркера.
+func (inj *Injector) applyTarget(targetType string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if targetType == "page" {
+			return inj.apply(ctx, true)
+		}
+		if isWorkerTarget(targetType) {
+			return inj.InjectWorker(ctx).Do(ctx)
+		}
+
+		if err := inj.SetUserAgentOverride(ctx).Do(ctx); err != nil {
+			return err
+		}
+		if err := inj.SetTimezoneOverride(ctx).Do(ctx); err != nil {
+			return err
+		}
+		if err := inj.SetLocaleOverride(ctx).Do(ctx); err != nil {
+			return err
+		}
+		if err := inj.Inject(ctx).Do(ctx); err != nil {
+			return err
+		}
+		return inj.EnableAutoAttach(ctx).Do(ctx)
+	})
+}
@@ -0,0 +1,57 @@
+package fingerprint
+
+import (
+	"math"
+	"time"
+)
+
+// batteryMinLevel уровень, ниже которого симулированная батарея не
+// разряжается: страница не должна увидеть выключившееся устройство
+const batteryMinLevel = 0.01
+
+// At возвращает состояние батареи через elapsed симулированного времени,
+// как его видит страница. Уровень меняется линейно, чтобы батарея
+// зарядилась за ChargingTime или разрядилась за DischargingTime, и
+// округляется до 0.01, как в Chrome; время пересчитывается от уровня.
+func (b *Battery) At(elapsed time.Duration) Battery {
+	state := *b
+	state.Speed = 0
+	state.StartTime = 0
+	seconds := elapsed.Seconds()
+
+	switch {
+	case b.Charging && b.Level >= 1:
+		state.Level = 1
+		state.ChargingTime = 0
+	case b.Charging && b.ChargingTime > 0:
+		rate := (1 - b.Level) / b.ChargingTime
+		level := math.Min(b.Level+rate*seconds, 1)
+		state.Level = level
+		state.ChargingTime = math.Round((1 - level) / rate)
+	case !b.Charging && b.DischargingTime > 0:
+		rate := b.Level / b.DischargingTime
+		level := math.Max(b.Level-rate*seconds, batteryMinLevel)
+		state.Level = level
+		state.DischargingTime = math.Round(level / rate)
+	}
+	state.Level = math.Round(state.Level*100) / 100
+	return state
+}
+
+// withLevel возвращает копию батареи с другим уровнем. Оставшееся время
+// пересчитывается при той же полной длительности зарядки и разрядки;
+// заряженная батарея на зарядке (компьютер без батареи) не меняется.
+func (b *Battery) withLevel(level float64) *Battery {
+	battery := *b
+	if b.Charging && b.Level >= 1 {
+		return &battery
+	}
+	if b.Charging && b.ChargingTime > 0 {
+		battery.ChargingTime = math.Round(b.ChargingTime / (1 - b.Level) * (1 - level))
+	}
+	if !b.Charging && b.DischargingTime > 0 && b.Level > 0 {
+		battery.DischargingTime = math.Round(b.DischargingTime / b.Level * level)
+	}
+	battery.Level = level
+	return &battery
+}
@@ -0,0 +1,89 @@
+package fingerprint
+
+import (
+	"strconv"
+
+	"github.com/chromedp/cdproto/emulation"
+)
+
+// chromeGreaseChars и chromeGreaseVersions используются Chrome для
+// GREASE-бренда ("Not?A_Brand") в Sec-CH-UA
+var (
+	chromeGreaseChars    = []string{" ", "(", ":", "-", ".", "/", ")", ";", "=", "?", "_"}
+	chromeGreaseVersions = []string{"8", "99", "24"}
+	chromeBrandOrders    = [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+)
+
+// ChromeBrands возвращает бренды Chrome для мажорной и полной версии
+// в том же порядке и с тем же GREASE-брендом, что и сам Chrome
+func ChromeBrands(major int, fullVersion string) (brands, fullVersionList []Brand) {
+	grease := "Not" + chromeGreaseChars[major%len(chromeGreaseChars)] +
+		"A" + chromeGreaseChars[(major+1)%len(chromeGreaseChars)] + "Brand"
+	greaseVersion := chromeGreaseVersions[major%len(chromeGreaseVersions)]
+	version := strconv.Itoa(major)
+
+	brands = make([]Brand, 3)
+	fullVersionList = make([]Brand, 3)
+	order := chromeBrandOrders[major%len(chromeBrandOrders)]
+
+	brands[order[0]] = Brand{Brand: grease, Version: greaseVersion}
+	brands[order[1]] = Brand{Brand: "Chromium", Version: version}
+	brands[order[2]] = Brand{Brand: "Google Chrome", Version: version}
+
+	fullVersionList[order[0]] = Brand{Brand: grease, Version: greaseVersion + ".0.0.0"}
+	fullVersionList[order[1]] = Brand{Brand: "Chromium", Version: fullVersion}
+	fullVersionList[order[2]] = Brand{Brand: "Google Chrome", Version: fullVersion}
+	return brands, fullVersionList
+}
+
+// newChromeClientHints создает Client Hints Chrome
+func newChromeClientHints(major int, fullVersion, platform, platformVersion, architecture, model string, mobile bool) *ClientHints {
+	brands, fullVersionList := ChromeBrands(major, fullVersion)
+
+	bitness := "64"
+	if platform == "Android" {
+		// Chrome на Android не сообщает архитектуру и разрядность
+		bitness = ""
+	}
+
+	return &ClientHints{
+		Brands:          brands,
+		FullVersionList: fullVersionList,
+		Platform:        platform,
+		PlatformVersion: platformVersion,
+		Architecture:    architecture,
+		Bitness:         bitness,
+		Model:           model,
+		Mobile:          mobile,
+	}
+}
+
+// userAgentMetadata конвертирует Client Hints в параметр
+// Emulation.setUserAgentOverride
+func (ch *ClientHints) userAgentMetadata() *emulation.UserAgentMetadata {
+	convert := func(list []Brand) []*emulation.UserAgentBrandVersion {
+		result := make([]*emulation.UserAgentBrandVersion, 0, len(list))
+		for _, b := range list {
+			result = append(result, &emulation.UserAgentBrandVersion{Brand: b.Brand, Version: b.Version})
+		}
+		return result
+	}
+
+	formFactor := "Desktop"
+	if ch.Mobile {
+		formFactor = "Mobile"
+	}
+
+	return &emulation.UserAgentMetadata{
+		Brands:          convert(ch.Brands),
+		FullVersionList: convert(ch.FullVersionList),
+		Platform:        ch.Platform,
+		PlatformVersion: ch.PlatformVersion,
+		Architecture:    ch.Architecture,
+		Model:           ch.Model,
+		Mobile:          ch.Mobile,
+		Bitness:         ch.Bitness,
+		Wow64:           ch.Wow64,
+		FormFactors:     []string{formFactor},
+	}
+}
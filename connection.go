@@ -0,0 +1,69 @@
+package fingerprint
+
+import (
+	"github.com/chromedp/cdproto/network"
+)
+
+// Значения NetworkInformation.effectiveType
+const (
+	EffectiveTypeSlow2G = "slow-2g"
+	EffectiveType2G     = "2g"
+	EffectiveType3G     = "3g"
+	EffectiveType4G     = "4g"
+)
+
+// Chrome ограничивает rtt и downlink, чтобы они не различали быстрые сети
+const (
+	connectionMaxRTT      = 3000
+	connectionMaxDownlink = 10.0
+)
+
+// connectionTypes значения NetworkInformation.type
+var connectionTypes = map[string]bool{
+	"bluetooth": true,
+	"cellular":  true,
+	"ethernet":  true,
+	"none":      true,
+	"wifi":      true,
+	"wimax":     true,
+	"other":     true,
+	"unknown":   true,
+}
+
+// effectiveConnectionType возвращает effectiveType для rtt (мс) и
+// downlink (Мбит/с) по таблице спецификации Network Information API
+func effectiveConnectionType(rtt int, downlink float64) string {
+	switch {
+	case rtt >= 2000 || downlink <= 0.05:
+		return EffectiveTypeSlow2G
+	case rtt >= 1400 || downlink <= 0.07:
+		return EffectiveType2G
+	case rtt >= 270 || downlink <= 0.7:
+		return EffectiveType3G
+	}
+	return EffectiveType4G
+}
+
+// networkConditions возвращает параметры Network.emulateNetworkConditions,
+// при которых сеть соответствует RTT и Downlink
+func (c *Connection) networkConditions() *network.EmulateNetworkConditionsParams {
+	upload := -1.0
+	if c.Uplink > 0 {
+		upload = c.Uplink * 1e6 / 8
+	}
+	params := network.EmulateNetworkConditions(false, float64(c.RTT), c.Downlink*1e6/8, upload)
+
+	switch c.Type {
+	case "cellular":
+		switch c.EffectiveType {
+		case EffectiveType4G:
+			return params.WithConnectionType(network.ConnectionTypeCellular4g)
+		case EffectiveType3G:
+			return params.WithConnectionType(network.ConnectionTypeCellular3g)
+		}
+		return params.WithConnectionType(network.ConnectionTypeCellular2g)
+	case "bluetooth", "ethernet", "none", "wifi", "wimax", "other":
+		return params.WithConnectionType(network.ConnectionType(c.Type))
+	}
+	return params
+}
@@ -10,15 +10,17 @@ type DeviceDatabase struct {
 
 // BrowserVersion информация о версии браузера
 type BrowserVersion struct {
-	Name    string
-	Version string
-	Major   int
+	Name        string
+	Version     string
+	Major       int
+	FullVersion string // полная версия для Client Hints (Chrome)
 }
 
 // DeviceSpec спецификация устройства
 type DeviceSpec struct {
 	Name          string
 	Type          string // "desktop", "mobile", "tablet"
+	Laptop        bool   // компьютер с батареей
 	Platform      string
 	CPUCores      []int // Возможные варианты ядер
 	RAM           []int // Возможные варианты RAM (GB)
@@ -29,9 +31,12 @@ type DeviceSpec struct {
 
 // GPUSpec спецификация видеокарты
 type GPUSpec struct {
-	Vendor   string
-	Renderer string
-	Type     string // "desktop", "mobile"
+	Vendor       string
+	Renderer     string
+	Type         string      // "desktop", "mobile"
+	Architecture string      // архитектура в GPUAdapterInfo WebGPU
+	WebGL        *WebGLCaps  // возможности WebGL
+	WebGPU       *WebGPUCaps // возможности WebGPU; nil - нет адаптера
 }
 
 // OSVersion версия операционной системы
@@ -45,10 +50,10 @@ type OSVersion struct {
 func GetDeviceDatabase() *DeviceDatabase {
 	return &DeviceDatabase{
 		Browsers: []BrowserVersion{
-			{Name: "Chrome", Version: "119.0.0.0", Major: 119},
-			{Name: "Chrome", Version: "120.0.0.0", Major: 120},
-			{Name: "Chrome", Version: "121.0.0.0", Major: 121},
-			{Name: "Chrome", Version: "122.0.0.0", Major: 122},
+			{Name: "Chrome", Version: "119.0.0.0", Major: 119, FullVersion: "119.0.6045.159"},
+			{Name: "Chrome", Version: "120.0.0.0", Major: 120, FullVersion: "120.0.6099.129"},
+			{Name: "Chrome", Version: "121.0.0.0", Major: 121, FullVersion: "121.0.6167.184"},
+			{Name: "Chrome", Version: "122.0.0.0", Major: 122, FullVersion: "122.0.6261.128"},
 			{Name: "Firefox", Version: "120.0", Major: 120},
 			{Name: "Firefox", Version: "121.0", Major: 121},
 			{Name: "Safari", Version: "17.0", Major: 17},
@@ -70,6 +75,7 @@ func GetDeviceDatabase() *DeviceDatabase {
 			{
 				Name:          "MacBook Pro",
 				Type:          "desktop",
+				Laptop:        true,
 				Platform:      "MacIntel",
 				CPUCores:      []int{8, 10, 12},
 				RAM:           []int{8, 16, 32, 64},
@@ -194,50 +200,50 @@ func GetDeviceDatabase() *DeviceDatabase {
 		},
 		GPUs: []GPUSpec{
 			// Desktop - NVIDIA
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4090", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4080", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4070", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3090", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3080", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3070", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce GTX 1660 Ti", Type: "desktop"},
-			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce GTX 1080 Ti", Type: "desktop"},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4090", Type: "desktop", Architecture: "lovelace", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4080", Type: "desktop", Architecture: "lovelace", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 4070", Type: "desktop", Architecture: "lovelace", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3090", Type: "desktop", Architecture: "ampere", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3080", Type: "desktop", Architecture: "ampere", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce RTX 3070", Type: "desktop", Architecture: "ampere", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce GTX 1660 Ti", Type: "desktop", Architecture: "turing", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "NVIDIA Corporation", Renderer: "NVIDIA GeForce GTX 1080 Ti", Type: "desktop", Architecture: "pascal", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
 
 			// Desktop - AMD
-			{Vendor: "AMD", Renderer: "AMD Radeon RX 7900 XTX", Type: "desktop"},
-			{Vendor: "AMD", Renderer: "AMD Radeon RX 7800 XT", Type: "desktop"},
-			{Vendor: "AMD", Renderer: "AMD Radeon RX 6900 XT", Type: "desktop"},
-			{Vendor: "AMD", Renderer: "AMD Radeon RX 6800 XT", Type: "desktop"},
-			{Vendor: "AMD", Renderer: "AMD Radeon RX 5700 XT", Type: "desktop"},
+			{Vendor: "AMD", Renderer: "AMD Radeon RX 7900 XTX", Type: "desktop", Architecture: "rdna-3", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "AMD", Renderer: "AMD Radeon RX 7800 XT", Type: "desktop", Architecture: "rdna-3", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "AMD", Renderer: "AMD Radeon RX 6900 XT", Type: "desktop", Architecture: "rdna-2", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "AMD", Renderer: "AMD Radeon RX 6800 XT", Type: "desktop", Architecture: "rdna-2", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
+			{Vendor: "AMD", Renderer: "AMD Radeon RX 5700 XT", Type: "desktop", Architecture: "rdna-1", WebGL: webglCapsDesktop, WebGPU: webgpuCapsDesktop},
 
 			// Desktop - Intel
-			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 770", Type: "desktop"},
-			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 730", Type: "desktop"},
-			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 630", Type: "desktop"},
-			{Vendor: "Intel Inc.", Renderer: "Intel(R) Iris Xe Graphics", Type: "desktop"},
+			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 770", Type: "desktop", Architecture: "gen-12lp", WebGL: webglCapsIntel, WebGPU: webgpuCapsDesktop},
+			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 730", Type: "desktop", Architecture: "gen-12lp", WebGL: webglCapsIntel, WebGPU: webgpuCapsDesktop},
+			{Vendor: "Intel Inc.", Renderer: "Intel(R) UHD Graphics 630", Type: "desktop", Architecture: "gen-9", WebGL: webglCapsIntel, WebGPU: webgpuCapsDesktop},
+			{Vendor: "Intel Inc.", Renderer: "Intel(R) Iris Xe Graphics", Type: "desktop", Architecture: "gen-12lp", WebGL: webglCapsIntel, WebGPU: webgpuCapsDesktop},
 
 			// Desktop - Apple
-			{Vendor: "Apple Inc.", Renderer: "Apple M3 Pro", Type: "desktop"},
-			{Vendor: "Apple Inc.", Renderer: "Apple M2 Pro", Type: "desktop"},
-			{Vendor: "Apple Inc.", Renderer: "Apple M1 Pro", Type: "desktop"},
-			{Vendor: "Apple Inc.", Renderer: "Apple M1", Type: "desktop"},
+			{Vendor: "Apple Inc.", Renderer: "Apple M3 Pro", Type: "desktop", Architecture: "metal-3", WebGL: webglCapsAppleSilicon, WebGPU: webgpuCapsAppleSilicon},
+			{Vendor: "Apple Inc.", Renderer: "Apple M2 Pro", Type: "desktop", Architecture: "metal-3", WebGL: webglCapsAppleSilicon, WebGPU: webgpuCapsAppleSilicon},
+			{Vendor: "Apple Inc.", Renderer: "Apple M1 Pro", Type: "desktop", Architecture: "metal-3", WebGL: webglCapsAppleSilicon, WebGPU: webgpuCapsAppleSilicon},
+			{Vendor: "Apple Inc.", Renderer: "Apple M1", Type: "desktop", Architecture: "metal-3", WebGL: webglCapsAppleSilicon, WebGPU: webgpuCapsAppleSilicon},
 
 			// Mobile - Qualcomm
-			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 740", Type: "mobile"},
-			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 730", Type: "mobile"},
-			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 650", Type: "mobile"},
-			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 640", Type: "mobile"},
+			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 740", Type: "mobile", Architecture: "adreno-7xx", WebGL: webglCapsAdreno, WebGPU: webgpuCapsAndroid},
+			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 730", Type: "mobile", Architecture: "adreno-7xx", WebGL: webglCapsAdreno, WebGPU: webgpuCapsAndroid},
+			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 650", Type: "mobile", Architecture: "adreno-6xx", WebGL: webglCapsAdreno, WebGPU: webgpuCapsAndroid},
+			{Vendor: "Qualcomm", Renderer: "Adreno (TM) 640", Type: "mobile", Architecture: "adreno-6xx", WebGL: webglCapsAdreno, WebGPU: webgpuCapsAndroid},
 
 			// Mobile - Apple
-			{Vendor: "Apple Inc.", Renderer: "Apple A17 Pro GPU", Type: "mobile"},
-			{Vendor: "Apple Inc.", Renderer: "Apple A16 GPU", Type: "mobile"},
-			{Vendor: "Apple Inc.", Renderer: "Apple A15 GPU", Type: "mobile"},
-			{Vendor: "Apple Inc.", Renderer: "Apple A14 GPU", Type: "mobile"},
+			{Vendor: "Apple Inc.", Renderer: "Apple A17 Pro GPU", Type: "mobile", WebGL: webglCapsAppleMobile},
+			{Vendor: "Apple Inc.", Renderer: "Apple A16 GPU", Type: "mobile", WebGL: webglCapsAppleMobile},
+			{Vendor: "Apple Inc.", Renderer: "Apple A15 GPU", Type: "mobile", WebGL: webglCapsAppleMobile},
+			{Vendor: "Apple Inc.", Renderer: "Apple A14 GPU", Type: "mobile", WebGL: webglCapsAppleMobile},
 
 			// Mobile - ARM Mali
-			{Vendor: "ARM", Renderer: "Mali-G710", Type: "mobile"},
-			{Vendor: "ARM", Renderer: "Mali-G78", Type: "mobile"},
-			{Vendor: "ARM", Renderer: "Mali-G77", Type: "mobile"},
+			{Vendor: "ARM", Renderer: "Mali-G710", Type: "mobile", Architecture: "valhall", WebGL: webglCapsMali, WebGPU: webgpuCapsAndroid},
+			{Vendor: "ARM", Renderer: "Mali-G78", Type: "mobile", Architecture: "valhall", WebGL: webglCapsMali, WebGPU: webgpuCapsAndroid},
+			{Vendor: "ARM", Renderer: "Mali-G77", Type: "mobile", Architecture: "valhall", WebGL: webglCapsMali, WebGPU: webgpuCapsAndroid},
 		},
 		OSes: []OSVersion{
 			{
@@ -260,6 +266,11 @@ func GetDeviceDatabase() *DeviceDatabase {
 				Platform: "iPhone",
 				Versions: []string{"17.0", "17.1", "16.7", "16.6"},
 			},
+			{
+				Name:     "iPadOS",
+				Platform: "iPad",
+				Versions: []string{"17.0", "17.1", "16.7", "16.6"},
+			},
 			{
 				Name:     "Android",
 				Platform: "Linux armv8l",
@@ -268,3 +279,342 @@ func GetDeviceDatabase() *DeviceDatabase {
 		},
 	}
 }
+
+// Точность шейдеров: desktop-видеокарты исполняют mediump и lowp
+// с полной точностью, мобильные - в половинной (fp16)
+var (
+	desktopShaderPrecision = map[string]ShaderPrecision{
+		"LOW_FLOAT":    {RangeMin: 127, RangeMax: 127, Precision: 23},
+		"MEDIUM_FLOAT": {RangeMin: 127, RangeMax: 127, Precision: 23},
+		"HIGH_FLOAT":   {RangeMin: 127, RangeMax: 127, Precision: 23},
+		"LOW_INT":      {RangeMin: 31, RangeMax: 30, Precision: 0},
+		"MEDIUM_INT":   {RangeMin: 31, RangeMax: 30, Precision: 0},
+		"HIGH_INT":     {RangeMin: 31, RangeMax: 30, Precision: 0},
+	}
+	mobileShaderPrecision = map[string]ShaderPrecision{
+		"LOW_FLOAT":    {RangeMin: 15, RangeMax: 15, Precision: 10},
+		"MEDIUM_FLOAT": {RangeMin: 15, RangeMax: 15, Precision: 10},
+		"HIGH_FLOAT":   {RangeMin: 127, RangeMax: 127, Precision: 23},
+		"LOW_INT":      {RangeMin: 15, RangeMax: 14, Precision: 0},
+		"MEDIUM_INT":   {RangeMin: 15, RangeMax: 14, Precision: 0},
+		"HIGH_INT":     {RangeMin: 31, RangeMax: 30, Precision: 0},
+	}
+)
+
+// Расширения WebGL по бэкенду ANGLE
+var (
+	angleD3D11Extensions = []string{
+		"ANGLE_instanced_arrays", "EXT_blend_minmax", "EXT_clip_control", "EXT_color_buffer_half_float",
+		"EXT_depth_clamp", "EXT_disjoint_timer_query", "EXT_float_blend", "EXT_frag_depth",
+		"EXT_polygon_offset_clamp", "EXT_shader_texture_lod", "EXT_texture_compression_bptc",
+		"EXT_texture_compression_rgtc", "EXT_texture_filter_anisotropic", "EXT_texture_mirror_clamp_to_edge",
+		"EXT_sRGB", "KHR_parallel_shader_compile", "OES_element_index_uint", "OES_fbo_render_mipmap",
+		"OES_standard_derivatives", "OES_texture_float", "OES_texture_float_linear", "OES_texture_half_float",
+		"OES_texture_half_float_linear", "OES_vertex_array_object", "WEBGL_blend_func_extended",
+		"WEBGL_color_buffer_float", "WEBGL_compressed_texture_s3tc", "WEBGL_compressed_texture_s3tc_srgb",
+		"WEBGL_debug_renderer_info", "WEBGL_debug_shaders", "WEBGL_depth_texture", "WEBGL_draw_buffers",
+		"WEBGL_lose_context", "WEBGL_multi_draw", "WEBGL_polygon_mode",
+	}
+	angleD3D11WebGL2Extensions = []string{
+		"EXT_clip_control", "EXT_color_buffer_float", "EXT_color_buffer_half_float", "EXT_conservative_depth",
+		"EXT_depth_clamp", "EXT_disjoint_timer_query_webgl2", "EXT_float_blend", "EXT_polygon_offset_clamp",
+		"EXT_render_snorm", "EXT_texture_compression_bptc", "EXT_texture_compression_rgtc",
+		"EXT_texture_filter_anisotropic", "EXT_texture_mirror_clamp_to_edge", "EXT_texture_norm16",
+		"KHR_parallel_shader_compile", "NV_shader_noperspective_interpolation", "OES_draw_buffers_indexed",
+		"OES_sample_variables", "OES_shader_multisample_interpolation", "OES_texture_float_linear",
+		"OVR_multiview2", "WEBGL_blend_func_extended", "WEBGL_clip_cull_distance",
+		"WEBGL_compressed_texture_s3tc", "WEBGL_compressed_texture_s3tc_srgb", "WEBGL_debug_renderer_info",
+		"WEBGL_debug_shaders", "WEBGL_lose_context", "WEBGL_multi_draw", "WEBGL_polygon_mode",
+		"WEBGL_provoking_vertex", "WEBGL_stencil_texturing",
+	}
+	angleMetalExtensions = []string{
+		"ANGLE_instanced_arrays", "EXT_blend_minmax", "EXT_clip_control", "EXT_color_buffer_half_float",
+		"EXT_depth_clamp", "EXT_float_blend", "EXT_frag_depth", "EXT_polygon_offset_clamp",
+		"EXT_shader_texture_lod", "EXT_texture_compression_bptc", "EXT_texture_compression_rgtc",
+		"EXT_texture_filter_anisotropic", "EXT_texture_mirror_clamp_to_edge", "EXT_sRGB",
+		"KHR_parallel_shader_compile", "OES_element_index_uint", "OES_fbo_render_mipmap",
+		"OES_standard_derivatives", "OES_texture_float", "OES_texture_float_linear", "OES_texture_half_float",
+		"OES_texture_half_float_linear", "OES_vertex_array_object", "WEBGL_blend_func_extended",
+		"WEBGL_color_buffer_float", "WEBGL_compressed_texture_astc", "WEBGL_compressed_texture_etc",
+		"WEBGL_compressed_texture_etc1", "WEBGL_compressed_texture_pvrtc", "WEBGL_compressed_texture_s3tc",
+		"WEBGL_compressed_texture_s3tc_srgb", "WEBGL_debug_renderer_info", "WEBGL_debug_shaders",
+		"WEBGL_depth_texture", "WEBGL_draw_buffers", "WEBGL_lose_context", "WEBGL_multi_draw",
+		"WEBGL_polygon_mode",
+	}
+	angleMetalWebGL2Extensions = []string{
+		"EXT_clip_control", "EXT_color_buffer_float", "EXT_color_buffer_half_float", "EXT_conservative_depth",
+		"EXT_depth_clamp", "EXT_float_blend", "EXT_polygon_offset_clamp", "EXT_render_snorm",
+		"EXT_texture_compression_bptc", "EXT_texture_compression_rgtc", "EXT_texture_filter_anisotropic",
+		"EXT_texture_mirror_clamp_to_edge", "EXT_texture_norm16", "KHR_parallel_shader_compile",
+		"NV_shader_noperspective_interpolation", "OES_draw_buffers_indexed", "OES_sample_variables",
+		"OES_shader_multisample_interpolation", "OES_texture_float_linear", "OVR_multiview2",
+		"WEBGL_blend_func_extended", "WEBGL_clip_cull_distance", "WEBGL_compressed_texture_astc",
+		"WEBGL_compressed_texture_etc", "WEBGL_compressed_texture_etc1", "WEBGL_compressed_texture_pvrtc",
+		"WEBGL_compressed_texture_s3tc", "WEBGL_compressed_texture_s3tc_srgb", "WEBGL_debug_renderer_info",
+		"WEBGL_debug_shaders", "WEBGL_lose_context", "WEBGL_multi_draw", "WEBGL_polygon_mode",
+		"WEBGL_provoking_vertex", "WEBGL_stencil_texturing",
+	}
+	angleGLESExtensions = []string{
+		"ANGLE_instanced_arrays", "EXT_blend_minmax", "EXT_color_buffer_half_float", "EXT_disjoint_timer_query",
+		"EXT_float_blend", "EXT_frag_depth", "EXT_shader_texture_lod", "EXT_texture_filter_anisotropic",
+		"EXT_sRGB", "KHR_parallel_shader_compile", "OES_element_index_uint", "OES_fbo_render_mipmap",
+		"OES_standard_derivatives", "OES_texture_float", "OES_texture_float_linear", "OES_texture_half_float",
+		"OES_texture_half_float_linear", "OES_vertex_array_object", "WEBGL_color_buffer_float",
+		"WEBGL_compressed_texture_astc", "WEBGL_compressed_texture_etc", "WEBGL_compressed_texture_etc1",
+		"WEBGL_debug_renderer_info", "WEBGL_debug_shaders", "WEBGL_depth_texture", "WEBGL_draw_buffers",
+		"WEBGL_lose_context", "WEBGL_multi_draw",
+	}
+	angleGLESWebGL2Extensions = []string{
+		"EXT_color_buffer_float", "EXT_color_buffer_half_float", "EXT_disjoint_timer_query_webgl2",
+		"EXT_float_blend", "EXT_texture_filter_anisotropic", "EXT_texture_norm16", "KHR_parallel_shader_compile",
+		"OES_draw_buffers_indexed", "OES_texture_float_linear", "OVR_multiview2", "WEBGL_compressed_texture_astc",
+		"WEBGL_compressed_texture_etc", "WEBGL_compressed_texture_etc1", "WEBGL_debug_renderer_info",
+		"WEBGL_debug_shaders", "WEBGL_lose_context", "WEBGL_multi_draw",
+	}
+)
+
+// webglParameters собирает числовые лимиты getParameter: общие значения
+// и отличия видеокарты
+func webglParameters(overrides map[string]int) map[string]int {
+	params := map[string]int{
+		"RED_BITS":                                8,
+		"GREEN_BITS":                              8,
+		"BLUE_BITS":                               8,
+		"ALPHA_BITS":                              8,
+		"DEPTH_BITS":                              24,
+		"STENCIL_BITS":                            8,
+		"SUBPIXEL_BITS":                           4,
+		"MAX_TEXTURE_SIZE":                        16384,
+		"MAX_CUBE_MAP_TEXTURE_SIZE":               16384,
+		"MAX_RENDERBUFFER_SIZE":                   16384,
+		"MAX_VERTEX_ATTRIBS":                      16,
+		"MAX_VERTEX_UNIFORM_VECTORS":              4096,
+		"MAX_FRAGMENT_UNIFORM_VECTORS":            1024,
+		"MAX_VARYING_VECTORS":                     30,
+		"MAX_TEXTURE_IMAGE_UNITS":                 16,
+		"MAX_VERTEX_TEXTURE_IMAGE_UNITS":          16,
+		"MAX_COMBINED_TEXTURE_IMAGE_UNITS":        32,
+		"MAX_3D_TEXTURE_SIZE":                     2048,
+		"MAX_ARRAY_TEXTURE_LAYERS":                2048,
+		"MAX_COLOR_ATTACHMENTS":                   8,
+		"MAX_DRAW_BUFFERS":                        8,
+		"MAX_SAMPLES":                             16,
+		"MAX_TEXTURE_LOD_BIAS":                    15,
+		"MAX_UNIFORM_BUFFER_BINDINGS":             24,
+		"MAX_UNIFORM_BLOCK_SIZE":                  65536,
+		"MAX_VERTEX_UNIFORM_BLOCKS":               12,
+		"MAX_FRAGMENT_UNIFORM_BLOCKS":             12,
+		"MAX_COMBINED_UNIFORM_BLOCKS":             24,
+		"MAX_VARYING_COMPONENTS":                  120,
+		"MAX_VERTEX_OUTPUT_COMPONENTS":            120,
+		"MAX_FRAGMENT_INPUT_COMPONENTS":           120,
+		"MAX_TRANSFORM_FEEDBACK_SEPARATE_ATTRIBS": 4,
+		"MAX_ELEMENTS_VERTICES":                   2147483647,
+		"MAX_ELEMENTS_INDICES":                    2147483647,
+	}
+	for name, value := range overrides {
+		params[name] = value
+	}
+	return params
+}
+
+// webglArrayParameters собирает лимиты getParameter, которые
+// возвращаются массивами
+func webglArrayParameters(overrides map[string][]int) map[string][]int {
+	params := map[string][]int{
+		"ALIASED_LINE_WIDTH_RANGE": {1, 1},
+		"ALIASED_POINT_SIZE_RANGE": {1, 1024},
+		"MAX_VIEWPORT_DIMS":        {32767, 32767},
+	}
+	for name, value := range overrides {
+		params[name] = value
+	}
+	return params
+}
+
+// Возможности WebGL по семействам видеокарт
+var (
+	// NVIDIA и AMD через ANGLE Direct3D 11
+	webglCapsDesktop = &WebGLCaps{
+		Parameters:       webglParameters(nil),
+		ArrayParameters:  webglArrayParameters(nil),
+		Extensions:       angleD3D11Extensions,
+		WebGL2Extensions: angleD3D11WebGL2Extensions,
+		ShaderPrecision:  desktopShaderPrecision,
+	}
+	webglCapsIntel = &WebGLCaps{
+		Parameters:       webglParameters(map[string]int{"MAX_SAMPLES": 8}),
+		ArrayParameters:  webglArrayParameters(nil),
+		Extensions:       angleD3D11Extensions,
+		WebGL2Extensions: angleD3D11WebGL2Extensions,
+		ShaderPrecision:  desktopShaderPrecision,
+	}
+	// Apple M через ANGLE Metal
+	webglCapsAppleSilicon = &WebGLCaps{
+		Parameters: webglParameters(map[string]int{
+			"MAX_VERTEX_UNIFORM_VECTORS":    1024,
+			"MAX_VARYING_VECTORS":           31,
+			"MAX_SAMPLES":                   4,
+			"MAX_TEXTURE_LOD_BIAS":          16,
+			"MAX_VARYING_COMPONENTS":        124,
+			"MAX_VERTEX_OUTPUT_COMPONENTS":  124,
+			"MAX_FRAGMENT_INPUT_COMPONENTS": 124,
+		}),
+		ArrayParameters: webglArrayParameters(map[string][]int{
+			"ALIASED_POINT_SIZE_RANGE": {1, 511},
+			"MAX_VIEWPORT_DIMS":        {16384, 16384},
+		}),
+		Extensions:       angleMetalExtensions,
+		WebGL2Extensions: angleMetalWebGL2Extensions,
+		ShaderPrecision:  desktopShaderPrecision,
+	}
+	// Apple A в Safari на iOS: тот же бэкенд Metal, mediump в fp16
+	webglCapsAppleMobile = &WebGLCaps{
+		Parameters:       webglCapsAppleSilicon.Parameters,
+		ArrayParameters:  webglCapsAppleSilicon.ArrayParameters,
+		Extensions:       angleMetalExtensions,
+		WebGL2Extensions: angleMetalWebGL2Extensions,
+		ShaderPrecision:  mobileShaderPrecision,
+	}
+	webglCapsAdreno = &WebGLCaps{
+		Parameters: webglParameters(map[string]int{
+			"MAX_VERTEX_ATTRIBS":               32,
+			"MAX_VERTEX_UNIFORM_VECTORS":       256,
+			"MAX_FRAGMENT_UNIFORM_VECTORS":     256,
+			"MAX_VARYING_VECTORS":              31,
+			"MAX_COMBINED_TEXTURE_IMAGE_UNITS": 96,
+			"MAX_SAMPLES":                      4,
+			"MAX_UNIFORM_BUFFER_BINDINGS":      72,
+			"MAX_COMBINED_UNIFORM_BLOCKS":      72,
+			"MAX_VARYING_COMPONENTS":           124,
+			"MAX_VERTEX_OUTPUT_COMPONENTS":     128,
+			"MAX_FRAGMENT_INPUT_COMPONENTS":    128,
+		}),
+		ArrayParameters: webglArrayParameters(map[string][]int{
+			"ALIASED_LINE_WIDTH_RANGE": {1, 8},
+			"ALIASED_POINT_SIZE_RANGE": {1, 1023},
+			"MAX_VIEWPORT_DIMS":        {16384, 16384},
+		}),
+		Extensions:       angleGLESExtensions,
+		WebGL2Extensions: angleGLESWebGL2Extensions,
+		ShaderPrecision:  mobileShaderPrecision,
+	}
+	webglCapsMali = &WebGLCaps{
+		Parameters: webglParameters(map[string]int{
+			"MAX_TEXTURE_SIZE":                 8192,
+			"MAX_CUBE_MAP_TEXTURE_SIZE":        8192,
+			"MAX_RENDERBUFFER_SIZE":            8192,
+			"MAX_VERTEX_UNIFORM_VECTORS":       1024,
+			"MAX_VARYING_VECTORS":              15,
+			"MAX_COMBINED_TEXTURE_IMAGE_UNITS": 96,
+			"MAX_SAMPLES":                      4,
+			"MAX_UNIFORM_BUFFER_BINDINGS":      84,
+			"MAX_COMBINED_UNIFORM_BLOCKS":      84,
+			"MAX_VARYING_COMPONENTS":           60,
+			"MAX_VERTEX_OUTPUT_COMPONENTS":     64,
+			"MAX_FRAGMENT_INPUT_COMPONENTS":    60,
+		}),
+		ArrayParameters: webglArrayParameters(map[string][]int{
+			"ALIASED_LINE_WIDTH_RANGE": {1, 100},
+			"MAX_VIEWPORT_DIMS":        {8192, 8192},
+		}),
+		Extensions:       angleGLESExtensions,
+		WebGL2Extensions: angleGLESWebGL2Extensions,
+		ShaderPrecision:  mobileShaderPrecision,
+	}
+)
+
+// webgpuLimits собирает лимиты адаптера WebGPU: значения Chrome для
+// дискретных и встроенных видеокарт и отличия семейства
+func webgpuLimits(overrides map[string]int64) map[string]int64 {
+	limits := map[string]int64{
+		"maxTextureDimension1D":                     16384,
+		"maxTextureDimension2D":                     16384,
+		"maxTextureDimension3D":                     2048,
+		"maxTextureArrayLayers":                     2048,
+		"maxBindGroups":                             4,
+		"maxBindGroupsPlusVertexBuffers":            24,
+		"maxBindingsPerBindGroup":                   1000,
+		"maxDynamicUniformBuffersPerPipelineLayout": 10,
+		"maxDynamicStorageBuffersPerPipelineLayout": 8,
+		"maxSampledTexturesPerShaderStage":          16,
+		"maxSamplersPerShaderStage":                 16,
+		"maxStorageBuffersPerShaderStage":           10,
+		"maxStorageTexturesPerShaderStage":          8,
+		"maxUniformBuffersPerShaderStage":           12,
+		"maxUniformBufferBindingSize":               65536,
+		"maxStorageBufferBindingSize":               2147483644,
+		"minUniformBufferOffsetAlignment":           256,
+		"minStorageBufferOffsetAlignment":           256,
+		"maxVertexBuffers":                          8,
+		"maxBufferSize":                             4294967296,
+		"maxVertexAttributes":                       30,
+		"maxVertexBufferArrayStride":                2048,
+		"maxInterStageShaderVariables":              28,
+		"maxColorAttachments":                       8,
+		"maxColorAttachmentBytesPerSample":          128,
+		"maxComputeWorkgroupStorageSize":            32768,
+		"maxComputeInvocationsPerWorkgroup":         1024,
+		"maxComputeWorkgroupSizeX":                  1024,
+		"maxComputeWorkgroupSizeY":                  1024,
+		"maxComputeWorkgroupSizeZ":                  64,
+		"maxComputeWorkgroupsPerDimension":          65535,
+	}
+	for name, value := range overrides {
+		limits[name] = value
+	}
+	return limits
+}
+
+// Функции WebGPU, общие для адаптеров Chrome
+var webgpuFeatures = []string{
+	"depth-clip-control",
+	"depth32float-stencil8",
+	"indirect-first-instance",
+	"rg11b10ufloat-renderable",
+	"bgra8unorm-storage",
+	"float32-filterable",
+}
+
+// Возможности WebGPU по семействам видеокарт
+var (
+	// NVIDIA, AMD и Intel через Direct3D 12 и Vulkan
+	webgpuCapsDesktop = &WebGPUCaps{
+		Features: append(append([]string(nil), webgpuFeatures...),
+			"texture-compression-bc", "timestamp-query", "shader-f16"),
+		Limits:                webgpuLimits(nil),
+		PreferredCanvasFormat: "bgra8unorm",
+	}
+	// Apple M через Metal
+	webgpuCapsAppleSilicon = &WebGPUCaps{
+		Features: append(append([]string(nil), webgpuFeatures...),
+			"texture-compression-bc", "texture-compression-etc2", "texture-compression-astc",
+			"timestamp-query", "shader-f16"),
+		Limits: webgpuLimits(map[string]int64{
+			"maxStorageBufferBindingSize":  4294967292,
+			"maxInterStageShaderVariables": 124,
+		}),
+		PreferredCanvasFormat: "bgra8unorm",
+	}
+	// Adreno и Mali через Vulkan на Android
+	webgpuCapsAndroid = &WebGPUCaps{
+		Features: append(append([]string(nil), webgpuFeatures[:4]...),
+			"texture-compression-etc2", "texture-compression-astc"),
+		Limits: webgpuLimits(map[string]int64{
+			"maxTextureDimension1D":             8192,
+			"maxTextureDimension2D":             8192,
+			"maxStorageBuffersPerShaderStage":   8,
+			"maxStorageTexturesPerShaderStage":  4,
+			"maxStorageBufferBindingSize":       134217728,
+			"maxBufferSize":                     268435456,
+			"maxVertexAttributes":               16,
+			"maxInterStageShaderVariables":      16,
+			"maxColorAttachmentBytesPerSample":  32,
+			"maxComputeWorkgroupStorageSize":    16384,
+			"maxComputeInvocationsPerWorkgroup": 256,
+			"maxComputeWorkgroupSizeX":          256,
+			"maxComputeWorkgroupSizeY":          256,
+		}),
+		PreferredCanvasFormat: "rgba8unorm",
+	}
+)
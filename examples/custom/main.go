@@ -49,10 +49,10 @@ func main() {
 			Offset: -180,
 		},
 		WebGL: &fp.WebGL{
-			Vendor:           "Google Inc. (NVIDIA)",
-			Renderer:         "ANGLE (NVIDIA, NVIDIA GeForce RTX 3080 Direct3D11 vs_5_0 ps_5_0, D3D11)",
-			UnmaskedVendor:   "NVIDIA Corporation",
-			UnmaskedRenderer: "NVIDIA GeForce RTX 3080",
+			Vendor:           "WebKit",
+			Renderer:         "WebKit WebGL",
+			UnmaskedVendor:   "Google Inc. (NVIDIA)",
+			UnmaskedRenderer: "ANGLE (NVIDIA, NVIDIA GeForce RTX 3080 Direct3D11 vs_5_0 ps_5_0, D3D11)",
 		},
 		Canvas: &fp.Canvas{
 			Noise: 0.02,
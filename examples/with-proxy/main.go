@@ -13,6 +13,22 @@ func main() {
 	// Настройки прокси (замените на свои)
 	proxyServer := "http://proxy-server:port"
 
+	// Создаем fingerprint
+	fingerprint := fp.NewChrome119Windows11()
+
+	// Настраиваем timezone под прокси (например, если прокси в UK)
+	fingerprint.Timezone = &fp.Timezone{
+		ID:     "Europe/London",
+		Offset: 0,
+	}
+
+	// Настраиваем язык под прокси
+	fingerprint.Language = "en-GB"
+	fingerprint.Languages = []string{"en-GB", "en"}
+
+	// WebRTC только через TURN: реальный IP не попадает в кандидаты
+	fingerprint.WebRTC.Mode = fp.WebRTCModeProxyOnly
+
 	// Опции chromedp с прокси
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", false),
@@ -30,6 +46,8 @@ func main() {
 		chromedp.Flag("no-first-run", true),
 		chromedp.Flag("no-default-browser-check", true),
 	)
+	// Флаги fingerprint: политика WebRTC
+	opts = append(opts, fingerprint.AllocatorOptions()...)
 
 	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
 	defer cancel()
@@ -41,22 +59,6 @@ func main() {
 	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	// Создаем fingerprint
-	fingerprint := fp.NewChrome119Windows11()
-
-	// Настраиваем timezone под прокси (например, если прокси в UK)
-	fingerprint.Timezone = &fp.Timezone{
-		ID:     "Europe/London",
-		Offset: 0,
-	}
-
-	// Настраиваем язык под прокси
-	fingerprint.Language = "en-GB"
-	fingerprint.Languages = []string{"en-GB", "en"}
-
-	// Отключаем WebRTC для защиты реального IP
-	fingerprint.WebRTC.Disable = true
-
 	// Создаем инжектор
 	injector := fp.NewInjector(fingerprint)
 
@@ -6,22 +6,28 @@ import (
 
 // Fingerprint содержит все параметры для изменения отпечатка браузера
 type Fingerprint struct {
-	UserAgent           string    `json:"userAgent"`
-	Platform            string    `json:"platform"`
-	Vendor              string    `json:"vendor"`
-	Language            string    `json:"language"`
-	Languages           []string  `json:"languages"`
-	Screen              *Screen   `json:"screen"`
-	Timezone            *Timezone `json:"timezone"`
-	WebGL               *WebGL    `json:"webgl"`
-	Canvas              *Canvas   `json:"canvas"`
-	WebRTC              *WebRTC   `json:"webrtc"`
-	Fonts               []string  `json:"fonts"`
-	Plugins             []Plugin  `json:"plugins"`
-	HardwareConcurrency int       `json:"hardwareConcurrency"`
-	DeviceMemory        int       `json:"deviceMemory"`
-	Audio               *Audio    `json:"audio"`
-	Battery             *Battery  `json:"battery"`
+	UserAgent           string         `json:"userAgent"`
+	Platform            string         `json:"platform"`
+	Vendor              string         `json:"vendor"`
+	Language            string         `json:"language"`
+	Languages           []string       `json:"languages"`
+	Screen              *Screen        `json:"screen"`
+	Window              *Window        `json:"window"`        // nil - viewport совпадает с экраном
+	MediaFeatures       *MediaFeatures `json:"mediaFeatures"` // nil - медиа-функции не меняются
+	Timezone            *Timezone      `json:"timezone"`
+	WebGL               *WebGL         `json:"webgl"`
+	WebGPU              *WebGPU        `json:"webgpu"` // nil - navigator.gpu не меняется
+	Canvas              *Canvas        `json:"canvas"`
+	WebRTC              *WebRTC        `json:"webrtc"`
+	Fonts               []string       `json:"fonts"`
+	Plugins             []Plugin       `json:"plugins"`
+	HardwareConcurrency int            `json:"hardwareConcurrency"`
+	DeviceMemory        int            `json:"deviceMemory"`
+	Audio               *Audio         `json:"audio"`
+	Battery             *Battery       `json:"battery"`
+	MediaDevices        *MediaDevices  `json:"mediaDevices"` // nil - устройства и mediaCapabilities не меняются
+	Connection          *Connection    `json:"connection"`   // nil - navigator.connection не меняется
+	ClientHints         *ClientHints   `json:"clientHints"`  // nil - браузер без Client Hints (Firefox, Safari, Chrome на iOS)
 }
 
 // Screen параметры экрана
@@ -33,33 +39,145 @@ type Screen struct {
 	ColorDepth       int     `json:"colorDepth"`
 	PixelDepth       int     `json:"pixelDepth"`
 	DevicePixelRatio float64 `json:"devicePixelRatio"`
+	Orientation      string  `json:"orientation,omitempty"`      // screen.orientation.type; пусто - по ширине и высоте
+	OrientationAngle int     `json:"orientationAngle,omitempty"` // screen.orientation.angle: 0, 90, 180, 270
+}
+
+// Window геометрия окна браузера в CSS-пикселях. Разница OuterHeight и
+// InnerHeight - высота панелей браузера (вкладки, адресная строка),
+// OuterWidth и InnerWidth - рамки окна.
+type Window struct {
+	InnerWidth     int `json:"innerWidth"` // viewport вместе с полосой прокрутки
+	InnerHeight    int `json:"innerHeight"`
+	OuterWidth     int `json:"outerWidth"`
+	OuterHeight    int `json:"outerHeight"`
+	ScreenX        int `json:"screenX"` // положение окна на экране
+	ScreenY        int `json:"screenY"`
+	ScrollbarWidth int `json:"scrollbarWidth"` // 0 - полосы прокрутки поверх страницы (macOS, телефоны)
+}
+
+// MediaFeatures медиа-функции CSS и matchMedia. ColorScheme, ReducedMotion
+// и ColorGamut применяются через Emulation.setEmulatedMedia (SetEmulatedMedia),
+// Pointer, Hover и DynamicRange - только флагами запуска из AllocatorOptions;
+// Pointer "coarse" включает и эмуляцию touch. Значения совпадают в @media
+// и matchMedia.
+type MediaFeatures struct {
+	ColorScheme   string `json:"colorScheme"`   // prefers-color-scheme: "light", "dark"
+	ReducedMotion string `json:"reducedMotion"` // prefers-reduced-motion: "no-preference", "reduce"
+	Pointer       string `json:"pointer"`       // pointer и any-pointer: "fine", "coarse", "none"
+	Hover         string `json:"hover"`         // hover и any-hover: "hover", "none"
+	ColorGamut    string `json:"colorGamut"`    // color-gamut: "srgb", "p3", "rec2020"
+	DynamicRange  string `json:"dynamicRange"`  // dynamic-range: "standard", "high"
 }
 
 // Timezone параметры временной зоны
 type Timezone struct {
 	ID     string `json:"id"`
-	Offset int    `json:"offset"` // в минутах
+	Offset int    `json:"offset"` // в минутах, как Date.getTimezoneOffset: положительно к западу от UTC
 }
 
-// WebGL параметры WebGL
+// WebGL параметры WebGL. Пустые значения не переопределяются.
 type WebGL struct {
-	Vendor                 string `json:"vendor"`
-	Renderer               string `json:"renderer"`
-	UnmaskedVendor         string `json:"unmaskedVendor"`
-	UnmaskedRenderer       string `json:"unmaskedRenderer"`
-	ShadingLanguageVersion string `json:"shadingLanguageVersion"`
+	Vendor                 string `json:"vendor"`                 // VENDOR: "WebKit" в Chrome и Safari, "Mozilla" в Firefox
+	Renderer               string `json:"renderer"`               // RENDERER: "WebKit WebGL" в Chrome и Safari
+	UnmaskedVendor         string `json:"unmaskedVendor"`         // UNMASKED_VENDOR_WEBGL, например "Google Inc. (NVIDIA)"
+	UnmaskedRenderer       string `json:"unmaskedRenderer"`       // UNMASKED_RENDERER_WEBGL, например "ANGLE (NVIDIA, ...)"
+	Version                string `json:"version"`                // VERSION контекста WebGL 1
+	ShadingLanguageVersion string `json:"shadingLanguageVersion"` // SHADING_LANGUAGE_VERSION контекста WebGL 1
+
+	// Parameters числовые значения getParameter по именам констант WebGL
+	// ("MAX_TEXTURE_SIZE"), ArrayParameters - значения-массивы
+	// (MAX_VIEWPORT_DIMS, ALIASED_LINE_WIDTH_RANGE, ALIASED_POINT_SIZE_RANGE)
+	Parameters       map[string]int             `json:"parameters,omitempty"`
+	ArrayParameters  map[string][]int           `json:"arrayParameters,omitempty"`
+	Extensions       []string                   `json:"extensions,omitempty"`       // getSupportedExtensions для WebGL 1
+	WebGL2Extensions []string                   `json:"webgl2Extensions,omitempty"` // getSupportedExtensions для WebGL 2
+	ShaderPrecision  map[string]ShaderPrecision `json:"shaderPrecision,omitempty"`  // по типу точности: "HIGH_FLOAT", "MEDIUM_INT"...
+	// ContextAttributes флаги, заменяемые в результате getContextAttributes
+	ContextAttributes map[string]bool `json:"contextAttributes,omitempty"`
+}
+
+// ShaderPrecision результат getShaderPrecisionFormat
+type ShaderPrecision struct {
+	RangeMin  int `json:"rangeMin"`
+	RangeMax  int `json:"rangeMax"`
+	Precision int `json:"precision"`
+}
+
+// WebGPU параметры WebGPU: navigator.gpu и адаптер из requestAdapter.
+// Пустые значения не переопределяются.
+type WebGPU struct {
+	Disable   bool `json:"disable"`   // удалить navigator.gpu, как в браузерах без WebGPU
+	NoAdapter bool `json:"noAdapter"` // requestAdapter возвращает null, как в Chrome на Linux
+
+	// GPUAdapterInfo. Если Vendor задан, подменяются все четыре поля;
+	// Chrome оставляет device и description пустыми
+	Vendor       string `json:"vendor"`       // "nvidia", "amd", "intel", "apple"...
+	Architecture string `json:"architecture"` // "ampere", "rdna-3", "gen-12lp", "metal-3"...
+	Device       string `json:"device"`
+	Description  string `json:"description"`
+
+	Features              []string         `json:"features,omitempty"` // GPUAdapter.features
+	Limits                map[string]int64 `json:"limits,omitempty"`   // GPUAdapter.limits по именам
+	PreferredCanvasFormat string           `json:"preferredCanvasFormat"`
 }
 
 // Canvas параметры Canvas
 type Canvas struct {
 	Noise float64 `json:"noise"` // Уровень шума для canvas fingerprinting (0.0 - 1.0)
+	Seed  int64   `json:"seed"`  // Seed шума; 0 - seed выводится из UserAgent
 }
 
 // WebRTC параметры WebRTC
 type WebRTC struct {
-	PublicIP string `json:"publicIp"`
-	LocalIP  string `json:"localIp"`
-	Disable  bool   `json:"disable"`
+	// Mode режим: WebRTCModeDisable, WebRTCModeMask, WebRTCModeProxyOnly;
+	// пустой - WebRTC не меняется (или отключается, если задан Disable)
+	Mode     string `json:"mode,omitempty"`
+	PublicIP string `json:"publicIp"` // адрес srflx-кандидатов в режиме mask; пустой - не меняется
+	LocalIP  string `json:"localIp"`  // адрес host-кандидатов; пустой - имя mDNS, как в Chrome
+	Disable  bool   `json:"disable"`  // то же, что Mode WebRTCModeDisable
+}
+
+// MediaDevices устройства navigator.mediaDevices и ответы
+// navigator.mediaCapabilities.decodingInfo
+type MediaDevices struct {
+	Seed    int64                 `json:"seed"`             // seed deviceId и groupId; 0 - seed выводится из UserAgent
+	Devices []MediaDevice         `json:"devices"`          // enumerateDevices в порядке браузера
+	Codecs  map[string]MediaCodec `json:"codecs,omitempty"` // по кодеку ("avc1", "vp09", "opus") или MIME-типу
+}
+
+// MediaDevice устройство enumerateDevices. deviceId и groupId выводятся
+// из seed и origin страницы, как в Chrome они различаются между сайтами.
+// До разрешения на камеру или микрофон видна только одна запись каждого
+// вида с пустыми полями.
+type MediaDevice struct {
+	Kind       string `json:"kind"`                 // MediaAudioInput, MediaVideoInput, MediaAudioOutput
+	Label      string `json:"label"`                // видна после разрешения
+	DeviceID   string `json:"deviceId,omitempty"`   // "default", "communications"; пустой - из seed
+	Group      int    `json:"group"`                // одна группа - одно физическое устройство (общий groupId)
+	FacingMode string `json:"facingMode,omitempty"` // "user" или "environment" у камер телефонов
+}
+
+// MediaCodec ответ decodingInfo для кодека
+type MediaCodec struct {
+	Supported      bool `json:"supported"`
+	Smooth         bool `json:"smooth"`
+	PowerEfficient bool `json:"powerEfficient"`
+}
+
+// Connection параметры navigator.connection (Network Information API).
+// Type и DownlinkMax есть только у Chrome на Android; у Chrome на
+// компьютере Type пустой.
+type Connection struct {
+	Disable       bool    `json:"disable"`               // удалить navigator.connection, как в Firefox и Safari
+	Type          string  `json:"type,omitempty"`        // "wifi", "cellular", "ethernet", ...
+	EffectiveType string  `json:"effectiveType"`         // "slow-2g", "2g", "3g", "4g"
+	RTT           int     `json:"rtt"`                   // мс
+	Downlink      float64 `json:"downlink"`              // Мбит/с
+	DownlinkMax   float64 `json:"downlinkMax,omitempty"` // Мбит/с; 0 - Infinity
+	Uplink        float64 `json:"uplink,omitempty"`      // Мбит/с, только для Throttle; 0 - без ограничения
+	SaveData      bool    `json:"saveData"`
+	Throttle      bool    `json:"throttle,omitempty"` // ограничить сеть до RTT и Downlink через Network.emulateNetworkConditions
 }
 
 // Plugin информация о плагине
@@ -77,17 +195,46 @@ type MimeType struct {
 	Suffixes    string `json:"suffixes"`
 }
 
-// Audio параметры Audio Context
+// Audio параметры Audio Context.
+// Нулевые SampleRate, BaseLatency и MaxChannelCount не переопределяются.
 type Audio struct {
-	Noise float64 `json:"noise"` // Уровень шума для audio fingerprinting (0.0 - 1.0)
+	Noise           float64 `json:"noise"`           // Уровень шума для audio fingerprinting (0.0 - 1.0)
+	SampleRate      float64 `json:"sampleRate"`      // AudioContext.sampleRate (Гц)
+	BaseLatency     float64 `json:"baseLatency"`     // AudioContext.baseLatency (секунды)
+	MaxChannelCount int     `json:"maxChannelCount"` // AudioDestinationNode.maxChannelCount
 }
 
-// Battery параметры батареи
+// ClientHints параметры User-Agent Client Hints: navigator.userAgentData
+// и заголовки Sec-CH-UA*
+type ClientHints struct {
+	Brands          []Brand `json:"brands"`          // Sec-CH-UA, мажорные версии
+	FullVersionList []Brand `json:"fullVersionList"` // Sec-CH-UA-Full-Version-List
+	Platform        string  `json:"platform"`        // "Windows", "macOS", "Linux", "Android"
+	PlatformVersion string  `json:"platformVersion"` // например "15.0.0" для Windows 11
+	Architecture    string  `json:"architecture"`    // "x86", "arm" или ""
+	Bitness         string  `json:"bitness"`         // "64", "32" или ""
+	Model           string  `json:"model"`           // модель устройства (Android)
+	Mobile          bool    `json:"mobile"`
+	Wow64           bool    `json:"wow64"`
+}
+
+// Brand бренд и версия браузера в Client Hints
+type Brand struct {
+	Brand   string `json:"brand"`
+	Version string `json:"version"`
+}
+
+// Battery параметры батареи. Время в секундах: ChargingTime учитывается
+// на зарядке, DischargingTime - без нее, 0 - Infinity (неизвестно).
+// Заряженная батарея на зарядке сообщает chargingTime 0 - так Chrome
+// описывает компьютер без батареи.
 type Battery struct {
 	Charging        bool    `json:"charging"`
 	ChargingTime    float64 `json:"chargingTime"`
 	DischargingTime float64 `json:"dischargingTime"`
 	Level           float64 `json:"level"`
+	Speed           float64 `json:"speed,omitempty"`     // скорость симулированных часов; 0 - реальное время
+	StartTime       int64   `json:"startTime,omitempty"` // Unix-время в мс, когда уровень был Level; 0 - загрузка страницы
 }
 
 // ToJSON конвертирует Fingerprint в JSON строку
@@ -116,17 +263,29 @@ func NewDefaultFingerprint() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 1.0,
 		},
+		Window: &Window{
+			InnerWidth:     1920,
+			InnerHeight:    969,
+			OuterWidth:     1920,
+			OuterHeight:    1040,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 17,
+		},
+		MediaFeatures: newMediaFeatures("Win32", "Intel(R) UHD Graphics 630"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
+			Offset: 240,
 		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (Intel)",
-			Renderer:               "ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0)",
-			UnmaskedVendor:         "Intel Inc.",
-			UnmaskedRenderer:       "Intel(R) UHD Graphics 630",
+		WebGL: webglCapsIntel.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (Intel)",
+			UnmaskedRenderer:       "ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: webgpuCapsDesktop.adapter("intel", "gen-9"),
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -137,11 +296,14 @@ func NewDefaultFingerprint() *Fingerprint {
 			"Arial", "Courier New", "Georgia", "Times New Roman",
 			"Verdana", "Trebuchet MS", "Comic Sans MS",
 		},
-		Plugins:             []Plugin{},
+		Plugins:             ChromePDFPlugins(),
 		HardwareConcurrency: 8,
 		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.01,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        true,
@@ -149,5 +311,8 @@ func NewDefaultFingerprint() *Fingerprint {
 			DischargingTime: 0,
 			Level:           1.0,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Win32", "", false, "Intel(R) UHD Graphics 630"),
+		Connection:   &Connection{EffectiveType: EffectiveType4G, RTT: 50, Downlink: 10},
+		ClientHints:  newChromeClientHints(119, "119.0.6045.159", "Windows", "10.0.0", "x86", "", false),
 	}
 }
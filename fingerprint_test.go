@@ -2,7 +2,16 @@ package fingerprint
 
 import (
 	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
 )
 
 func TestNewDefaultFingerprint(t *testing.T) {
@@ -31,7 +40,7 @@ func TestNewDefaultFingerprint(t *testing.T) {
 
 func TestFingerprintToJSON(t *testing.T) {
 	fp := NewDefaultFingerprint()
-	
+
 	jsonStr, err := fp.ToJSON()
 	if err != nil {
 		t.Errorf("ToJSON failed: %v", err)
@@ -57,6 +66,11 @@ func TestPresets(t *testing.T) {
 		{"MacOS", NewChrome119MacOS()},
 		{"Linux", NewChrome119Linux()},
 		{"Android", NewChrome119Android()},
+		{"SafariIOS", NewSafari17iOS()},
+		{"ChromeIOS", NewChrome119iOS()},
+		{"Android134", NewChrome134Android()},
+		{"Windows134", NewChrome134Windows11()},
+		{"Default", NewDefaultFingerprint()},
 	}
 
 	for _, preset := range presets {
@@ -80,6 +94,10 @@ func TestPresets(t *testing.T) {
 			if preset.fp.HardwareConcurrency <= 0 {
 				t.Errorf("%s: HardwareConcurrency should be positive", preset.name)
 			}
+
+			for _, v := range preset.fp.Validate() {
+				t.Errorf("%s: unexpected violation: %s", preset.name, v)
+			}
 		})
 	}
 }
@@ -108,6 +126,66 @@ func TestTimezone(t *testing.T) {
 	}
 }
 
+func TestTimezoneOffsetAt(t *testing.T) {
+	tests := []struct {
+		id     string
+		at     string
+		offset int
+	}{
+		{"America/New_York", "2024-01-15T12:00:00Z", 300},
+		{"America/New_York", "2024-07-15T12:00:00Z", 240},
+		{"America/New_York", "2024-03-10T06:59:59Z", 300},
+		{"America/New_York", "2024-03-10T07:00:00Z", 240},
+		{"America/New_York", "2024-11-03T05:59:59Z", 240},
+		{"America/New_York", "2024-11-03T06:00:00Z", 300},
+		{"Europe/London", "2024-01-15T12:00:00Z", 0},
+		{"Europe/London", "2024-07-15T12:00:00Z", -60},
+		{"Europe/Berlin", "2024-10-27T00:59:59Z", -120},
+		{"Europe/Berlin", "2024-10-27T01:00:00Z", -60},
+		{"Europe/Moscow", "2010-07-01T12:00:00Z", -240},
+		{"Europe/Moscow", "2013-01-15T12:00:00Z", -240},
+		{"Europe/Moscow", "2024-07-15T12:00:00Z", -180},
+		{"Australia/Sydney", "2024-01-15T12:00:00Z", -660},
+		{"Australia/Sydney", "2024-07-15T12:00:00Z", -600},
+		{"America/Sao_Paulo", "2018-01-15T12:00:00Z", 120},
+		{"America/Sao_Paulo", "2024-01-15T12:00:00Z", 180},
+		{"America/St_Johns", "2024-01-15T12:00:00Z", 210},
+		{"Asia/Kolkata", "2024-07-15T12:00:00Z", -330},
+		{"Asia/Kathmandu", "2024-07-15T12:00:00Z", -345},
+		{"Pacific/Chatham", "2024-01-15T12:00:00Z", -825},
+		{"UTC", "2024-07-15T12:00:00Z", 0},
+	}
+
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.at)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", tt.at, err)
+		}
+		tz := &Timezone{ID: tt.id}
+
+		offset, err := tz.OffsetAt(at)
+		if err != nil {
+			t.Fatalf("OffsetAt(%s) failed: %v", tt.id, err)
+		}
+		if offset != tt.offset {
+			t.Errorf("%s at %s: expected offset %d, got %d", tt.id, tt.at, tt.offset, offset)
+		}
+
+		// Таблица переходов, которую получает скрипт, дает то же смещение
+		transitions, err := tz.transitions()
+		if err != nil {
+			t.Fatalf("transitions(%s) failed: %v", tt.id, err)
+		}
+		if got := zoneOffsetAt(transitions, at.UnixMilli()); got != int64(tt.offset) {
+			t.Errorf("%s at %s: transitions give offset %d, expected %d", tt.id, tt.at, got, tt.offset)
+		}
+	}
+
+	if _, err := (&Timezone{ID: "Mars/Olympus_Mons"}).OffsetAt(time.Now()); err == nil {
+		t.Error("OffsetAt should fail for unknown timezone")
+	}
+}
+
 func TestWebGL(t *testing.T) {
 	fp := NewDefaultFingerprint()
 
@@ -128,6 +206,23 @@ func TestCanvasNoise(t *testing.T) {
 	}
 }
 
+func TestRandomizeFingerprintCanvasSeed(t *testing.T) {
+	base := NewDefaultFingerprint()
+	fp := RandomizeFingerprint(base)
+
+	if fp.Canvas.Seed == 0 {
+		t.Error("Randomized fingerprint should have canvas seed")
+	}
+	if base.Canvas.Seed != 0 || fp.Canvas == base.Canvas {
+		t.Error("RandomizeFingerprint should not modify base canvas")
+	}
+
+	noise := NewDefaultFingerprint().Audio.Noise
+	if base.Audio.Noise != noise || fp.Audio == base.Audio {
+		t.Error("RandomizeFingerprint should not modify base audio")
+	}
+}
+
 func TestBattery(t *testing.T) {
 	fp := NewDefaultFingerprint()
 
@@ -136,3 +231,855 @@ func TestBattery(t *testing.T) {
 	}
 }
 
+func TestBatteryAt(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		battery Battery
+		elapsed time.Duration
+		want    Battery
+	}{
+		{"desktop", Battery{Charging: true, Level: 1}, time.Hour,
+			Battery{Charging: true, Level: 1}},
+		{"discharging", Battery{DischargingTime: 8000, Level: 0.8, Speed: 60, StartTime: 1}, 1000 * time.Second,
+			Battery{DischargingTime: 7000, Level: 0.7}},
+		{"empty", Battery{DischargingTime: 8000, Level: 0.8}, 10 * time.Hour,
+			Battery{DischargingTime: 100, Level: 0.01}},
+		{"charging", Battery{Charging: true, ChargingTime: 5000, Level: 0.5}, 2500 * time.Second,
+			Battery{Charging: true, ChargingTime: 2500, Level: 0.75}},
+		{"charged", Battery{Charging: true, ChargingTime: 5000, Level: 0.5}, 2 * time.Hour,
+			Battery{Charging: true, Level: 1}},
+		{"unknown", Battery{Charging: true, Level: 0.5}, time.Hour,
+			Battery{Charging: true, Level: 0.5}},
+	} {
+		if got := tt.battery.At(tt.elapsed); got != tt.want {
+			t.Errorf("%s: At(%v) = %+v, want %+v", tt.name, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateBattery(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	laptop := map[bool]bool{}
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		b := fp.Battery
+		switch fp.Platform {
+		case "Win32", "Linux x86_64":
+			if *b != (Battery{Charging: true, Level: 1}) {
+				t.Errorf("Seed %d: desktop without battery should be charged, got %+v", seed, b)
+			}
+		case "MacIntel":
+			laptop[b.Charging && b.Level == 1] = true
+		default:
+			if b.Level >= 1 || (b.Charging && b.ChargingTime == 0) || (!b.Charging && b.DischargingTime == 0) {
+				t.Errorf("Seed %d: phone battery should drift, got %+v", seed, b)
+			}
+		}
+	}
+	if !laptop[true] || !laptop[false] {
+		t.Error("Laptops should be both plugged in and on battery")
+	}
+
+	base := NewChrome134Android()
+	fp := RandomizeFingerprint(base)
+	if base.Battery.Level != 0.87 || fp.Battery.DischargingTime != math.Round(7200/0.87*fp.Battery.Level) {
+		t.Errorf("RandomizeFingerprint should rescale a copy of the battery, got %+v", fp.Battery)
+	}
+}
+
+func TestValidateBattery(t *testing.T) {
+	fp := NewChrome119Windows11()
+	fp.Battery.ChargingTime = 600
+	fp.Battery.Speed = -1
+
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Field != "battery.speed" || violations[1].Severity != SeverityWarning {
+		t.Errorf("Expected battery violations, got %v", violations)
+	}
+}
+
+func TestGenerateWithSeed(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	for _, opts := range []GenerateOptions{
+		{Seed: 42},
+		{Seed: 42, DeviceType: "mobile"},
+		{Seed: 7, OS: "macos", Browser: "safari"},
+	} {
+		first, err := generator.Generate(&opts)
+		if err != nil {
+			t.Fatalf("Generate(%+v) failed: %v", opts, err)
+		}
+		second, err := generator.Generate(&opts)
+		if err != nil {
+			t.Fatalf("Generate(%+v) failed: %v", opts, err)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("Generate(%+v) should return identical fingerprints", opts)
+		}
+	}
+
+	seeded, _ := generator.Generate(&GenerateOptions{Seed: 42})
+	sourced, _ := generator.Generate(&GenerateOptions{Source: rand.NewSource(42)})
+	if !reflect.DeepEqual(seeded, sourced) {
+		t.Error("Source should produce the same fingerprint as the equal Seed")
+	}
+
+	other, _ := generator.Generate(&GenerateOptions{Seed: 43})
+	if reflect.DeepEqual(seeded, other) {
+		t.Error("Different seeds should produce different fingerprints")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	fp := NewChrome119MacOS()
+	data, err := fp.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if !reflect.DeepEqual(fp, decoded) {
+		t.Error("FromJSON should restore the fingerprint produced by ToJSON")
+	}
+
+	// Поля, добавленные после сохранения, остаются нулевыми
+	if _, err := FromJSON(`{"userAgent":"UA","screen":{"width":1920}}`); err != nil {
+		t.Errorf("FromJSON should accept JSON without newer fields: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		data  string
+		error string
+	}{
+		{"unknown field", `{"userAgent":"UA","gpu":"x"}`, `unknown field "gpu"`},
+		{"nested unknown field", `{"screen":{"size":1}}`, `unknown field "size"`},
+		{"wrong type", `{"screen":{"width":"1920"}}`, `field "screen.width"`},
+		{"trailing data", `{"userAgent":"UA"} {}`, "unexpected data"},
+		{"syntax", `{"userAgent":`, "failed to decode fingerprint"},
+	}
+	for _, tt := range tests {
+		_, err := FromJSON(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.error) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.error, err)
+		}
+	}
+}
+
+func TestSaveAndLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	fp, err := NewFingerprintGenerator().Generate(&GenerateOptions{Seed: 42})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	path := filepath.Join(dir, "profile.json")
+	profile := NewProfile(fp)
+	profile.Seed = 42
+	profile.Source = "generator"
+	if err := SaveProfile(path, profile); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	loaded, err := LoadProfile(path)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if loaded.Version != ProfileVersion || loaded.Seed != 42 || loaded.Source != "generator" {
+		t.Errorf("Profile metadata was not restored: %+v", loaded)
+	}
+	if !loaded.CreatedAt.Equal(profile.CreatedAt) {
+		t.Errorf("Expected createdAt %v, got %v", profile.CreatedAt, loaded.CreatedAt)
+	}
+	if !reflect.DeepEqual(fp, loaded.Fingerprint) {
+		t.Error("Loaded fingerprint should equal the saved one")
+	}
+
+	// SaveToFile/LoadFromFile и JSON без метаданных
+	if err := fp.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if loadedFP, err := LoadFromFile(path); err != nil || !reflect.DeepEqual(fp, loadedFP) {
+		t.Errorf("LoadFromFile should restore the fingerprint: %v", err)
+	}
+
+	bare := filepath.Join(dir, "bare.json")
+	data, _ := fp.ToJSON()
+	if err := os.WriteFile(bare, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if loadedFP, err := LoadFromFile(bare); err != nil || !reflect.DeepEqual(fp, loadedFP) {
+		t.Errorf("LoadFromFile should accept ToJSON output: %v", err)
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		error string
+	}{
+		{"newer version", `{"version":99,"fingerprint":{}}`, "newer than supported"},
+		{"invalid version", `{"version":0,"fingerprint":{}}`, "invalid profile version"},
+		{"no fingerprint", `{"version":1}`, "no fingerprint"},
+		{"unknown field", `{"version":1,"fingerprint":{},"owner":"me"}`, `unknown field "owner"`},
+		{"invalid createdAt", `{"version":1,"createdAt":"yesterday","fingerprint":{}}`, "yesterday"},
+		{"invalid fingerprint", `{"version":1,"fingerprint":{"hardwareConcurrency":"8"}}`, `field "fingerprint.hardwareConcurrency"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseProfile([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.error) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.error, err)
+		}
+	}
+
+	if _, err := LoadProfile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadProfile should fail for missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fp := NewChrome119Windows11()
+	fp.Platform = "iPhone"
+	fp.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	fp.Vendor = "Google Inc."
+	fp.WebGL.UnmaskedVendor = "NVIDIA Corporation"
+	fp.WebGL.UnmaskedRenderer = "NVIDIA GeForce RTX 3080"
+	fp.Screen.Width = 390
+	fp.Screen.Height = 844
+	fp.Screen.DevicePixelRatio = 1
+	fp.Languages = []string{"de-DE", "de"}
+	fp.Timezone = &Timezone{ID: "Europe/Berlin", Offset: 240}
+	fp.DeviceMemory = 16
+
+	violations := fp.Validate()
+	if !violations.HasErrors() || violations.Err() == nil {
+		t.Fatal("Inconsistent fingerprint should have errors")
+	}
+
+	got := make(map[string]bool)
+	for _, v := range violations {
+		got[v.Rule+" "+v.Field] = true
+	}
+	for _, expected := range []string{
+		RuleUserAgentPlatform + " platform",
+		RuleUserAgentVendor + " vendor",
+		RuleGPUPlatform + " webgl.unmaskedRenderer",
+		RuleScreenDevice + " screen.devicePixelRatio",
+		RuleScreenAvail + " screen.availWidth",
+		RuleLanguages + " languages",
+		RuleTimezone + " timezone.offset",
+		RuleHardware + " deviceMemory",
+	} {
+		if !got[expected] {
+			t.Errorf("Expected violation %q, got %v", expected, violations)
+		}
+	}
+}
+
+func TestValidateCustomRules(t *testing.T) {
+	rule := Rule{
+		Name: "no-linux",
+		Check: func(fp *Fingerprint) []Violation {
+			if platformOS(fp.Platform) == "linux" {
+				return []Violation{{Field: "platform", Severity: SeverityWarning, Message: "Linux is rare"}}
+			}
+			return nil
+		},
+	}
+
+	violations := NewChrome119Linux().Validate(rule)
+	if len(violations) != 1 || violations[0].Rule != "no-linux" {
+		t.Fatalf("Expected one violation of custom rule, got %v", violations)
+	}
+	if violations.HasErrors() || violations.Err() != nil {
+		t.Error("Warnings should not be reported as errors")
+	}
+}
+
+func TestGenerateIsConsistent(t *testing.T) {
+	generator := NewFingerprintGenerator()
+	for seed := int64(1); seed <= 500; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate(seed=%d) failed: %v", seed, err)
+		}
+		for _, v := range fp.Validate() {
+			t.Errorf("Generate(seed=%d): unexpected violation: %s", seed, v)
+		}
+	}
+}
+
+func TestGeneratePlugins(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	desktop, err := generator.Generate(&GenerateOptions{DeviceType: "desktop", Seed: 1})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !reflect.DeepEqual(desktop.Plugins, ChromePDFPlugins()) {
+		t.Errorf("Desktop fingerprint should have Chrome PDF plugins, got %v", desktop.Plugins)
+	}
+
+	mobile, err := generator.Generate(&GenerateOptions{DeviceType: "mobile", Seed: 1})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(mobile.Plugins) != 0 {
+		t.Errorf("Mobile fingerprint should have no plugins, got %v", mobile.Plugins)
+	}
+
+	plugins := ChromePDFPlugins()
+	if len(plugins) != 5 || plugins[0].Name != "PDF Viewer" || len(plugins[0].MimeTypes) != 2 {
+		t.Errorf("Unexpected Chrome PDF plugins: %v", plugins)
+	}
+}
+
+func TestGenerateWebGL(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	tests := []struct {
+		browser, os      string
+		vendor, renderer string
+	}{
+		{"chrome", "windows", "WebKit", "ANGLE ("},
+		{"firefox", "windows", "Mozilla", "ANGLE ("},
+		{"safari", "ios", "WebKit", "Apple GPU"},
+	}
+	for _, tt := range tests {
+		fp, err := generator.Generate(&GenerateOptions{Browser: tt.browser, OS: tt.os, Seed: 1})
+		if err != nil {
+			t.Fatalf("Generate(%s) failed: %v", tt.browser, err)
+		}
+		w := fp.WebGL
+		if w.Vendor != tt.vendor || !strings.HasPrefix(w.UnmaskedRenderer, tt.renderer) {
+			t.Errorf("%s: unexpected WebGL strings %q, %q", tt.browser, w.Vendor, w.UnmaskedRenderer)
+		}
+		if w.Parameters["MAX_TEXTURE_SIZE"] == 0 || len(w.ArrayParameters["MAX_VIEWPORT_DIMS"]) != 2 ||
+			len(w.Extensions) == 0 || w.ShaderPrecision["HIGH_FLOAT"].Precision == 0 {
+			t.Errorf("%s: WebGL profile should include GPU capabilities: %+v", tt.browser, w)
+		}
+	}
+
+	// Изменения fingerprint не затрагивают базу устройств
+	fp := NewChrome119Windows11()
+	fp.WebGL.Parameters["MAX_SAMPLES"] = 1
+	fp.WebGL.ArrayParameters["MAX_VIEWPORT_DIMS"][0] = 1
+	fp.WebGL.Extensions[0] = "changed"
+	if webglCapsIntel.Parameters["MAX_SAMPLES"] != 8 ||
+		webglCapsIntel.ArrayParameters["MAX_VIEWPORT_DIMS"][0] != 32767 ||
+		webglCapsIntel.Extensions[0] == "changed" {
+		t.Error("WebGL capabilities should be copied from the device database")
+	}
+}
+
+func TestGenerateWebGPU(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		w := fp.WebGPU
+		chrome := strings.Contains(fp.UserAgent, "Chrome/") && !strings.Contains(fp.UserAgent, "CriOS")
+		switch {
+		case !chrome:
+			if !w.Disable {
+				t.Errorf("Seed %d: WebGPU should be disabled for %q", seed, fp.UserAgent)
+			}
+		case fp.Platform == "Linux x86_64":
+			if !w.NoAdapter {
+				t.Errorf("Seed %d: Chrome on Linux should have no WebGPU adapter", seed)
+			}
+		case w.Vendor != "":
+			if w.Architecture == "" || len(w.Features) == 0 || w.Limits["maxTextureDimension2D"] == 0 {
+				t.Errorf("Seed %d: incomplete WebGPU adapter %+v", seed, w)
+			}
+		}
+	}
+
+	fp := NewChrome134Windows11()
+	fp.WebGPU.Limits["maxBufferSize"] = 1
+	if webgpuCapsDesktop.Limits["maxBufferSize"] != 4294967296 {
+		t.Error("WebGPU limits should be copied from the device database")
+	}
+}
+
+func TestValidateWebGPU(t *testing.T) {
+	fp := NewChrome134Windows11()
+	fp.WebGPU.Vendor = "amd"
+
+	violations := fp.Validate()
+	if len(violations) != 1 || violations[0].Rule != RuleWebGPU {
+		t.Errorf("Expected webgpu violation, got %v", violations)
+	}
+}
+
+func TestWebRTCMode(t *testing.T) {
+	tests := []struct {
+		webrtc *WebRTC
+		mode   string
+		policy string
+	}{
+		{nil, "", ""},
+		{&WebRTC{}, "", ""},
+		{&WebRTC{Disable: true}, WebRTCModeDisable, "disable_non_proxied_udp"},
+		{&WebRTC{Mode: WebRTCModeMask}, WebRTCModeMask, "default_public_interface_only"},
+		{&WebRTC{Mode: WebRTCModeProxyOnly, Disable: true}, WebRTCModeProxyOnly, "default_public_interface_only"},
+	}
+	for _, tt := range tests {
+		if mode, policy := tt.webrtc.mode(), tt.webrtc.ipHandlingPolicy(); mode != tt.mode || policy != tt.policy {
+			t.Errorf("%+v: expected mode %q and policy %q, got %q and %q", tt.webrtc, tt.mode, tt.policy, mode, policy)
+		}
+	}
+
+	fp := NewDefaultFingerprint()
+	fp.MediaFeatures = nil
+	if len(fp.AllocatorOptions()) != 0 {
+		t.Error("Default fingerprint should not need launch flags")
+	}
+	fp.WebRTC.Mode = WebRTCModeMask
+	if len(fp.AllocatorOptions()) != 1 {
+		t.Error("WebRTC mask mode should set the IP handling policy flag")
+	}
+	if len(NewChrome134Android().AllocatorOptions()) != 2 {
+		t.Error("Pointer, hover and HDR should set blink settings and color profile flags")
+	}
+
+	fp.WebRTC = &WebRTC{Mode: "leak", PublicIP: "1.2.3"}
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Field != "webrtc.mode" || violations[1].Field != "webrtc.publicIp" {
+		t.Errorf("Expected webrtc violations, got %v", violations)
+	}
+}
+
+func TestParseProfileMigratesWebGL(t *testing.T) {
+	data := `{"version":1,"fingerprint":{
+		"userAgent":"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+		"webgl":{"vendor":"Google Inc. (Intel)","renderer":"ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			"unmaskedVendor":"Intel Inc.","unmaskedRenderer":"Intel(R) UHD Graphics 630"}}}`
+
+	profile, err := ParseProfile([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	w := profile.Fingerprint.WebGL
+	if profile.Version != ProfileVersion || w.Vendor != "WebKit" || w.Renderer != "WebKit WebGL" ||
+		w.UnmaskedVendor != "Google Inc. (Intel)" || !strings.HasPrefix(w.UnmaskedRenderer, "ANGLE (Intel") ||
+		w.Version != "WebGL 1.0 (OpenGL ES 2.0 Chromium)" {
+		t.Errorf("Version 1 WebGL was not migrated: %+v", w)
+	}
+
+	// Профиль текущей версии не меняется
+	fp := NewChrome119Windows11()
+	current, _ := json.Marshal(NewProfile(fp))
+	if profile, err := ParseProfile(current); err != nil || !reflect.DeepEqual(profile.Fingerprint, fp) {
+		t.Errorf("Current profile should not be migrated: %v", err)
+	}
+}
+
+func TestMigrateTimezoneOffset(t *testing.T) {
+	for _, tt := range []struct {
+		data   string
+		offset int
+	}{
+		{`{"version":2,"fingerprint":{"timezone":{"id":"Asia/Tokyo","offset":540}}}`, -540},
+		{`{"version":2,"fingerprint":{"timezone":{"id":"Asia/Tokyo","offset":-540}}}`, -540},
+		// Летнее время в Бразилии отменено в 2019 году
+		{`{"version":1,"fingerprint":{"timezone":{"id":"America/Sao_Paulo","offset":-120}}}`, 120},
+		{`{"version":2,"fingerprint":{"timezone":{"id":"Mars/Olympus_Mons","offset":540}}}`, 540},
+		// JSON без версии уже в текущем формате
+		{`{"timezone":{"id":"America/Sao_Paulo","offset":-180}}`, -180},
+		{`{"timezone":{"id":"America/Sao_Paulo","offset":180}}`, 180},
+	} {
+		profile, err := ParseProfile([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("ParseProfile(%s) failed: %v", tt.data, err)
+		}
+		if profile.Fingerprint.Timezone.Offset != tt.offset {
+			t.Errorf("ParseProfile(%s): expected offset %d, got %d", tt.data, tt.offset, profile.Fingerprint.Timezone.Offset)
+		}
+	}
+
+	fp, err := FromJSON(`{"timezone":{"id":"Asia/Tokyo","offset":540}}`)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if fp.Timezone.Offset != 540 {
+		t.Errorf("FromJSON should not migrate offset, got %d", fp.Timezone.Offset)
+	}
+}
+
+func TestChromeBrands(t *testing.T) {
+	brands, fullVersionList := ChromeBrands(119, "119.0.6045.159")
+
+	expected := []Brand{
+		{Brand: "Google Chrome", Version: "119"},
+		{Brand: "Chromium", Version: "119"},
+		{Brand: "Not?A_Brand", Version: "24"},
+	}
+	if !reflect.DeepEqual(brands, expected) {
+		t.Errorf("Expected brands %v, got %v", expected, brands)
+	}
+	if fullVersionList[0].Version != "119.0.6045.159" || fullVersionList[2].Version != "24.0.0.0" {
+		t.Errorf("Unexpected full version list: %v", fullVersionList)
+	}
+}
+
+func TestGenerateClientHints(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		chrome := strings.Contains(fp.UserAgent, "Chrome/") && !strings.Contains(fp.UserAgent, "CriOS")
+		if chrome != (fp.ClientHints != nil) {
+			t.Errorf("Seed %d: ClientHints %v for User-Agent %q", seed, fp.ClientHints, fp.UserAgent)
+		}
+	}
+}
+
+func TestValidateClientHints(t *testing.T) {
+	fp := NewChrome134Windows11()
+	fp.ClientHints.Mobile = true
+	fp.ClientHints.Platform = "Android"
+
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Rule != RuleClientHints {
+		t.Errorf("Expected 2 client-hints violations, got %v", violations)
+	}
+
+	safari := NewSafari17iOS()
+	safari.ClientHints = NewChrome134Windows11().ClientHints
+	if !safari.Validate().HasErrors() {
+		t.Error("Client Hints in Safari fingerprint should be an error")
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		languages []string
+		expected  string
+	}{
+		{[]string{"de-DE", "de", "en-US", "en"}, "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7"},
+		{[]string{"en-US"}, "en-US"},
+		{[]string{"en-US", "en"}, "en-US,en;q=0.9"},
+		{nil, ""},
+		{
+			[]string{"a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9", "a10", "a11", "a12"},
+			"a1,a2;q=0.9,a3;q=0.8,a4;q=0.7,a5;q=0.6,a6;q=0.5,a7;q=0.4,a8;q=0.3,a9;q=0.2,a10;q=0.1,a11;q=0.1,a12;q=0.1",
+		},
+	}
+	for _, tt := range tests {
+		if got := AcceptLanguage(tt.languages); got != tt.expected {
+			t.Errorf("AcceptLanguage(%v) = %q, expected %q", tt.languages, got, tt.expected)
+		}
+	}
+
+	fp := NewDefaultFingerprint()
+	fp.Languages = nil
+	if got := fp.AcceptLanguage(); got != fp.Language {
+		t.Errorf("Expected Language when Languages is empty, got %q", got)
+	}
+}
+
+func TestValidateLanguages(t *testing.T) {
+	fp := NewDefaultFingerprint()
+	fp.Language = "de-DE"
+	fp.Languages = []string{"de-DE", "de", "en US", "de"}
+
+	violations := fp.Validate()
+	if len(violations) != 2 {
+		t.Fatalf("Expected 2 violations, got %v", violations)
+	}
+	if violations[0].Field != "languages[2]" || violations[0].Severity != SeverityError {
+		t.Errorf("Expected invalid tag error, got %v", violations[0])
+	}
+	if violations[1].Field != "languages[3]" || violations[1].Severity != SeverityWarning {
+		t.Errorf("Expected duplicate warning, got %v", violations[1])
+	}
+
+	fp.Languages = []string{"en-US", "de-DE"}
+	if !fp.Validate().HasErrors() {
+		t.Error("Language must equal Languages[0]")
+	}
+}
+
+func TestGenerateMediaDevices(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		m := fp.MediaDevices
+		if m == nil || m.Seed == 0 || len(m.Codecs) == 0 {
+			t.Fatalf("Seed %d: incomplete media devices %+v", seed, m)
+		}
+		kinds := map[string]int{}
+		facing := map[string]bool{}
+		for _, d := range m.Devices {
+			if d.Label == "" {
+				t.Errorf("Seed %d: device %+v has no label", seed, d)
+			}
+			kinds[d.Kind]++
+			facing[d.FacingMode] = true
+		}
+		if kinds[MediaAudioInput] == 0 {
+			t.Errorf("Seed %d: no microphone in %+v", seed, m.Devices)
+		}
+		switch platformOS(fp.Platform) {
+		case "ios", "android":
+			if !facing["user"] || !facing["environment"] {
+				t.Errorf("Seed %d: phone should have front and back cameras, got %+v", seed, m.Devices)
+			}
+		case "macos":
+			if kinds[MediaVideoInput] != 1 {
+				t.Errorf("Seed %d: MacBook should have a built-in camera, got %+v", seed, m.Devices)
+			}
+		}
+	}
+
+	a, _ := generator.Generate(&GenerateOptions{Seed: 7})
+	b, _ := generator.Generate(&GenerateOptions{Seed: 7})
+	if !reflect.DeepEqual(a.MediaDevices, b.MediaDevices) {
+		t.Error("Media devices should be deterministic for a seed")
+	}
+}
+
+func TestMediaCodecs(t *testing.T) {
+	win := NewChrome134Windows11().MediaDevices.Codecs
+	if !win["av01"].PowerEfficient || !win["hvc1"].Supported {
+		t.Errorf("RTX 3060 should decode AV1 and HEVC in hardware, got %+v", win)
+	}
+	linux := NewChrome119Linux().MediaDevices.Codecs
+	if linux["hvc1"].Supported || linux["avc1"].PowerEfficient {
+		t.Errorf("Chrome on Linux should not decode HEVC or H.264 in hardware, got %+v", linux)
+	}
+	ios := NewSafari17iOS().MediaDevices.Codecs
+	if ios["vorbis"].Supported || ios["av01"].Supported {
+		t.Errorf("Safari on iOS should not decode Vorbis or AV1, got %+v", ios)
+	}
+}
+
+func TestGenerateConnection(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	types := map[string]bool{}
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		c := fp.Connection
+		chrome := strings.Contains(fp.UserAgent, "Chrome/")
+		switch {
+		case !chrome:
+			if !c.Disable {
+				t.Errorf("Seed %d: navigator.connection should be removed for %q", seed, fp.UserAgent)
+			}
+		case fp.Platform == "Linux armv8l":
+			types[c.Type] = true
+			if c.DownlinkMax == 0 {
+				t.Errorf("Seed %d: Android connection should have downlinkMax, got %+v", seed, c)
+			}
+		case c.Type != "":
+			t.Errorf("Seed %d: desktop Chrome should have no connection type, got %+v", seed, c)
+		}
+	}
+	if !types["wifi"] || !types["cellular"] {
+		t.Errorf("Android connections should be wifi and cellular, got %v", types)
+	}
+}
+
+func TestEffectiveConnectionType(t *testing.T) {
+	for _, tt := range []struct {
+		rtt      int
+		downlink float64
+		want     string
+	}{
+		{50, 10, EffectiveType4G},
+		{300, 10, EffectiveType3G},
+		{100, 0.5, EffectiveType3G},
+		{1500, 1, EffectiveType2G},
+		{2000, 1, EffectiveTypeSlow2G},
+		{100, 0.04, EffectiveTypeSlow2G},
+	} {
+		if got := effectiveConnectionType(tt.rtt, tt.downlink); got != tt.want {
+			t.Errorf("effectiveConnectionType(%d, %g) = %q, want %q", tt.rtt, tt.downlink, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionNetworkConditions(t *testing.T) {
+	c := NewChrome134Android().Connection
+	params := c.networkConditions()
+	if params.Latency != 150 || params.DownloadThroughput != 400000 || params.UploadThroughput != -1 ||
+		params.ConnectionType != network.ConnectionTypeCellular4g {
+		t.Errorf("Unexpected network conditions %+v", params)
+	}
+}
+
+func TestValidateConnection(t *testing.T) {
+	fp := NewChrome134Windows11()
+	fp.Connection.Type = "wifi"
+	fp.Connection.RTT = 400
+
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Field != "connection.type" || violations[1].Field != "connection.effectiveType" {
+		t.Errorf("Expected connection violations, got %v", violations)
+	}
+
+	fp = NewSafari17iOS()
+	fp.Connection = &Connection{EffectiveType: EffectiveType4G, RTT: 50, Downlink: 10}
+	if violations := fp.Validate(); len(violations) != 1 || violations[0].Rule != RuleConnection {
+		t.Errorf("Expected connection violation for Safari, got %v", violations)
+	}
+}
+
+func TestGenerateMediaFeatures(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	schemes := map[string]bool{}
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		m := fp.MediaFeatures
+		schemes[m.ColorScheme] = true
+		mobile := fp.Platform == "iPhone" || fp.Platform == "iPad" || fp.Platform == "Linux armv8l"
+		if mobile != (m.Pointer == "coarse" && m.Hover == "none") {
+			t.Errorf("Seed %d: unexpected pointer %q and hover %q on %s", seed, m.Pointer, m.Hover, fp.Platform)
+		}
+		portrait := fp.Screen.Orientation == OrientationPortraitPrimary
+		if portrait != (fp.Screen.Height > fp.Screen.Width) {
+			t.Errorf("Seed %d: orientation %q does not match %dx%d", seed, fp.Screen.Orientation, fp.Screen.Width, fp.Screen.Height)
+		}
+	}
+	if !schemes["light"] || !schemes["dark"] {
+		t.Errorf("Color schemes should vary, got %v", schemes)
+	}
+}
+
+func TestEmulatedMediaFeatures(t *testing.T) {
+	features := NewChrome134Android().MediaFeatures.emulatedFeatures()
+	values := map[string]string{}
+	for _, f := range features {
+		values[f.Name] = f.Value
+	}
+	if values["color-gamut"] != "p3" || values["prefers-color-scheme"] != "light" || len(values) != 3 {
+		t.Errorf("Unexpected emulated media features %v", values)
+	}
+
+	// pointer и hover задаются флагом запуска
+	settings := NewChrome134Android().MediaFeatures.blinkSettings()
+	if settings != "primaryPointerType=2,availablePointerTypes=2,primaryHoverType=1,availableHoverTypes=1" {
+		t.Errorf("Unexpected blink settings %q", settings)
+	}
+	if settings := (&MediaFeatures{ColorScheme: "dark"}).blinkSettings(); settings != "" {
+		t.Errorf("Empty pointer and hover should not need blink settings, got %q", settings)
+	}
+
+	orientation := NewChrome134Android().Screen.screenOrientation()
+	if orientation.Type != "portraitPrimary" || orientation.Angle != 0 {
+		t.Errorf("Unexpected screen orientation %+v", orientation)
+	}
+}
+
+func TestValidateMediaFeatures(t *testing.T) {
+	fp := NewChrome134Android()
+	fp.Screen.Orientation = OrientationLandscapePrimary
+	fp.MediaFeatures.Pointer = "fine"
+
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Field != "screen.orientation" || violations[1].Field != "mediaFeatures.pointer" {
+		t.Errorf("Expected media features violations, got %v", violations)
+	}
+}
+
+func TestGenerateWindow(t *testing.T) {
+	generator := NewFingerprintGenerator()
+
+	maximized := map[bool]bool{}
+	for seed := int64(1); seed <= 200; seed++ {
+		fp, err := generator.Generate(&GenerateOptions{Seed: seed, DeviceType: "desktop"})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		w, s := fp.Window, fp.Screen
+		maximized[w.OuterWidth == s.AvailWidth] = true
+		if w.InnerWidth > w.OuterWidth || w.InnerHeight >= w.OuterHeight || w.OuterHeight > s.AvailHeight {
+			t.Errorf("Seed %d: viewport %dx%d should be smaller than window and screen", seed, w.InnerWidth, w.InnerHeight)
+		}
+		if w.ScreenY+w.OuterHeight > s.Height || w.ScreenX+w.OuterWidth > s.AvailWidth {
+			t.Errorf("Seed %d: window %+v does not fit in screen %+v", seed, w, s)
+		}
+	}
+	if !maximized[true] || !maximized[false] {
+		t.Error("Desktop windows should be both maximized and not")
+	}
+
+	fp := NewChrome134Android()
+	if fp.Window.OuterWidth != fp.Screen.Width || fp.Window.InnerHeight != fp.Screen.Height-56 {
+		t.Errorf("Phone window should fill the screen below the address bar, got %+v", fp.Window)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	fp := NewChrome134Android()
+	original := *fp.Screen
+	originalWindow := *fp.Window
+
+	rotated := fp.rotated(true)
+	if rotated == nil {
+		t.Fatal("Portrait phone should rotate to landscape")
+	}
+	if *fp.Screen != original || *fp.Window != originalWindow {
+		t.Errorf("Rotation should not modify the original fingerprint, got %+v and %+v", fp.Screen, fp.Window)
+	}
+	s, w := rotated.Screen, rotated.Window
+	if s.Width != original.Height || s.Height != original.Width || s.AvailWidth != original.AvailHeight {
+		t.Errorf("Screen should swap width and height, got %+v", s)
+	}
+	if s.Orientation != OrientationLandscapePrimary || s.OrientationAngle != 90 {
+		t.Errorf("Expected landscape-primary at 90, got %s at %d", s.Orientation, s.OrientationAngle)
+	}
+	if w.OuterWidth != s.Width || w.InnerWidth != s.Width || w.InnerHeight != s.Height-56 {
+		t.Errorf("Window should fill the rotated screen below the address bar, got %+v", w)
+	}
+	if violations := rotated.Validate(); len(violations) != 0 {
+		t.Errorf("Rotated fingerprint should be consistent, got %v", violations)
+	}
+
+	if rotated.rotated(true) != nil {
+		t.Error("Landscape phone should not rotate to landscape again")
+	}
+	back := rotated.rotated(false)
+	if back == nil || *back.Window != originalWindow || back.Screen.Width != original.Width || back.Screen.OrientationAngle != 0 {
+		t.Errorf("Rotating back should restore portrait, got %+v", back)
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	fp := NewChrome134Windows11()
+	fp.Window.InnerWidth = 2000
+	fp.Window.ScreenX = 100
+
+	violations := fp.Validate()
+	if len(violations) != 2 || violations[0].Field != "window.innerWidth" || violations[1].Severity != SeverityWarning {
+		t.Errorf("Expected window violations, got %v", violations)
+	}
+}
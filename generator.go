@@ -1,15 +1,16 @@
 package fingerprint
 
 import (
-	"crypto/rand"
 	"fmt"
-	"math/big"
+	"math"
+	"math/rand"
 	"strings"
 )
 
 // FingerprintGenerator генератор уникальных fingerprint'ов
 type FingerprintGenerator struct {
-	db *DeviceDatabase
+	db  *DeviceDatabase
+	rnd *random // источник случайности текущей генерации
 }
 
 // NewFingerprintGenerator создает новый генератор
@@ -24,6 +25,13 @@ type GenerateOptions struct {
 	DeviceType string // "desktop", "mobile", "tablet", "" (любой)
 	OS         string // "windows", "macos", "linux", "ios", "android", "" (любой)
 	Browser    string // "chrome", "firefox", "safari", "" (любой)
+
+	// Seed делает генерацию воспроизводимой: одинаковые Seed и опции
+	// дают одинаковый fingerprint на любой платформе.
+	// 0 - случайные значения из crypto/rand.
+	Seed int64
+	// Source источник случайности, имеет приоритет над Seed
+	Source rand.Source
 }
 
 // Generate генерирует логически связанный fingerprint
@@ -32,6 +40,10 @@ func (g *FingerprintGenerator) Generate(opts *GenerateOptions) (*Fingerprint, er
 		opts = &GenerateOptions{}
 	}
 
+	// Каждая генерация использует свой источник, поэтому генератор
+	// можно вызывать из нескольких горутин
+	g = &FingerprintGenerator{db: g.db, rnd: newRandom(opts.Source, opts.Seed)}
+
 	// Выбираем устройство
 	device, err := g.selectDevice(opts)
 	if err != nil {
@@ -47,36 +59,52 @@ func (g *FingerprintGenerator) Generate(opts *GenerateOptions) (*Fingerprint, er
 	// Выбираем GPU
 	gpu := g.selectGPU(device)
 
+	// Выбираем версию OS
+	osVersion := os.Versions[g.rnd.intn(len(os.Versions))]
+
 	// Генерируем User-Agent
-	userAgent := g.generateUserAgent(browser, os, device)
+	userAgent := g.generateUserAgent(browser, osVersion, device)
 
 	// Генерируем Screen
 	screen := g.generateScreen(device)
 
+	// Генерируем язык
+	language := g.generateLanguage()
+
 	// Генерируем остальные параметры
 	fingerprint := &Fingerprint{
-		UserAgent: userAgent,
-		Platform:  device.Platform,
-		Vendor:    g.getVendor(browser.Name),
-		Language:  g.generateLanguage(),
-		Languages: g.generateLanguages(),
-		Screen:    screen,
-		Timezone:  RandomTimezone(),
-		WebGL:     g.generateWebGL(gpu, device.Platform),
+		UserAgent:     userAgent,
+		Platform:      device.Platform,
+		Vendor:        g.getVendor(browser.Name, device.Platform),
+		Language:      language,
+		Languages:     g.generateLanguages(language),
+		Screen:        screen,
+		Window:        g.generateWindow(browser, device, screen),
+		MediaFeatures: g.generateMediaFeatures(device, gpu),
+		Timezone:      randomTimezone(g.rnd),
+		WebGL:         g.generateWebGL(gpu, device.Platform, userAgent),
+		WebGPU:        g.generateWebGPU(browser, device.Platform, gpu),
 		Canvas: &Canvas{
-			Noise: 0.01 + float64(randomInt(30))/1000.0,
+			Noise: 0.01 + float64(g.rnd.intn(30))/1000.0,
+			Seed:  g.rnd.seed(),
 		},
 		WebRTC: &WebRTC{
 			Disable: false,
 		},
 		Fonts:               g.generateFonts(device.Platform),
-		Plugins:             []Plugin{},
-		HardwareConcurrency: device.CPUCores[randomInt(len(device.CPUCores))],
-		DeviceMemory:        device.RAM[randomInt(len(device.RAM))],
-		Audio: &Audio{
-			Noise: 0.01 + float64(randomInt(30))/1000.0,
-		},
-		Battery: g.generateBattery(device.Type),
+		Plugins:             g.generatePlugins(device.Type),
+		HardwareConcurrency: device.CPUCores[g.rnd.intn(len(device.CPUCores))],
+		DeviceMemory:        deviceMemory(device.RAM[g.rnd.intn(len(device.RAM))]),
+		Audio:               g.generateAudio(device.Platform),
+		Battery:             g.generateBattery(device),
+		MediaDevices:        g.generateMediaDevices(browser, device, gpu),
+		Connection:          g.generateConnection(browser, device),
+		ClientHints:         g.generateClientHints(browser, osVersion, device, gpu),
+	}
+
+	// Самопроверка: генератор не должен выдавать несогласованный fingerprint
+	if err := fingerprint.Validate().Err(); err != nil {
+		return nil, fmt.Errorf("generated fingerprint is inconsistent: %w", err)
 	}
 
 	return fingerprint, nil
@@ -120,7 +148,7 @@ func (g *FingerprintGenerator) selectDevice(opts *GenerateOptions) (*DeviceSpec,
 	}
 
 	// Выбираем случайное устройство
-	return &candidates[randomInt(len(candidates))], nil
+	return &candidates[g.rnd.intn(len(candidates))], nil
 }
 
 // selectOS выбирает OS для устройства
@@ -142,7 +170,7 @@ func (g *FingerprintGenerator) selectOS(device *DeviceSpec, opts *GenerateOption
 		}
 	}
 
-	return &candidates[randomInt(len(candidates))]
+	return &candidates[g.rnd.intn(len(candidates))]
 }
 
 // selectBrowser выбирает браузер
@@ -168,7 +196,7 @@ func (g *FingerprintGenerator) selectBrowser(device *DeviceSpec, opts *GenerateO
 		return &BrowserVersion{Name: "Chrome", Version: "119.0.0.0", Major: 119}
 	}
 
-	return &candidates[randomInt(len(candidates))]
+	return &candidates[g.rnd.intn(len(candidates))]
 }
 
 // selectGPU выбирает GPU для устройства
@@ -217,49 +245,55 @@ func (g *FingerprintGenerator) selectGPU(device *DeviceSpec) *GPUSpec {
 		}
 	}
 
-	return &candidates[randomInt(len(candidates))]
+	return &candidates[g.rnd.intn(len(candidates))]
 }
 
 // generateUserAgent генерирует User-Agent
-func (g *FingerprintGenerator) generateUserAgent(browser *BrowserVersion, os *OSVersion, device *DeviceSpec) string {
+func (g *FingerprintGenerator) generateUserAgent(browser *BrowserVersion, osVersion string, device *DeviceSpec) string {
 	switch browser.Name {
 	case "Chrome":
-		return g.generateChromeUserAgent(browser, os, device)
+		return g.generateChromeUserAgent(browser, osVersion, device)
 	case "Firefox":
-		return g.generateFirefoxUserAgent(browser, os, device)
+		return g.generateFirefoxUserAgent(browser, osVersion, device)
 	case "Safari":
-		return g.generateSafariUserAgent(browser, os, device)
+		return g.generateSafariUserAgent(browser, osVersion, device)
 	default:
-		return g.generateChromeUserAgent(browser, os, device)
+		return g.generateChromeUserAgent(browser, osVersion, device)
 	}
 }
 
 // generateChromeUserAgent генерирует Chrome User-Agent
-func (g *FingerprintGenerator) generateChromeUserAgent(browser *BrowserVersion, os *OSVersion, device *DeviceSpec) string {
+func (g *FingerprintGenerator) generateChromeUserAgent(browser *BrowserVersion, osVersion string, device *DeviceSpec) string {
 	switch device.Platform {
 	case "Win32":
 		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", browser.Version)
 	case "MacIntel":
-		osVersion := os.Versions[randomInt(len(os.Versions))]
-		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X %s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36",
-			strings.Replace(osVersion, ".", "_", -1), browser.Version)
+		// Chrome замораживает версию macOS в User-Agent, настоящая
+		// версия доступна только через Client Hints
+		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", browser.Version)
 	case "Linux x86_64":
 		return fmt.Sprintf("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", browser.Version)
 	case "iPhone":
-		osVersion := os.Versions[randomInt(len(os.Versions))]
 		return fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/%s Mobile/15E148 Safari/604.1",
-			strings.Replace(osVersion, ".", "_", -1), browser.Major)
+			strings.Replace(osVersion, ".", "_", -1), browser.Version)
+	case "iPad":
+		return fmt.Sprintf("Mozilla/5.0 (iPad; CPU OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/%s Mobile/15E148 Safari/604.1",
+			strings.Replace(osVersion, ".", "_", -1), browser.Version)
 	case "Linux armv8l":
-		androidVersion := os.Versions[randomInt(len(os.Versions))]
-		return fmt.Sprintf("Mozilla/5.0 (Linux; Android %s; %s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Mobile Safari/537.36",
-			androidVersion, device.Name, browser.Version)
+		// Chrome на планшетах не добавляет "Mobile"
+		mobile := ""
+		if device.Type == "mobile" {
+			mobile = "Mobile "
+		}
+		return fmt.Sprintf("Mozilla/5.0 (Linux; Android %s; %s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s %sSafari/537.36",
+			osVersion, device.Name, browser.Version, mobile)
 	default:
 		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", browser.Version)
 	}
 }
 
 // generateFirefoxUserAgent генерирует Firefox User-Agent
-func (g *FingerprintGenerator) generateFirefoxUserAgent(browser *BrowserVersion, os *OSVersion, device *DeviceSpec) string {
+func (g *FingerprintGenerator) generateFirefoxUserAgent(browser *BrowserVersion, osVersion string, device *DeviceSpec) string {
 	switch device.Platform {
 	case "Win32":
 		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:%d.0) Gecko/20100101 Firefox/%s", browser.Major, browser.Version)
@@ -267,35 +301,96 @@ func (g *FingerprintGenerator) generateFirefoxUserAgent(browser *BrowserVersion,
 		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:%d.0) Gecko/20100101 Firefox/%s", browser.Major, browser.Version)
 	case "Linux x86_64":
 		return fmt.Sprintf("Mozilla/5.0 (X11; Linux x86_64; rv:%d.0) Gecko/20100101 Firefox/%s", browser.Major, browser.Version)
+	case "Linux armv8l":
+		formFactor := "Mobile"
+		if device.Type == "tablet" {
+			formFactor = "Tablet"
+		}
+		return fmt.Sprintf("Mozilla/5.0 (Android %s; %s; rv:%d.0) Gecko/%d.0 Firefox/%s",
+			osVersion, formFactor, browser.Major, browser.Major, browser.Version)
+	case "iPhone", "iPad":
+		// Firefox на iOS работает на WebKit
+		iosVersion := strings.Replace(osVersion, ".", "_", -1)
+		if device.Platform == "iPad" {
+			return fmt.Sprintf("Mozilla/5.0 (iPad; CPU OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/%s Mobile/15E148 Safari/605.1.15",
+				iosVersion, browser.Version)
+		}
+		return fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/%s Mobile/15E148 Safari/605.1.15",
+			iosVersion, browser.Version)
 	default:
 		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:%d.0) Gecko/20100101 Firefox/%s", browser.Major, browser.Version)
 	}
 }
 
 // generateSafariUserAgent генерирует Safari User-Agent
-func (g *FingerprintGenerator) generateSafariUserAgent(browser *BrowserVersion, os *OSVersion, device *DeviceSpec) string {
+func (g *FingerprintGenerator) generateSafariUserAgent(browser *BrowserVersion, osVersion string, device *DeviceSpec) string {
 	if device.Platform == "iPhone" {
-		osVersion := os.Versions[randomInt(len(os.Versions))]
 		return fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/15E148 Safari/604.1",
 			strings.Replace(osVersion, ".", "_", -1), browser.Version)
 	}
+	if device.Platform == "iPad" {
+		return fmt.Sprintf("Mozilla/5.0 (iPad; CPU OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/15E148 Safari/604.1",
+			strings.Replace(osVersion, ".", "_", -1), browser.Version)
+	}
 
 	// MacOS
 	return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Safari/605.1.15", browser.Version)
 }
 
+// generateClientHints генерирует User-Agent Client Hints.
+// Их поддерживает только Chrome, и не на iOS.
+func (g *FingerprintGenerator) generateClientHints(browser *BrowserVersion, osVersion string, device *DeviceSpec, gpu *GPUSpec) *ClientHints {
+	if browser.Name != "Chrome" || device.Platform == "iPhone" || device.Platform == "iPad" {
+		return nil
+	}
+
+	fullVersion := browser.FullVersion
+	if fullVersion == "" {
+		fullVersion = browser.Version
+	}
+
+	switch device.Platform {
+	case "Win32":
+		// Windows 11 сообщает версию платформы 13 и выше
+		platformVersion := "10.0.0"
+		if osVersion == "11.0" {
+			platformVersion = "15.0.0"
+		}
+		return newChromeClientHints(browser.Major, fullVersion, "Windows", platformVersion, "x86", "", false)
+	case "MacIntel":
+		architecture := "x86"
+		if gpu.Vendor == "Apple Inc." {
+			architecture = "arm"
+		}
+		return newChromeClientHints(browser.Major, fullVersion, "macOS", clientHintsVersion(osVersion), architecture, "", false)
+	case "Linux armv8l":
+		return newChromeClientHints(browser.Major, fullVersion, "Android", clientHintsVersion(osVersion), "", device.Name, device.Type == "mobile")
+	default:
+		return newChromeClientHints(browser.Major, fullVersion, "Linux", "", "x86", "", false)
+	}
+}
+
+// clientHintsVersion дополняет версию OS до трех компонентов ("14" -> "14.0.0")
+func clientHintsVersion(version string) string {
+	parts := strings.Split(version, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	return strings.Join(parts, ".")
+}
+
 // generateScreen генерирует параметры экрана
 func (g *FingerprintGenerator) generateScreen(device *DeviceSpec) *Screen {
-	width := device.ScreenWidths[randomInt(len(device.ScreenWidths))]
-	height := device.ScreenHeights[randomInt(len(device.ScreenHeights))]
-	dpr := device.DPRs[randomInt(len(device.DPRs))]
+	width := device.ScreenWidths[g.rnd.intn(len(device.ScreenWidths))]
+	height := device.ScreenHeights[g.rnd.intn(len(device.ScreenHeights))]
+	dpr := device.DPRs[g.rnd.intn(len(device.DPRs))]
 
 	availHeight := height
 	if device.Type == "desktop" {
 		availHeight = height - 40 // Панель задач
 	}
 
-	return &Screen{
+	screen := &Screen{
 		Width:            width,
 		Height:           height,
 		AvailWidth:       width,
@@ -304,25 +399,76 @@ func (g *FingerprintGenerator) generateScreen(device *DeviceSpec) *Screen {
 		PixelDepth:       24,
 		DevicePixelRatio: dpr,
 	}
+	screen.Orientation, screen.OrientationAngle = screen.orientation()
+	return screen
 }
 
-// generateWebGL генерирует WebGL параметры
-func (g *FingerprintGenerator) generateWebGL(gpu *GPUSpec, platform string) *WebGL {
-	vendor := fmt.Sprintf("Google Inc. (%s)", gpu.Vendor)
-	renderer := fmt.Sprintf("ANGLE (%s)", gpu.Renderer)
+// generateWindow генерирует окно браузера: на телефоне и планшете оно
+// занимает весь экран, на компьютере развернуто или занимает 60-95%
+// доступной области в случайном месте
+func (g *FingerprintGenerator) generateWindow(browser *BrowserVersion, device *DeviceSpec, screen *Screen) *Window {
+	w := newWindow(screen, browser.Name, device.Platform)
+	if device.Type != "desktop" || g.rnd.intn(2) == 0 {
+		return w
+	}
+
+	toolbar := w.OuterHeight - w.InnerHeight
+	top := availTop(screen, device.Platform)
+	w.OuterWidth = screen.AvailWidth * (60 + g.rnd.intn(36)) / 100
+	w.OuterHeight = screen.AvailHeight * (60 + g.rnd.intn(36)) / 100
+	w.ScreenX = g.rnd.intn(screen.AvailWidth - w.OuterWidth + 1)
+	w.ScreenY = top + g.rnd.intn(screen.AvailHeight-w.OuterHeight+1)
+
+	// У обычного окна на Windows невидимые рамки по 8px слева, справа и снизу
+	border := 0
+	if platformOS(device.Platform) == "windows" {
+		border = 8
+	}
+	w.InnerWidth = w.OuterWidth - 2*border
+	w.InnerHeight = w.OuterHeight - toolbar - border
+	return w
+}
 
-	if strings.Contains(platform, "Mac") || strings.Contains(platform, "iPhone") || strings.Contains(platform, "iPad") {
-		vendor = gpu.Vendor
-		renderer = gpu.Renderer
+// generateMediaFeatures генерирует медиа-функции CSS для устройства:
+// темная тема у трети пользователей, сокращение анимации - редко
+func (g *FingerprintGenerator) generateMediaFeatures(device *DeviceSpec, gpu *GPUSpec) *MediaFeatures {
+	m := newMediaFeatures(device.Platform, gpu.Renderer)
+	if g.rnd.intn(3) == 0 {
+		m.ColorScheme = "dark"
+	}
+	if g.rnd.intn(20) == 0 {
+		m.ReducedMotion = "reduce"
 	}
+	return m
+}
+
+// generateWebGL генерирует WebGL параметры: строки браузера, строки
+// видеокарты для платформы и возможности видеокарты из базы
+func (g *FingerprintGenerator) generateWebGL(gpu *GPUSpec, platform, userAgent string) *WebGL {
+	strs := browserWebGLStrings(userAgent)
+	unmaskedVendor, unmaskedRenderer := unmaskedWebGL(gpu, platform, userAgent)
+
+	return gpu.WebGL.applyTo(&WebGL{
+		Vendor:                 strs.vendor,
+		Renderer:               strs.renderer,
+		UnmaskedVendor:         unmaskedVendor,
+		UnmaskedRenderer:       unmaskedRenderer,
+		Version:                strs.version,
+		ShadingLanguageVersion: strs.shadingLanguageVersion,
+	})
+}
 
-	return &WebGL{
-		Vendor:                 vendor,
-		Renderer:               renderer,
-		UnmaskedVendor:         gpu.Vendor,
-		UnmaskedRenderer:       gpu.Renderer,
-		ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
+// generateWebGPU генерирует WebGPU: адаптер той же видеокарты, что и
+// в WebGL, или отсутствие WebGPU в браузере
+func (g *FingerprintGenerator) generateWebGPU(browser *BrowserVersion, platform string, gpu *GPUSpec) *WebGPU {
+	switch {
+	case !webgpuSupported(browser.Name, browser.Major, platform):
+		return &WebGPU{Disable: true}
+	case platformOS(platform) == "linux" || gpu.WebGPU == nil:
+		// Chrome на Linux не включает WebGPU по умолчанию
+		return &WebGPU{NoAdapter: true}
 	}
+	return gpu.WebGPU.adapter(webgpuVendor(gpu.Vendor), gpu.Architecture)
 }
 
 // generateFonts генерирует список шрифтов
@@ -343,15 +489,48 @@ func (g *FingerprintGenerator) generateFonts(platform string) []string {
 	}
 }
 
+// generatePlugins генерирует список плагинов. Desktop-браузеры
+// (Chrome, Firefox, Safari) отдают пять записей просмотрщика PDF,
+// мобильные браузеры - пустой список.
+func (g *FingerprintGenerator) generatePlugins(deviceType string) []Plugin {
+	if deviceType != "desktop" {
+		return []Plugin{}
+	}
+	return ChromePDFPlugins()
+}
+
+// generateAudio генерирует параметры Audio Context
+func (g *FingerprintGenerator) generateAudio(platform string) *Audio {
+	audio := &Audio{
+		Noise:           0.01 + float64(g.rnd.intn(30))/1000.0,
+		SampleRate:      48000,
+		MaxChannelCount: 2,
+	}
+
+	switch platform {
+	case "MacIntel":
+		sampleRates := []float64{44100, 48000}
+		audio.SampleRate = sampleRates[g.rnd.intn(len(sampleRates))]
+		audio.BaseLatency = 256 / audio.SampleRate
+	case "Linux x86_64":
+		audio.BaseLatency = 256 / audio.SampleRate
+	case "iPhone", "iPad":
+		// Safari не поддерживает baseLatency, оставляем без переопределения
+	default:
+		audio.BaseLatency = 0.01
+	}
+
+	return audio
+}
+
 // generateLanguage генерирует основной язык
 func (g *FingerprintGenerator) generateLanguage() string {
 	languages := []string{"en-US", "en-GB", "de-DE", "fr-FR", "es-ES", "ru-RU", "zh-CN", "ja-JP"}
-	return languages[randomInt(len(languages))]
+	return languages[g.rnd.intn(len(languages))]
 }
 
-// generateLanguages генерирует список языков
-func (g *FingerprintGenerator) generateLanguages() []string {
-	primary := g.generateLanguage()
+// generateLanguages генерирует список языков, начинающийся с основного
+func (g *FingerprintGenerator) generateLanguages(primary string) []string {
 	base := strings.Split(primary, "-")[0]
 
 	result := []string{primary}
@@ -365,9 +544,11 @@ func (g *FingerprintGenerator) generateLanguages() []string {
 	return result
 }
 
-// generateBattery генерирует параметры батареи
-func (g *FingerprintGenerator) generateBattery(deviceType string) *Battery {
-	if deviceType == "desktop" {
+// generateBattery генерирует параметры батареи. Компьютер без батареи
+// Chrome описывает как заряженную батарею на зарядке, ноутбук бывает
+// подключен к сети, телефон чаще работает от батареи.
+func (g *FingerprintGenerator) generateBattery(device *DeviceSpec) *Battery {
+	if device.Type == "desktop" && !device.Laptop {
 		return &Battery{
 			Charging:        true,
 			ChargingTime:    0,
@@ -376,20 +557,78 @@ func (g *FingerprintGenerator) generateBattery(deviceType string) *Battery {
 		}
 	}
 
-	// Mobile/Tablet
-	level := 0.5 + float64(randomInt(50))/100.0
-	charging := randomInt(2) == 0
+	// Полные время зарядки и работы от батареи в секундах
+	fullCharge, fullDischarge := 7200.0, 36000.0
+	if device.Laptop {
+		fullCharge, fullDischarge = 9000.0, 43200.0
+		if g.rnd.intn(3) == 0 {
+			return &Battery{Charging: true, Level: 1.0}
+		}
+	}
 
+	level := float64(20+g.rnd.intn(80)) / 100.0
+	if g.rnd.intn(3) == 0 {
+		return &Battery{
+			Charging:     true,
+			ChargingTime: math.Round((1 - level) * fullCharge),
+			Level:        level,
+		}
+	}
 	return &Battery{
-		Charging:        charging,
-		ChargingTime:    0,
-		DischargingTime: float64(10000 + randomInt(10000)),
+		Charging:        false,
+		DischargingTime: math.Round(level * fullDischarge),
 		Level:           level,
 	}
 }
 
-// getVendor возвращает vendor для браузера
-func (g *FingerprintGenerator) getVendor(browserName string) string {
+// generateMediaDevices генерирует устройства: у телефонов и планшетов
+// фронтальная и основная камеры, у MacBook встроенные камера, микрофон
+// и динамики, у остальных компьютеров веб-камера есть не всегда
+func (g *FingerprintGenerator) generateMediaDevices(browser *BrowserVersion, device *DeviceSpec, gpu *GPUSpec) *MediaDevices {
+	webcam := device.Platform == "MacIntel" || g.rnd.intn(2) == 0
+	media := newMediaDevices(browser.Name, device.Platform, device.Name, webcam, gpu.Renderer)
+	media.Seed = g.rnd.seed()
+	return media
+}
+
+// generateConnection генерирует navigator.connection. Network Information
+// API есть только в Chrome (кроме Chrome на iOS); компьютеры подключены
+// к быстрой сети, телефоны - через Wi-Fi или мобильную сеть.
+func (g *FingerprintGenerator) generateConnection(browser *BrowserVersion, device *DeviceSpec) *Connection {
+	os := platformOS(device.Platform)
+	if browser.Name != "Chrome" || os == "ios" {
+		return &Connection{Disable: true}
+	}
+
+	c := &Connection{}
+	switch {
+	case os != "android":
+		c.RTT = 50 * (1 + g.rnd.intn(3))
+		c.Downlink = float64(150+g.rnd.intn(851)) / 100.0
+	case device.Type == "tablet" || g.rnd.intn(2) == 0:
+		wifiSpeeds := []float64{72.2, 144.4, 300, 433.3, 866.7}
+		c.Type = "wifi"
+		c.RTT = 50 * (1 + g.rnd.intn(3))
+		c.Downlink = float64(150+g.rnd.intn(851)) / 100.0
+		c.DownlinkMax = wifiSpeeds[g.rnd.intn(len(wifiSpeeds))]
+	default:
+		// Максимальная скорость LTE в Chrome на Android
+		c.Type = "cellular"
+		c.RTT = 50 * (2 + g.rnd.intn(5))
+		c.Downlink = float64(60+g.rnd.intn(941)) / 100.0
+		c.DownlinkMax = 100
+	}
+	c.EffectiveType = effectiveConnectionType(c.RTT, c.Downlink)
+	return c
+}
+
+// getVendor возвращает vendor для браузера.
+// На iOS все браузеры работают на WebKit и отдают vendor Apple.
+func (g *FingerprintGenerator) getVendor(browserName, platform string) string {
+	if platform == "iPhone" || platform == "iPad" {
+		return "Apple Computer, Inc."
+	}
+
 	switch browserName {
 	case "Chrome":
 		return "Google Inc."
@@ -402,14 +641,12 @@ func (g *FingerprintGenerator) getVendor(browserName string) string {
 	}
 }
 
-// secureRandomInt безопасный генератор случайных чисел
-func secureRandomInt(max int) int {
-	if max <= 0 {
-		return 0
-	}
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
-	if err != nil {
-		return 0
+// deviceMemory возвращает navigator.deviceMemory для объема памяти в ГБ:
+// Chrome округляет объем вниз до степени двойки и ограничивает 8 ГБ
+func deviceMemory(ram int) int {
+	memory := 1
+	for memory*2 <= ram && memory < 8 {
+		memory *= 2
 	}
-	return int(n.Int64())
+	return memory
 }
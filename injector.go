@@ -2,276 +2,195 @@ package fingerprint
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 )
 
 // Injector отвечает за инжектирование fingerprint в браузер
 type Injector struct {
 	fingerprint *Fingerprint
+	patches     *PatchRegistry
+	without     []string
+	autoAttach  bool
+	wrapWorkers bool
+	errorf      func(string, ...interface{})
+
+	mu       sync.Mutex
+	stops    []context.CancelFunc
+	browsers map[*chromedp.Browser]bool
+	scripts  map[target.ID]page.ScriptIdentifier
+}
+
+// InjectorOption опция инжектора
+type InjectorOption func(*Injector)
+
+// WithPatches добавляет патчи в скрипт инжектирования.
+// Патч с именем встроенного заменяет встроенный.
+func WithPatches(patches ...Patch) InjectorOption {
+	return func(inj *Injector) {
+		for _, p := range patches {
+			inj.patches.Register(p)
+		}
+	}
+}
+
+// WithoutPatches отключает патчи по имени.
+// Патчи, зависящие от отключенных, также не подключаются.
+func WithoutPatches(names ...string) InjectorOption {
+	return func(inj *Injector) {
+		inj.without = append(inj.without, names...)
+	}
+}
+
+// WithAutoAttach включает в ApplyAll режим auto-attach (см. EnableAutoAttach)
+func WithAutoAttach() InjectorOption {
+	return func(inj *Injector) {
+		inj.autoAttach = true
+	}
+}
+
+// WithWorkerWrapper оборачивает конструктор Worker (патч "workers"):
+// Dedicated Worker запускается из blob: с прелюдией fingerprint, поэтому
+// переопределения действуют и без auto-attach. Сайт, политика CSP которого
+// запрещает blob: воркеры (worker-src), получит вместо воркера ошибку,
+// поэтому по умолчанию обертка выключена и Dedicated Worker'ы получают
+// fingerprint в режиме auto-attach.
+func WithWorkerWrapper() InjectorOption {
+	return func(inj *Injector) {
+		inj.wrapWorkers = true
+	}
+}
+
+// WithErrorf задает функцию для ошибок, возникающих в фоне
+// (например, при применении fingerprint к новой вкладке).
+// По умолчанию используется log.Printf.
+func WithErrorf(f func(string, ...interface{})) InjectorOption {
+	return func(inj *Injector) {
+		inj.errorf = f
+	}
 }
 
 // NewInjector создает новый инжектор с заданным fingerprint
-func NewInjector(fingerprint *Fingerprint) *Injector {
-	return &Injector{
+func NewInjector(fingerprint *Fingerprint, opts ...InjectorOption) *Injector {
+	inj := &Injector{
 		fingerprint: fingerprint,
+		patches:     DefaultPatchRegistry(),
+		errorf:      log.Printf,
+	}
+	for _, opt := range opts {
+		opt(inj)
 	}
+	if !inj.wrapWorkers {
+		inj.without = append(inj.without, PatchWorkers)
+	}
+	return inj
+}
+
+// Fingerprint возвращает текущий fingerprint инжектора. После Rotate это
+// копия с повернутыми экраном и окном. Возвращенный fingerprint нельзя
+// изменять: его читают действия инжектора, в том числе в фоне.
+func (inj *Injector) Fingerprint() *Fingerprint {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.fingerprint
+}
+
+// Patches возвращает реестр патчей инжектора
+func (inj *Injector) Patches() *PatchRegistry {
+	return inj.patches
 }
 
-// GetInjectionScript возвращает JavaScript код для инжектирования fingerprint
+// GetInjectionScript возвращает JavaScript код для инжектирования fingerprint.
+// Если fingerprint не удается сериализовать или патчи имеют неразрешимые
+// зависимости, возвращается пустая строка;
+// чтобы получить ошибку, используйте BuildInjectionScript.
 func (inj *Injector) GetInjectionScript() string {
-	fp := inj.fingerprint
-
-	script := fmt.Sprintf(`
-(function() {
-	'use strict';
-
-	// Переопределяем navigator.userAgent
-	Object.defineProperty(navigator, 'userAgent', {
-		get: function() { return '%s'; }
-	});
-
-	// Переопределяем navigator.platform
-	Object.defineProperty(navigator, 'platform', {
-		get: function() { return '%s'; }
-	});
-
-	// Переопределяем navigator.vendor
-	Object.defineProperty(navigator, 'vendor', {
-		get: function() { return '%s'; }
-	});
-
-	// Переопределяем navigator.language
-	Object.defineProperty(navigator, 'language', {
-		get: function() { return '%s'; }
-	});
-
-	// Переопределяем navigator.languages
-	Object.defineProperty(navigator, 'languages', {
-		get: function() { return %s; }
-	});
-
-	// Переопределяем navigator.hardwareConcurrency
-	Object.defineProperty(navigator, 'hardwareConcurrency', {
-		get: function() { return %d; }
-	});
-
-	// Переопределяем navigator.deviceMemory
-	Object.defineProperty(navigator, 'deviceMemory', {
-		get: function() { return %d; }
-	});
-`,
-		fp.UserAgent,
-		fp.Platform,
-		fp.Vendor,
-		fp.Language,
-		toJSArray(fp.Languages),
-		fp.HardwareConcurrency,
-		fp.DeviceMemory,
-	)
-
-	// Экран
-	if fp.Screen != nil {
-		script += fmt.Sprintf(`
-	// Переопределяем screen параметры
-	Object.defineProperty(screen, 'width', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(screen, 'height', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(screen, 'availWidth', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(screen, 'availHeight', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(screen, 'colorDepth', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(screen, 'pixelDepth', {
-		get: function() { return %d; }
-	});
-	Object.defineProperty(window, 'devicePixelRatio', {
-		get: function() { return %f; }
-	});
-`,
-			fp.Screen.Width,
-			fp.Screen.Height,
-			fp.Screen.AvailWidth,
-			fp.Screen.AvailHeight,
-			fp.Screen.ColorDepth,
-			fp.Screen.PixelDepth,
-			fp.Screen.DevicePixelRatio,
-		)
-	}
-
-	// WebGL
-	if fp.WebGL != nil {
-		script += fmt.Sprintf(`
-	// Переопределяем WebGL параметры
-	const getParameter = WebGLRenderingContext.prototype.getParameter;
-	WebGLRenderingContext.prototype.getParameter = function(parameter) {
-		if (parameter === 37445) {
-			return '%s';
-		}
-		if (parameter === 37446) {
-			return '%s';
-		}
-		return getParameter.call(this, parameter);
-	};
+	script, err := inj.BuildInjectionScript()
+	if err != nil {
+		return ""
+	}
+	return script
+}
 
-	const getParameter2 = WebGL2RenderingContext.prototype.getParameter;
-	WebGL2RenderingContext.prototype.getParameter = function(parameter) {
-		if (parameter === 37445) {
-			return '%s';
-		}
-		if (parameter === 37446) {
-			return '%s';
-		}
-		return getParameter2.call(this, parameter);
-	};
-`,
-			fp.WebGL.Vendor,
-			fp.WebGL.Renderer,
-			fp.WebGL.Vendor,
-			fp.WebGL.Renderer,
-		)
-	}
-
-	// Canvas fingerprinting защита
-	if fp.Canvas != nil && fp.Canvas.Noise > 0 {
-		script += fmt.Sprintf(`
-	// Добавляем шум к Canvas для защиты от fingerprinting
-	const originalToDataURL = HTMLCanvasElement.prototype.toDataURL;
-	HTMLCanvasElement.prototype.toDataURL = function() {
-		const context = this.getContext('2d');
-		if (context) {
-			const imageData = context.getImageData(0, 0, this.width, this.height);
-			const noise = %f;
-			for (let i = 0; i < imageData.data.length; i += 4) {
-				imageData.data[i] = imageData.data[i] + Math.random() * noise;
-				imageData.data[i + 1] = imageData.data[i + 1] + Math.random() * noise;
-				imageData.data[i + 2] = imageData.data[i + 2] + Math.random() * noise;
-			}
-			context.putImageData(imageData, 0, 0);
-		}
-		return originalToDataURL.apply(this, arguments);
-	};
-`,
-			fp.Canvas.Noise,
-		)
-	}
-
-	// WebRTC
-	if fp.WebRTC != nil && fp.WebRTC.Disable {
-		script += `
-	// Отключаем WebRTC
-	navigator.getUserMedia = undefined;
-	navigator.mediaDevices.getUserMedia = undefined;
-	navigator.mediaDevices.enumerateDevices = function() { return Promise.resolve([]); };
-	window.RTCPeerConnection = undefined;
-	window.RTCSessionDescription = undefined;
-	window.RTCIceCandidate = undefined;
-`
-	}
-
-	// Battery API
-	if fp.Battery != nil {
-		script += fmt.Sprintf(`
-	// Переопределяем Battery API
-	const originalGetBattery = navigator.getBattery;
-	navigator.getBattery = function() {
-		return Promise.resolve({
-			charging: %t,
-			chargingTime: %f,
-			dischargingTime: %f,
-			level: %f,
-			addEventListener: function() {},
-			removeEventListener: function() {}
-		});
-	};
-`,
-			fp.Battery.Charging,
-			fp.Battery.ChargingTime,
-			fp.Battery.DischargingTime,
-			fp.Battery.Level,
-		)
-	}
-
-	// Timezone
-	if fp.Timezone != nil {
-		script += fmt.Sprintf(`
-	// Переопределяем Timezone
-	Date.prototype.getTimezoneOffset = function() {
-		return %d;
-	};
-	Intl.DateTimeFormat.prototype.resolvedOptions = function() {
-		return {
-			locale: '%s',
-			calendar: 'gregory',
-			numberingSystem: 'latn',
-			timeZone: '%s',
-			year: 'numeric',
-			month: 'numeric',
-			day: 'numeric'
-		};
-	};
-`,
-			fp.Timezone.Offset,
-			fp.Language,
-			fp.Timezone.ID,
-		)
-	}
-
-	// Скрываем следы автоматизации
-	script += `
-	// Скрываем webdriver
-	Object.defineProperty(navigator, 'webdriver', {
-		get: function() { return undefined; }
-	});
-
-	// Удаляем chrome.runtime
-	if (window.chrome && window.chrome.runtime) {
-		delete window.chrome.runtime;
-	}
-
-	// Переопределяем permissions
-	const originalQuery = window.navigator.permissions.query;
-	window.navigator.permissions.query = function(parameters) {
-		if (parameters.name === 'notifications') {
-			return Promise.resolve({ state: 'denied' });
-		}
-		return originalQuery.apply(this, arguments);
-	};
+// BuildInjectionScript собирает JavaScript код для инжектирования fingerprint.
+// Скрипт состоит из включенных патчей в порядке зависимостей.
+// Все значения fingerprint передаются в скрипт одним JSON-объектом,
+// поэтому для одного и того же Fingerprint результат побайтово совпадает.
+func (inj *Injector) BuildInjectionScript() (string, error) {
+	fp := inj.Fingerprint()
+	patches, err := inj.patches.Resolve(fp, inj.without...)
+	if err != nil {
+		return "", err
+	}
+	return renderInjectionScript(fp, patches)
+}
+
+// BuildWorkerScript собирает JavaScript код для выполнения внутри воркера
+// (Dedicated, Shared или Service Worker). В него входят только патчи,
+// реализующие WorkerPatch. Dedicated Worker'ы, созданные страницей,
+// получают этот код в режиме auto-attach или, с опцией WithWorkerWrapper,
+// через обертку конструктора Worker.
+func (inj *Injector) BuildWorkerScript() (string, error) {
+	fp := inj.Fingerprint()
+	patches, err := inj.patches.Resolve(fp, inj.without...)
+	if err != nil {
+		return "", err
+	}
+	return renderWorkerScript(fp, patches)
+}
 
-	// Добавляем плагины
-	Object.defineProperty(navigator, 'plugins', {
-		get: function() {
-			return [];
+// InjectWorker применяет fingerprint в текущей цели-воркере.
+// В воркерах нет домена Emulation, поэтому User-Agent задается через Network.
+func (inj *Injector) InjectWorker(ctx context.Context) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		script, err := inj.BuildWorkerScript()
+		if err != nil {
+			return err
 		}
-	});
 
-	console.log('🔒 Fingerprint injected successfully');
-})();
-`
+		// Network.setUserAgentOverride принимает те же параметры, что и
+		// Emulation.setUserAgentOverride, но в cdproto отдельной функции нет
+		if err := cdp.Execute(ctx, "Network.setUserAgentOverride", inj.userAgentOverride(), nil); err != nil {
+			return fmt.Errorf("failed to set worker user agent: %w", err)
+		}
 
-	return script
+		_, exp, err := runtime.Evaluate(script).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate worker script: %w", err)
+		}
+		if exp != nil {
+			return fmt.Errorf("failed to evaluate worker script: %w", exp)
+		}
+		return nil
+	})
 }
 
 // Inject инжектирует fingerprint в текущую страницу
 func (inj *Injector) Inject(ctx context.Context) chromedp.Action {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		script := inj.GetInjectionScript()
+		script, err := inj.BuildInjectionScript()
+		if err != nil {
+			return err
+		}
 
 		// Инжектируем скрипт на всех страницах
-		_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+		id, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to add script: %w", err)
 		}
+		inj.setScript(ctx, id)
 
 		// Также выполняем скрипт на текущей странице
 		var res interface{}
@@ -283,55 +202,188 @@ func (inj *Injector) Inject(ctx context.Context) chromedp.Action {
 	})
 }
 
-// SetUserAgentOverride устанавливает User-Agent через CDP
+// setScript запоминает скрипт, добавленный для новых документов цели
+func (inj *Injector) setScript(ctx context.Context, id page.ScriptIdentifier) {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return
+	}
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	if inj.scripts == nil {
+		inj.scripts = make(map[target.ID]page.ScriptIdentifier)
+	}
+	inj.scripts[c.Target.TargetID] = id
+}
+
+// updateScript заменяет скрипт для новых документов текущей цели
+// скриптом по текущему fingerprint. Если Inject для цели не выполнялся,
+// ничего не делает.
+func (inj *Injector) updateScript(ctx context.Context) error {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return nil
+	}
+	inj.mu.Lock()
+	old, ok := inj.scripts[c.Target.TargetID]
+	inj.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	script, err := inj.BuildInjectionScript()
+	if err != nil {
+		return err
+	}
+	if err := page.RemoveScriptToEvaluateOnNewDocument(old).Do(ctx); err != nil {
+		return err
+	}
+	id, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+	if err != nil {
+		return err
+	}
+	inj.setScript(ctx, id)
+	return nil
+}
+
+// SetUserAgentOverride устанавливает User-Agent через CDP.
+// Если в fingerprint есть ClientHints, они передаются как userAgentMetadata
+// и попадают в заголовки Sec-CH-UA* и navigator.userAgentData.
 func (inj *Injector) SetUserAgentOverride(ctx context.Context) chromedp.Action {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		return emulation.SetUserAgentOverride(inj.fingerprint.UserAgent).
-			WithAcceptLanguage(inj.fingerprint.Language).
-			WithPlatform(inj.fingerprint.Platform).
-			Do(ctx)
+		return inj.userAgentOverride().Do(ctx)
+	})
+}
+
+// userAgentOverride возвращает параметры переопределения User-Agent
+func (inj *Injector) userAgentOverride() *emulation.SetUserAgentOverrideParams {
+	fp := inj.Fingerprint()
+	params := emulation.SetUserAgentOverride(fp.UserAgent).
+		WithAcceptLanguage(inj.acceptLanguages()).
+		WithPlatform(fp.Platform)
+	if fp.ClientHints != nil {
+		params = params.WithUserAgentMetadata(fp.ClientHints.userAgentMetadata())
+	}
+	return params
+}
+
+// acceptLanguages возвращает список языков для переопределения User-Agent.
+// Chrome отдает его в navigator.languages и сам добавляет q-значения
+// в заголовок Accept-Language (см. AcceptLanguage), поэтому список
+// передается без них.
+func (inj *Injector) acceptLanguages() string {
+	return strings.Join(inj.Fingerprint().languageList(), ",")
+}
+
+// SetLocaleOverride устанавливает локаль ICU по Fingerprint.Language через
+// CDP: от нее зависят Intl, toLocaleString и localeCompare без явной локали
+func (inj *Injector) SetLocaleOverride(ctx context.Context) chromedp.Action {
+	language := inj.Fingerprint().Language
+	if language == "" {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		err := emulation.SetLocaleOverride().WithLocale(language).Do(ctx)
+		// Локаль ICU общая для процесса: попап в процессе вкладки, которая
+		// его открыла, уже получил ее переопределение
+		var cdpErr *cdproto.Error
+		if errors.As(err, &cdpErr) && strings.HasPrefix(cdpErr.Message, "Another locale override is already in effect") {
+			return nil
+		}
+		return err
 	})
 }
 
 // SetTimezoneOverride устанавливает временную зону через CDP
 func (inj *Injector) SetTimezoneOverride(ctx context.Context) chromedp.Action {
-	if inj.fingerprint.Timezone == nil {
+	tz := inj.Fingerprint().Timezone
+	if tz == nil {
 		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
 	}
 
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		return emulation.SetTimezoneOverride(inj.fingerprint.Timezone.ID).Do(ctx)
+		return emulation.SetTimezoneOverride(tz.ID).Do(ctx)
 	})
 }
 
-// SetDeviceMetrics устанавливает viewport и метрики устройства через CDP
+// SetNetworkConditions ограничивает сеть через CDP, чтобы реальные
+// задержка и скорость соответствовали navigator.connection.
+// Действует только при Connection.Throttle.
+func (inj *Injector) SetNetworkConditions(ctx context.Context) chromedp.Action {
+	c := inj.Fingerprint().Connection
+	if c == nil || c.Disable || !c.Throttle {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := network.Enable().Do(ctx); err != nil {
+			return err
+		}
+		return c.networkConditions().Do(ctx)
+	})
+}
+
+// SetDeviceMetrics устанавливает viewport и метрики устройства через CDP.
+// Viewport - внутренний размер Window, а без него - размер экрана.
 func (inj *Injector) SetDeviceMetrics(ctx context.Context) chromedp.Action {
-	if inj.fingerprint.Screen == nil {
+	fp := inj.Fingerprint()
+	if fp.Screen == nil {
 		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
 	}
 
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		screen := inj.fingerprint.Screen
+		screen := fp.Screen
+		width, height := fp.viewport()
 
 		// Определяем, мобильное ли это устройство
 		isMobile := inj.isMobileDevice()
 
 		// Устанавливаем метрики устройства
-		return emulation.SetDeviceMetricsOverride(
-			int64(screen.Width),
-			int64(screen.Height),
+		err := emulation.SetDeviceMetricsOverride(
+			int64(width),
+			int64(height),
 			screen.DevicePixelRatio,
 			isMobile,
 		).WithScreenWidth(int64(screen.Width)).
 			WithScreenHeight(int64(screen.Height)).
+			WithScreenOrientation(screen.screenOrientation()).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Полосы прокрутки поверх страницы не занимают места в viewport
+		if w := fp.Window; w != nil && w.ScrollbarWidth == 0 {
+			return emulation.SetScrollbarsHidden(true).Do(ctx)
+		}
+		return nil
+	})
+}
+
+// SetEmulatedMedia устанавливает медиа-функции CSS prefers-color-scheme,
+// prefers-reduced-motion и color-gamut через CDP: они действуют и в @media,
+// и в matchMedia. pointer, hover и dynamic-range задаются флагами запуска
+// (см. Fingerprint.AllocatorOptions).
+func (inj *Injector) SetEmulatedMedia(ctx context.Context) chromedp.Action {
+	media := inj.Fingerprint().MediaFeatures
+	if media == nil {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		return emulation.SetEmulatedMedia().
+			WithFeatures(media.emulatedFeatures()).
 			Do(ctx)
 	})
 }
 
 // SetTouchEmulation включает эмуляцию touch событий для мобильных устройств
+// или для MediaFeatures.Pointer "coarse". Вместе с ней Chrome сообщает
+// (pointer: coarse) и (hover: none).
 func (inj *Injector) SetTouchEmulation(ctx context.Context) chromedp.Action {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		if inj.isMobileDevice() {
+		if inj.hasTouch() {
 			// Включаем touch эмуляцию для мобильных
 			return emulation.SetTouchEmulationEnabled(true).
 				WithMaxTouchPoints(5).
@@ -341,57 +393,88 @@ func (inj *Injector) SetTouchEmulation(ctx context.Context) chromedp.Action {
 	})
 }
 
+// hasTouch сообщает, нужна ли эмуляция touch: по MediaFeatures.Pointer,
+// а без него - для мобильных устройств
+func (inj *Injector) hasTouch() bool {
+	if m := inj.Fingerprint().MediaFeatures; m != nil && m.Pointer != "" {
+		return m.Pointer == "coarse"
+	}
+	return inj.isMobileDevice()
+}
+
 // isMobileDevice определяет, является ли устройство мобильным
 func (inj *Injector) isMobileDevice() bool {
-	platform := inj.fingerprint.Platform
+	fp := inj.Fingerprint()
+	platform := fp.Platform
 	// Проверяем по platform string
 	return platform == "Linux armv8l" || // Android
 		platform == "iPhone" || // iOS
 		platform == "iPad" || // iPad
-		inj.fingerprint.Screen.Width <= 768 // Или по размеру экрана
+		fp.Screen.Width <= 768 // Или по размеру экрана
 }
 
 // ApplyAll применяет все настройки fingerprint
 func (inj *Injector) ApplyAll(ctx context.Context) chromedp.Action {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		// Применяем User-Agent
-		if err := inj.SetUserAgentOverride(ctx).Do(ctx); err != nil {
-			return fmt.Errorf("failed to set user agent: %w", err)
+		// Размер окна задается только для окна текущей вкладки: новые
+		// вкладки открываются в нем же, а попапы сохраняют свой размер.
+		// Окно без рамки (headless, удаленный браузер, ограниченный CDP)
+		// может не менять размер, поэтому ошибка не прерывает ApplyAll.
+		if err := inj.SetWindowBounds(ctx).Do(ctx); err != nil {
+			inj.errorf("failed to set window bounds: %v", err)
 		}
+		return inj.apply(ctx, inj.autoAttach)
+	})
+}
 
-		// Применяем Timezone
-		if err := inj.SetTimezoneOverride(ctx).Do(ctx); err != nil {
-			return fmt.Errorf("failed to set timezone: %w", err)
-		}
+// apply применяет все настройки fingerprint к текущей цели
+func (inj *Injector) apply(ctx context.Context, autoAttach bool) error {
+	// Применяем User-Agent
+	if err := inj.SetUserAgentOverride(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set user agent: %w", err)
+	}
 
-		// Применяем Device Metrics (viewport и screen)
-		if err := inj.SetDeviceMetrics(ctx).Do(ctx); err != nil {
-			return fmt.Errorf("failed to set device metrics: %w", err)
-		}
+	// Применяем Timezone
+	if err := inj.SetTimezoneOverride(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set timezone: %w", err)
+	}
 
-		// Применяем Touch Emulation для мобильных
-		if err := inj.SetTouchEmulation(ctx).Do(ctx); err != nil {
-			return fmt.Errorf("failed to set touch emulation: %w", err)
-		}
+	// Применяем локаль
+	if err := inj.SetLocaleOverride(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set locale: %w", err)
+	}
 
-		// Инжектируем скрипт
-		if err := inj.Inject(ctx).Do(ctx); err != nil {
-			return fmt.Errorf("failed to inject script: %w", err)
-		}
+	// Применяем ограничения сети
+	if err := inj.SetNetworkConditions(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set network conditions: %w", err)
+	}
 
-		return nil
-	})
-}
+	// Применяем Device Metrics (viewport и screen)
+	if err := inj.SetDeviceMetrics(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set device metrics: %w", err)
+	}
 
-// Вспомогательная функция для конвертации слайса в JS массив
-func toJSArray(arr []string) string {
-	result := "["
-	for i, v := range arr {
-		if i > 0 {
-			result += ", "
+	// Применяем медиа-функции CSS
+	if err := inj.SetEmulatedMedia(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set emulated media: %w", err)
+	}
+
+	// Применяем Touch Emulation для мобильных
+	if err := inj.SetTouchEmulation(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to set touch emulation: %w", err)
+	}
+
+	// Инжектируем скрипт
+	if err := inj.Inject(ctx).Do(ctx); err != nil {
+		return fmt.Errorf("failed to inject script: %w", err)
+	}
+
+	// Применяем fingerprint к новым вкладкам, попапам и iframe
+	if autoAttach {
+		if err := inj.EnableAutoAttach(ctx).Do(ctx); err != nil {
+			return fmt.Errorf("failed to enable auto-attach: %w", err)
 		}
-		result += fmt.Sprintf("'%s'", v)
 	}
-	result += "]"
-	return result
+
+	return nil
 }
@@ -1,8 +1,25 @@
 package fingerprint
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
 )
 
 func TestNewInjector(t *testing.T) {
@@ -44,6 +61,10 @@ func TestGetInjectionScript(t *testing.T) {
 			t.Errorf("Script should contain '%s'", part)
 		}
 	}
+	// Скрипт не должен оставлять следов в консоли страницы
+	if strings.Contains(script, "console.") {
+		t.Error("Script should not write to the console")
+	}
 }
 
 func TestGetInjectionScriptWithWebRTCDisabled(t *testing.T) {
@@ -58,32 +79,1556 @@ func TestGetInjectionScriptWithWebRTCDisabled(t *testing.T) {
 	}
 }
 
-func TestGetInjectionScriptWithCanvasNoise(t *testing.T) {
+func TestGetInjectionScriptWithWebRTCModes(t *testing.T) {
 	fp := NewDefaultFingerprint()
-	fp.Canvas.Noise = 0.5
+	if strings.Contains(NewInjector(fp).GetInjectionScript(), "rewriteCandidate") {
+		t.Error("Script should not contain WebRTC patch without mode")
+	}
 
-	injector := NewInjector(fp)
+	fp.WebRTC = &WebRTC{Mode: WebRTCModeProxyOnly, PublicIP: "198.18.0.1"}
+	script := NewInjector(fp).GetInjectionScript()
+	for _, part := range []string{
+		`"webrtc":{"mode":"proxy-only","publicIp":"198.18.0.1"`,
+		"rewriteCandidate",
+		"localDescription",
+		"iceTransportPolicy: 'relay'",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+}
+
+func TestGetInjectionScriptWithMediaDevices(t *testing.T) {
+	fp := NewChrome134Android()
+	fp.MediaDevices.Seed = 42
+	fp.WebRTC.Disable = true
+
+	script := NewInjector(fp).GetInjectionScript()
+	for _, part := range []string{
+		`"mediaDevices":{"seed":42,"devices":[`,
+		`"facingMode":"environment"`,
+		"enumerateDevices",
+		"getUserMedia",
+		"decodingInfo",
+		"!cfg.mediaDevices",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	fp.MediaDevices = nil
+	if strings.Contains(NewInjector(fp).GetInjectionScript(), "decodingInfo") {
+		t.Error("Script should not contain media patch without MediaDevices")
+	}
+}
+
+func TestBatteryPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Android()
+	fp.Battery.Speed = 100
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	// Уровень 0.87 разряжается за 7200 с, при speed 100 шаг 0.01 занимает
+	// около секунды симулированных часов
+	var result struct {
+		Native          bool     `json:"native"`
+		Same            bool     `json:"same"`
+		Charging        bool     `json:"charging"`
+		Level           float64  `json:"level"`
+		DischargingTime float64  `json:"dischargingTime"`
+		Events          []string `json:"events"`
+		NextLevel       float64  `json:"nextLevel"`
+		NextTime        float64  `json:"nextTime"`
+		IllegalGetter   bool     `json:"illegalGetter"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`Promise.all([navigator.getBattery(), navigator.getBattery()]).then(function(managers) {
+		const battery = managers[0];
+		const result = {
+			native: battery instanceof BatteryManager && battery instanceof EventTarget,
+			same: battery === managers[1],
+			charging: battery.charging,
+			level: battery.level,
+			dischargingTime: battery.dischargingTime,
+			events: []
+		};
+		try {
+			Object.getOwnPropertyDescriptor(BatteryManager.prototype, 'level').get.call({});
+		} catch (e) {
+			result.illegalGetter = e instanceof TypeError;
+		}
+		return new Promise(function(resolve) {
+			setTimeout(function() {
+				resolve(result);
+			}, 5000);
+			battery.addEventListener('dischargingtimechange', function() {
+				result.events.push('dischargingtimechange');
+			});
+			battery.onlevelchange = function(event) {
+				result.events.push(event.type);
+				result.nextLevel = battery.level;
+				result.nextTime = battery.dischargingTime;
+				resolve(result);
+			};
+		});
+	})`, &result, awaitPromise))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if !result.Native || !result.Same || !result.IllegalGetter {
+		t.Errorf("getBattery should resolve to one native-looking BatteryManager, got %+v", result)
+	}
+	if result.Charging || result.Level != 0.87 || math.Abs(result.DischargingTime-7200) > 200 {
+		t.Errorf("Initial state should match the fingerprint, got %+v", result)
+	}
+	if result.NextLevel != 0.86 || result.NextTime >= result.DischargingTime {
+		t.Errorf("Battery should drain by 0.01 with dischargingTime, got level %v and time %v", result.NextLevel, result.NextTime)
+	}
+	if !slices.Contains(result.Events, "dischargingtimechange") {
+		t.Errorf("Level change should come with dischargingtimechange, got %v", result.Events)
+	}
+}
+
+func TestConnectionPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	type connection struct {
+		Native        bool    `json:"native"`
+		Type          string  `json:"type"`
+		HasType       bool    `json:"hasType"`
+		EffectiveType string  `json:"effectiveType"`
+		RTT           int     `json:"rtt"`
+		Downlink      float64 `json:"downlink"`
+		DownlinkMax   float64 `json:"downlinkMax"`
+		SaveData      bool    `json:"saveData"`
+	}
+	read := func(fp *Fingerprint) connection {
+		tctx, cancel := chromedp.NewContext(ctx)
+		defer cancel()
+		inj := NewInjector(fp)
+		var c connection
+		err := chromedp.Run(tctx, inj.ApplyAll(tctx), chromedp.Navigate(srv.URL+"/page"), chromedp.Evaluate(`(function() {
+			const c = navigator.connection;
+			return {
+				native: c instanceof NetworkInformation,
+				type: c.type || '',
+				hasType: 'type' in c || 'downlinkMax' in c,
+				effectiveType: c.effectiveType,
+				rtt: c.rtt,
+				downlink: c.downlink,
+				downlinkMax: c.downlinkMax === Infinity ? -1 : c.downlinkMax || 0,
+				saveData: c.saveData
+			};
+		})()`, &c))
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		return c
+	}
+
+	// rtt и downlink умножаются на множитель origin из [0.9, 1.1] и округляются
+	android := NewChrome134Android()
+	c := read(android)
+	want := android.Connection
+	if !c.Native || c.Type != want.Type || c.EffectiveType != want.EffectiveType || c.DownlinkMax != want.DownlinkMax || c.SaveData {
+		t.Errorf("Android connection should match the fingerprint, got %+v", c)
+	}
+	if c.RTT%50 != 0 || math.Abs(float64(c.RTT-want.RTT)) > 0.1*float64(want.RTT)+25 {
+		t.Errorf("rtt should be rounded near %d, got %d", want.RTT, c.RTT)
+	}
+	if math.Mod(c.Downlink*40, 1) != 0 || math.Abs(c.Downlink-want.Downlink) > 0.1*want.Downlink+0.0125 {
+		t.Errorf("downlink should be rounded near %v, got %v", want.Downlink, c.Downlink)
+	}
+
+	// У desktop Chrome нет type и downlinkMax
+	desktop := NewChrome134Windows11()
+	c = read(desktop)
+	if !c.Native || c.HasType || c.EffectiveType != desktop.Connection.EffectiveType || c.Downlink > 10 {
+		t.Errorf("Desktop connection should have no type, got %+v", c)
+	}
+}
+
+func TestMediaFeatures(t *testing.T) {
+	desktop := NewChrome134Windows11()
+	desktop.MediaFeatures.ColorScheme = "dark"
+	desktop.MediaFeatures.ReducedMotion = "reduce"
+
+	for _, fp := range []*Fingerprint{desktop, NewChrome134Android()} {
+		m := fp.MediaFeatures
+		ctx := newTestBrowser(t, fp.AllocatorOptions()...)
+		srv := newTestServer(t)
+
+		inj := NewInjector(fp)
+		if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+			t.Fatalf("ApplyAll failed: %v", err)
+		}
+
+		var matches map[string]bool
+		queries := []string{
+			"(prefers-color-scheme: " + m.ColorScheme + ")",
+			"(prefers-reduced-motion: " + m.ReducedMotion + ")",
+			"(pointer: " + m.Pointer + ")",
+			"(any-pointer: " + m.Pointer + ")",
+			"(hover: " + m.Hover + ")",
+			"(any-hover: " + m.Hover + ")",
+			"(color-gamut: " + m.ColorGamut + ")",
+			"(dynamic-range: " + m.DynamicRange + ")",
+		}
+		// @media в CSS должен совпадать с matchMedia
+		err := chromedp.Run(ctx, chromedp.Evaluate(`(function(queries) {
+			const result = {};
+			queries.forEach(function(query) {
+				result[query] = matchMedia(query).matches;
+			});
+			const style = document.createElement('style');
+			style.textContent = '@media ' + queries[2] + ' and ' + queries[4] + ' { body { order: 7; } }';
+			document.head.appendChild(style);
+			result.css = getComputedStyle(document.body).order === '7';
+			return result;
+		})(`+jsQuote(strings.Join(queries, "\n"))+`.split('\n'))`, &matches))
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+
+		for _, query := range append(queries, "css") {
+			if !matches[query] {
+				t.Errorf("%s: %s should match", fp.Platform, query)
+			}
+		}
+	}
+}
+
+func TestGetInjectionScriptWithScreenOrientation(t *testing.T) {
+	fp := NewChrome134Android()
+	fp.Screen.Orientation = OrientationPortraitSecondary
+	fp.Screen.OrientationAngle = 180
+
+	script := NewInjector(fp).GetInjectionScript()
+	for _, part := range []string{
+		`"orientation":"portrait-secondary","orientationAngle":180`,
+		"ScreenOrientation.prototype",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+}
+
+func TestGetInjectionScriptWithWindow(t *testing.T) {
+	fp := NewChrome119MacOS()
+
+	script := NewInjector(fp).GetInjectionScript()
+	for _, part := range []string{
+		`"window":{"innerWidth":2560,"innerHeight":1338,"outerWidth":2560,"outerHeight":1417,"screenX":0,"screenY":23,"scrollbarWidth":0}`,
+		"screenLeft: cfg.window.screenX",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	width, height := fp.viewport()
+	fp.Window = nil
+	screenWidth, screenHeight := fp.viewport()
+	if width != 2560 || height != 1338 || screenWidth != 2560 || screenHeight != 1440 {
+		t.Errorf("Unexpected viewports %dx%d and %dx%d", width, height, screenWidth, screenHeight)
+	}
+}
+
+func TestInjectorRotate(t *testing.T) {
+	script := NewInjector(NewChrome134Android()).GetInjectionScript()
+	for _, part := range []string{
+		"addEventListener('orientationchange', sync)",
+		"addEventListener('resize', sync)",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	fp := NewChrome134Windows11()
+	if err := NewInjector(fp).Rotate(context.Background(), false).Do(context.Background()); err == nil {
+		t.Error("Rotate should fail for a desktop fingerprint")
+	}
+	if fp.Screen.Width != 1920 {
+		t.Errorf("Failed rotation should not change the screen, got %+v", fp.Screen)
+	}
+}
+
+func TestInjectorRotateInBrowser(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Android()
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`window.rotationEvents = [];
+		addEventListener('resize', function() { rotationEvents.push('resize'); });
+		addEventListener('orientationchange', function() { rotationEvents.push('orientationchange'); });
+		screen.orientation.addEventListener('change', function() { rotationEvents.push('change'); });
+		true`, nil))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if err := chromedp.Run(ctx, inj.Rotate(ctx, true)); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	var result struct {
+		Width       int      `json:"width"`
+		Height      int      `json:"height"`
+		AvailWidth  int      `json:"availWidth"`
+		AvailHeight int      `json:"availHeight"`
+		Type        string   `json:"type"`
+		Angle       int      `json:"angle"`
+		Events      []string `json:"events"`
+	}
+	err = chromedp.Run(ctx, chromedp.Poll(`rotationEvents.length >= 3 && {
+		width: screen.width,
+		height: screen.height,
+		availWidth: screen.availWidth,
+		availHeight: screen.availHeight,
+		type: screen.orientation.type,
+		angle: screen.orientation.angle,
+		events: rotationEvents
+	}`, &result, chromedp.WithPollingTimeout(5*time.Second)))
+	if err != nil {
+		var events []string
+		chromedp.Run(ctx, chromedp.Evaluate(`rotationEvents`, &events))
+		t.Fatalf("Rotation events did not arrive, got %v: %v", events, err)
+	}
+
+	// Исходный fingerprint остается портретным
+	s := fp.Screen
+	if result.Width != s.Height || result.Height != s.Width || result.AvailWidth != s.AvailHeight || result.AvailHeight != s.AvailWidth {
+		t.Errorf("Screen should be swapped after rotation, got %+v", result)
+	}
+	if result.Type != "landscape-primary" || result.Angle != 90 {
+		t.Errorf("Expected landscape-primary at 90, got %s at %d", result.Type, result.Angle)
+	}
+	for _, event := range []string{"resize", "orientationchange", "change"} {
+		if !slices.Contains(result.Events, event) {
+			t.Errorf("Rotation should fire %s, got %v", event, result.Events)
+		}
+	}
+	if fp.Screen.Orientation != "" || inj.Fingerprint().Screen.Orientation != OrientationLandscapePrimary {
+		t.Error("Rotate should swap in a rotated copy of the fingerprint")
+	}
+}
+
+// stubExecutor принимает все команды CDP, кроме fail
+type stubExecutor struct {
+	fail    string
+	methods []string
+}
+
+func (e *stubExecutor) Execute(ctx context.Context, method string, params, res interface{}) error {
+	e.methods = append(e.methods, method)
+	if method == e.fail {
+		return fmt.Errorf("%s: not supported", method)
+	}
+	if r, ok := res.(*runtime.EvaluateReturns); ok {
+		r.Result = &runtime.RemoteObject{Type: runtime.TypeUndefined}
+	}
+	return nil
+}
+
+func TestApplyAllWithoutWindowBounds(t *testing.T) {
+	var errs []string
+	inj := NewInjector(NewChrome134Windows11(), WithErrorf(func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}))
+
+	exec := &stubExecutor{fail: "Browser.getWindowForTarget"}
+	ctx := cdp.WithExecutor(context.Background(), exec)
+	if err := inj.ApplyAll(ctx).Do(ctx); err != nil {
+		t.Fatalf("ApplyAll should not fail when the window cannot be resized: %v", err)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "failed to set window bounds") {
+		t.Errorf("Window bounds error should be reported, got %q", errs)
+	}
+	if !strings.Contains(strings.Join(exec.methods, " "), "Page.addScriptToEvaluateOnNewDocument") {
+		t.Errorf("ApplyAll should inject the script, got %v", exec.methods)
+	}
+}
+
+func TestGetInjectionScriptWithWebGL(t *testing.T) {
+	injector := NewInjector(NewChrome119Windows11())
 	script := injector.GetInjectionScript()
 
-	if !strings.Contains(script, "toDataURL") {
-		t.Error("Script should contain Canvas noise code")
+	for _, part := range []string{
+		`"unmaskedRenderer":"ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)"`,
+		`"MAX_VIEWPORT_DIMS":[32767,32767]`,
+		"WEBGL_debug_renderer_info",
+		"getSupportedExtensions",
+		"getShaderPrecisionFormat",
+		"WebGLShaderPrecisionFormat.prototype",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	// WebGL в OffscreenCanvas воркеров отдает те же значения
+	worker, err := injector.BuildWorkerScript()
+	if err != nil {
+		t.Fatalf("BuildWorkerScript failed: %v", err)
+	}
+	if !strings.Contains(worker, "getShaderPrecisionFormat") {
+		t.Error("Worker script should contain WebGL patch")
+	}
+}
+
+func TestGetInjectionScriptWithWebGPU(t *testing.T) {
+	script := NewInjector(NewChrome134Windows11()).GetInjectionScript()
+	for _, part := range []string{
+		`"webgpu":{"disable":false,"noAdapter":false,"vendor":"nvidia","architecture":"ampere"`,
+		"GPUAdapterInfo.prototype",
+		"GPUSupportedLimits.prototype",
+		"requestAdapter",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	safari := NewInjector(NewSafari17iOS()).GetInjectionScript()
+	if !strings.Contains(safari, `"webgpu":{"disable":true`) {
+		t.Error("Safari 17 script should hide navigator.gpu")
+	}
+
+	fp := NewChrome134Windows11()
+	fp.WebGPU = nil
+	if strings.Contains(NewInjector(fp).GetInjectionScript(), "GPUAdapterInfo") {
+		t.Error("Script should not contain WebGPU patch without WebGPU")
+	}
+}
+
+func TestCanvasPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewDefaultFingerprint()
+	fp.Canvas.Noise = 0.5
+	fp.Canvas.Seed = 42
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	// Canvas залит одним цветом, поэтому каждый канал отличается
+	// от исходного значения только шумом
+	var result struct {
+		DataURL  string `json:"dataURL"`
+		Again    string `json:"again"`
+		Blob     string `json:"blob"`
+		Pixels   []int  `json:"pixels"`
+		Part     []int  `json:"part"`
+		Copy     []int  `json:"copy"`
+		Painted  []int  `json:"painted"`
+		Constant bool   `json:"constant"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`(function() {
+		const size = 16;
+		const canvas = document.createElement('canvas');
+		canvas.width = size;
+		canvas.height = size;
+		const context = canvas.getContext('2d');
+		context.fillStyle = 'rgb(100, 150, 200)';
+		context.fillRect(0, 0, size, size);
+
+		const result = {
+			dataURL: canvas.toDataURL(),
+			again: canvas.toDataURL(),
+			pixels: Array.from(context.getImageData(0, 0, size, size).data),
+			part: Array.from(context.getImageData(4, 4, 8, 8).data)
+		};
+		// drawImage копирует настоящие пиксели: если бы чтения меняли
+		// canvas, копия получила бы шум дважды
+		const copy = document.createElement('canvas');
+		copy.width = size;
+		copy.height = size;
+		copy.getContext('2d').drawImage(canvas, 0, 0);
+		result.copy = Array.from(copy.getContext('2d').getImageData(0, 0, size, size).data);
+
+		return new Promise(function(resolve) {
+			canvas.toBlob(function(blob) {
+				const reader = new FileReader();
+				reader.onload = function() {
+					result.blob = reader.result;
+					resolve(result);
+				};
+				reader.readAsDataURL(blob);
+			});
+		});
+	})()`, &result, awaitPromise))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if result.DataURL != result.Again || result.DataURL != result.Blob {
+		t.Error("toDataURL and toBlob should return the same image on every call")
+	}
+	if !slices.Equal(result.Pixels, result.Copy) {
+		t.Error("Reading the canvas should not modify it")
+	}
+
+	const size = 16
+	changed := 0
+	for i, v := range result.Pixels {
+		want := []int{100, 150, 200, 255}[i%4]
+		if v != want {
+			changed++
+		}
+		if v < want-1 || v > want+1 || (i%4 == 3 && v != want) {
+			t.Fatalf("Noise should change color channels by 1, got %d for %d", v, want)
+		}
+	}
+	if changed == 0 {
+		t.Error("getImageData should return noised pixels")
+	}
+
+	// Шум зависит от положения пикселя на canvas, а не в прочитанной области
+	for y := 0; y < 8; y++ {
+		row := result.Pixels[((y+4)*size+4)*4 : ((y+4)*size+12)*4]
+		if !slices.Equal(result.Part[y*8*4:(y+1)*8*4], row) {
+			t.Fatalf("getImageData of a region should match the full read at row %d", y)
+		}
+	}
+
+	// toDataURL кодирует те же пиксели, что возвращает getImageData
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(result.DataURL, "data:image/png;base64,"))
+	if err != nil {
+		t.Fatalf("Invalid data URL: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid PNG: %v", err)
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i := (y*size + x) * 4
+			if got := []int{int(c.R), int(c.G), int(c.B), int(c.A)}; !slices.Equal(got, result.Pixels[i:i+4]) {
+				t.Fatalf("toDataURL pixel (%d, %d) %v should match getImageData %v", x, y, got, result.Pixels[i:i+4])
+			}
+		}
 	}
 }
 
-func TestToJSArray(t *testing.T) {
+func TestJSLiteral(t *testing.T) {
 	tests := []struct {
 		input    []string
 		expected string
 	}{
-		{[]string{"en-US", "en"}, "['en-US', 'en']"},
-		{[]string{"ru"}, "['ru']"},
+		{[]string{"en-US", "en"}, `["en-US","en"]`},
+		{[]string{"ru"}, `["ru"]`},
 		{[]string{}, "[]"},
+		{[]string{"it's"}, `["it's"]`},
+		{[]string{"</script>"}, `["\u003c/script\u003e"]`},
+		{[]string{"a\u2028b"}, `["a\u2028b"]`},
 	}
 
 	for _, test := range tests {
-		result := toJSArray(test.input)
+		result, err := jsLiteral(test.input)
+		if err != nil {
+			t.Fatalf("jsLiteral failed: %v", err)
+		}
 		if result != test.expected {
 			t.Errorf("Expected %s, got %s", test.expected, result)
 		}
 	}
 }
+
+func TestGetInjectionScriptEscapesValues(t *testing.T) {
+	fp := NewDefaultFingerprint()
+	fp.UserAgent = "Mozilla'; alert(1); //\n\"</script>"
+	fp.Platform = "Win32\\"
+	fp.Languages = []string{"en-US'", "en\nUS"}
+	fp.Timezone.ID = "America/New_York'); alert(2); ('"
+
+	script, err := NewInjector(fp).BuildInjectionScript()
+	if err != nil {
+		t.Fatalf("BuildInjectionScript failed: %v", err)
+	}
+
+	for _, bad := range []string{"alert(1); //\n", "</script>", "'en-US''"} {
+		if strings.Contains(script, bad) {
+			t.Errorf("Script should not contain raw value %q", bad)
+		}
+	}
+
+	config, err := jsLiteral(fp)
+	if err != nil {
+		t.Fatalf("jsLiteral failed: %v", err)
+	}
+	if !strings.Contains(script, "const cfg = "+config+";") {
+		t.Error("Script should contain JSON-encoded fingerprint config")
+	}
+}
+
+func TestGetInjectionScriptDeterministic(t *testing.T) {
+	fp := NewChrome134Android()
+
+	first := NewInjector(fp).GetInjectionScript()
+	second := NewInjector(NewChrome134Android()).GetInjectionScript()
+
+	if first != second {
+		t.Error("Script should be identical for identical fingerprints")
+	}
+}
+
+func TestBuildInjectionScriptInvalidValue(t *testing.T) {
+	fp := NewDefaultFingerprint()
+	fp.Canvas.Noise = math.NaN()
+
+	if _, err := NewInjector(fp).BuildInjectionScript(); err == nil {
+		t.Error("Expected error for non-serializable fingerprint")
+	}
+	if script := NewInjector(fp).GetInjectionScript(); script != "" {
+		t.Error("GetInjectionScript should return empty script on error")
+	}
+}
+
+func TestGetInjectionScriptUsesPrototypeOverrides(t *testing.T) {
+	script := NewInjector(NewDefaultFingerprint()).GetInjectionScript()
+
+	if !strings.Contains(script, "const utils = ") {
+		t.Error("Script should define shared utils helper")
+	}
+
+	for _, part := range []string{
+		"utils.replaceGetter(Navigator.prototype, 'userAgent'",
+		"utils.replaceGetter(Screen.prototype, prop",
+		"replaceMethod(Function.prototype, 'toString'",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	for _, instance := range []string{"defineProperty(navigator,", "defineProperty(screen,"} {
+		if strings.Contains(script, instance) {
+			t.Errorf("Script should not define properties on instances: '%s'", instance)
+		}
+	}
+}
+
+func TestInjectorAutoAttachOptions(t *testing.T) {
+	var messages []string
+	injector := NewInjector(NewDefaultFingerprint(), WithAutoAttach(), WithErrorf(func(format string, args ...interface{}) {
+		messages = append(messages, format)
+	}))
+
+	if !injector.autoAttach {
+		t.Error("WithAutoAttach should enable auto-attach mode")
+	}
+
+	injector.errorf("test %s", "error")
+	if len(messages) != 1 {
+		t.Error("WithErrorf should set error handler")
+	}
+
+	if !injector.claimChild("target-1") {
+		t.Error("First attach of target should be accepted")
+	}
+	if injector.claimChild("target-1") {
+		t.Error("Repeated attach of target should be ignored")
+	}
+
+	injector.forgetTarget("target-1")
+	if !injector.claimChild("target-1") {
+		t.Error("Closed target should be forgotten")
+	}
+	injector.forgetTarget("target-1")
+}
+
+func TestClaimAutoAttachTarget(t *testing.T) {
+	first, second := NewInjector(NewChrome134Windows11()), NewInjector(NewChrome134Android())
+	first.claimRoot(&target.Info{TargetID: "claim-tab-1", BrowserContextID: "claim-context-1"})
+	second.claimRoot(&target.Info{TargetID: "claim-tab-2", BrowserContextID: "claim-context-1"})
+	defer first.releaseBrowser(nil)
+	defer second.releaseBrowser(nil)
+
+	for _, tt := range []struct {
+		inj         *Injector
+		info        target.Info
+		owned, ok   bool
+		description string
+	}{
+		{second, target.Info{TargetID: "claim-popup", OpenerID: "claim-tab-2", BrowserContextID: "claim-context-1"}, true, true, "popup belongs to its opener"},
+		{first, target.Info{TargetID: "claim-popup-2", OpenerID: "claim-tab-2", BrowserContextID: "claim-context-1"}, false, false, "popup of another injector is skipped"},
+		{first, target.Info{TargetID: "claim-new-tab", BrowserContextID: "claim-context-1"}, true, true, "new tab belongs to the context owner"},
+		{second, target.Info{TargetID: "claim-new-tab", BrowserContextID: "claim-context-1"}, false, false, "target is handled once"},
+		{second, target.Info{TargetID: "claim-other-tab", BrowserContextID: "claim-context-2"}, false, true, "foreign target is only resumed"},
+		{first, target.Info{TargetID: "claim-worker", Type: "service_worker", BrowserContextID: "claim-context-1"}, true, true, "worker belongs to the context owner"},
+		{first, target.Info{TargetID: "claim-other-worker", Type: "shared_worker", BrowserContextID: "claim-context-2"}, false, true, "worker of another context is only resumed"},
+	} {
+		owned, ok := tt.inj.claimTarget(&tt.info)
+		if owned != tt.owned || ok != tt.ok {
+			t.Errorf("%s: got owned=%v ok=%v", tt.description, owned, ok)
+		}
+	}
+}
+
+func TestBuildWorkerScript(t *testing.T) {
+	injector := NewInjector(NewDefaultFingerprint(), WithPatches(&ScriptPatch{
+		PatchName:  "worker-only",
+		WorkerBody: "self.__workerOnlyPatch = true;",
+	}))
+
+	script, err := injector.BuildWorkerScript()
+	if err != nil {
+		t.Fatalf("BuildWorkerScript failed: %v", err)
+	}
+
+	for _, part := range []string{
+		"(function(workerPrelude, workerURL)",
+		"WorkerNavigator.prototype",
+		"getTimezoneOffset",
+		"self.__workerOnlyPatch = true;",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Worker script should contain '%s'", part)
+		}
+	}
+
+	// Патчи без WorkerBody не должны попадать в воркер
+	for _, part := range []string{"Screen.prototype", "HTMLCanvasElement"} {
+		if strings.Contains(script, part) {
+			t.Errorf("Worker script should not contain '%s'", part)
+		}
+	}
+
+	if !strings.HasSuffix(script, ", null);\n") {
+		t.Error("Worker script should invoke the prelude")
+	}
+}
+
+func TestGetInjectionScriptWrapsWorkers(t *testing.T) {
+	script := NewInjector(NewDefaultFingerprint(), WithWorkerWrapper()).GetInjectionScript()
+
+	if !strings.Contains(script, "const workerPrelude = \"(function(workerPrelude, workerURL)") {
+		t.Error("Script should embed worker prelude as a string literal")
+	}
+	if !strings.Contains(script, "utils.replaceConstructor(self, 'Worker'") {
+		t.Error("Script should wrap Worker constructor")
+	}
+
+	// Без опции обертка выключена: CSP может запрещать blob: воркеры
+	script = NewInjector(NewDefaultFingerprint()).GetInjectionScript()
+	if strings.Contains(script, "utils.replaceConstructor(self, 'Worker'") {
+		t.Error("Script should not wrap Worker by default")
+	}
+	if strings.Contains(script, "workerPrelude") {
+		t.Error("Script should not embed worker prelude by default")
+	}
+
+	script = NewInjector(NewDefaultFingerprint(), WithWorkerWrapper(), WithoutPatches(PatchWorkers)).GetInjectionScript()
+	if strings.Contains(script, "utils.replaceConstructor(self, 'Worker'") {
+		t.Error("Script should not wrap Worker without workers patch")
+	}
+	if strings.Contains(script, "workerPrelude") {
+		t.Error("Script should not embed worker prelude without workers patch")
+	}
+}
+
+func TestAudioPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewDefaultFingerprint()
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	// Буферы с одинаковыми отсчетами 0.5 и нулем в начале: шум зависит
+	// только от fingerprint и индекса отсчета
+	var result struct {
+		First           []float64 `json:"first"`
+		Again           []float64 `json:"again"`
+		Other           []float64 `json:"other"`
+		Copied          []float64 `json:"copied"`
+		Rendered        []float64 `json:"rendered"`
+		RenderedAgain   []float64 `json:"renderedAgain"`
+		SampleRate      float64   `json:"sampleRate"`
+		OfflineRate     float64   `json:"offlineRate"`
+		BaseLatency     float64   `json:"baseLatency"`
+		MaxChannelCount int       `json:"maxChannelCount"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`(function() {
+		const constant = function() {
+			const buffer = new AudioBuffer({ length: 64, sampleRate: 44100 });
+			const samples = new Float32Array(64).fill(0.5);
+			samples[0] = 0;
+			buffer.copyToChannel(samples, 0);
+			return buffer;
+		};
+		const render = function() {
+			const context = new OfflineAudioContext(1, 4410, 44100);
+			const oscillator = context.createOscillator();
+			oscillator.connect(context.destination);
+			oscillator.start();
+			return context.startRendering().then(function(buffer) {
+				return Array.from(buffer.getChannelData(0).slice(100, 164));
+			});
+		};
+
+		const buffer = constant();
+		const first = Array.from(buffer.getChannelData(0));
+		const copied = new Float32Array(64);
+		const copiedBuffer = constant();
+		copiedBuffer.copyFromChannel(copied, 0);
+		const audio = new AudioContext();
+		const result = {
+			first: first,
+			again: Array.from(buffer.getChannelData(0)),
+			other: Array.from(constant().getChannelData(0)),
+			copied: Array.from(copied),
+			sampleRate: audio.sampleRate,
+			offlineRate: new OfflineAudioContext(1, 1, 22050).sampleRate,
+			baseLatency: audio.baseLatency,
+			maxChannelCount: audio.destination.maxChannelCount
+		};
+		return Promise.all([render(), render()]).then(function(rendered) {
+			result.rendered = rendered[0];
+			result.renderedAgain = rendered[1];
+			return result;
+		});
+	})()`, &result, awaitPromise))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if len(result.First) != 64 || result.First[0] != 0 {
+		t.Fatalf("Silence should stay silent, got %v", result.First)
+	}
+	changed := 0
+	for _, v := range result.First[1:] {
+		if v != 0.5 {
+			changed++
+		}
+		if math.Abs(v-0.5) > 0.5*fp.Audio.Noise*1e-3 {
+			t.Fatalf("Noise should stay within Audio.Noise, got %v", v)
+		}
+	}
+	if changed == 0 {
+		t.Error("getChannelData should return noised samples")
+	}
+	if !slices.Equal(result.First, result.Again) {
+		t.Error("Repeated getChannelData should not add noise again")
+	}
+	if !slices.Equal(result.First, result.Other) || !slices.Equal(result.First, result.Copied) {
+		t.Error("Noise should be deterministic for getChannelData and copyFromChannel")
+	}
+	if !slices.Equal(result.Rendered, result.RenderedAgain) {
+		t.Error("Rendered audio should be the same on every run")
+	}
+
+	a := fp.Audio
+	if result.SampleRate != float64(a.SampleRate) || result.BaseLatency != a.BaseLatency || result.MaxChannelCount != a.MaxChannelCount {
+		t.Errorf("AudioContext should report the fingerprint, got sampleRate %v, baseLatency %v and maxChannelCount %d",
+			result.SampleRate, result.BaseLatency, result.MaxChannelCount)
+	}
+	if result.OfflineRate != 22050 {
+		t.Errorf("OfflineAudioContext should keep its sample rate, got %v", result.OfflineRate)
+	}
+}
+
+func TestGetInjectionScriptWithFonts(t *testing.T) {
+	fp := NewDefaultFingerprint()
+	fp.Fonts = []string{"Arial", "Segoe UI"}
+	script := NewInjector(fp).GetInjectionScript()
+
+	for _, part := range []string{
+		"proto, 'check'",
+		"replaceConstructor(self, 'FontFace'",
+		"utils.replaceGetter(proto, 'size'",
+		`"fonts":["Arial","Segoe UI"]`,
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	fp.Fonts = nil
+	script = NewInjector(fp).GetInjectionScript()
+	if strings.Contains(script, "replaceConstructor(self, 'FontFace'") {
+		t.Error("Script should not contain fonts patch without Fonts")
+	}
+}
+
+// measureFontScript измеряет текст шрифтом family с запасным monospace
+const measureFontScript = `function measure(family) {
+	const span = document.createElement('span');
+	span.style.font = '72px ' + family + ', monospace';
+	span.textContent = 'mmmmmmmmmmlli';
+	document.body.appendChild(span);
+	const width = span.offsetWidth;
+	span.remove();
+	return width;
+}`
+
+func TestFontsPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	// Ищем установленный в системе шрифт, которого нет в списке пресета
+	var family string
+	err := chromedp.Run(ctx, chromedp.Navigate(srv.URL+"/page"), chromedp.Evaluate(`(function() {
+		const measure = `+measureFontScript+`;
+		return [
+			"'DejaVu Sans'", "'Liberation Sans'", "'Noto Sans'", "Ubuntu", "Cantarell", "FreeSans", "Helvetica"
+		].find(function(family) {
+			return measure(family) !== measure('monospace');
+		}) || '';
+	})()`, &family))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if family == "" {
+		t.Skip("No known font is installed")
+	}
+
+	inj := NewInjector(NewChrome134Windows11())
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	var result struct {
+		Width     int    `json:"width"`
+		Baseline  int    `json:"baseline"`
+		Check     bool   `json:"check"`
+		CheckList bool   `json:"checkList"`
+		Size      int    `json:"size"`
+		Local     string `json:"local"`
+	}
+	err = chromedp.Run(ctx, chromedp.Evaluate(`document.fonts.ready.then(function() {
+		const family = `+jsQuote(family)+`;
+		const measure = `+measureFontScript+`;
+		const result = {
+			width: measure(family),
+			baseline: measure('monospace'),
+			check: document.fonts.check('12px ' + family),
+			checkList: document.fonts.check('12px Arial'),
+			size: document.fonts.size
+		};
+		// FontFace с local() шрифта вне списка не загружается
+		return new FontFace('probe', 'local(' + family + ')').load().then(function() {
+			return Object.assign(result, { local: 'loaded' });
+		}, function() {
+			return Object.assign(result, { local: 'error' });
+		});
+	})`, &result, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if result.Width != result.Baseline {
+		t.Errorf("Font %s outside the list should measure as monospace (%dpx), got %dpx", family, result.Baseline, result.Width)
+	}
+	if result.Check {
+		t.Errorf("document.fonts.check should report %s as unavailable", family)
+	}
+	if !result.CheckList {
+		t.Error("document.fonts.check should report fonts from the list as available")
+	}
+	if result.Local != "error" {
+		t.Errorf("FontFace with local(%s) should fail to load, got %s", family, result.Local)
+	}
+	if result.Size != 0 {
+		t.Errorf("Shadowing font faces should be hidden from document.fonts, got size %d", result.Size)
+	}
+}
+
+// awaitPromise ждет результат Promise, возвращенного выражением
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
+// jsQuote кодирует строку как литерал JavaScript
+func jsQuote(s string) string {
+	literal, _ := jsLiteral(s)
+	return literal
+}
+
+func TestPluginsPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewDefaultFingerprint()
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	var result struct {
+		Native        bool     `json:"native"`
+		Names         []string `json:"names"`
+		Keys          []string `json:"keys"`
+		Lookups       bool     `json:"lookups"`
+		Missing       bool     `json:"missing"`
+		MissingArgs   bool     `json:"missingArgs"`
+		PluginMimes   []string `json:"pluginMimes"`
+		MimeTypes     []string `json:"mimeTypes"`
+		EnabledPlugin bool     `json:"enabledPlugin"`
+		Suffixes      string   `json:"suffixes"`
+		Tag           string   `json:"tag"`
+		PDFViewer     bool     `json:"pdfViewer"`
+		IllegalGetter bool     `json:"illegalGetter"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`(function() {
+		const plugins = navigator.plugins;
+		const mimeTypes = navigator.mimeTypes;
+		const first = plugins[0];
+		const pdf = mimeTypes['application/pdf'];
+		const result = {
+			native: plugins instanceof PluginArray && first instanceof Plugin &&
+				mimeTypes instanceof MimeTypeArray && pdf instanceof MimeType,
+			names: Array.from(plugins).map(function(p) { return p.name; }),
+			keys: Object.keys(plugins),
+			lookups: plugins.item(0) === first && plugins.namedItem(first.name) === first &&
+				plugins[first.name] === first && first.item(0) === first[0] &&
+				navigator.plugins === plugins,
+			missing: plugins.item(100) === null && plugins.namedItem('missing') === null,
+			pluginMimes: Array.from(first).map(function(m) { return m.type; }),
+			mimeTypes: Array.from(mimeTypes).map(function(m) { return m.type; }),
+			enabledPlugin: pdf.enabledPlugin === first && plugins[1][0] === pdf,
+			suffixes: pdf.suffixes,
+			tag: Object.prototype.toString.call(plugins),
+			pdfViewer: navigator.pdfViewerEnabled
+		};
+		try {
+			plugins.item();
+		} catch (e) {
+			result.missingArgs = e instanceof TypeError;
+		}
+		try {
+			Object.getOwnPropertyDescriptor(Plugin.prototype, 'name').get.call({});
+		} catch (e) {
+			result.illegalGetter = e instanceof TypeError;
+		}
+		return result;
+	})()`, &result))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	var names []string
+	for _, p := range fp.Plugins {
+		names = append(names, p.Name)
+	}
+	if !result.Native || result.Tag != "[object PluginArray]" {
+		t.Errorf("Plugins should use the native prototypes, got %+v", result)
+	}
+	if !slices.Equal(result.Names, names) {
+		t.Errorf("Expected plugins %v, got %v", names, result.Names)
+	}
+	if len(result.Keys) != len(names) || result.Keys[0] != "0" {
+		t.Errorf("Only indices should be enumerable, got %v", result.Keys)
+	}
+	if !result.Lookups || !result.Missing || !result.MissingArgs || !result.IllegalGetter {
+		t.Errorf("item and namedItem should behave like the native ones, got %+v", result)
+	}
+	mimes := []string{"application/pdf", "text/pdf"}
+	if !slices.Equal(result.PluginMimes, mimes) || !slices.Equal(result.MimeTypes, mimes) {
+		t.Errorf("Expected MIME types %v, got %v and %v", mimes, result.PluginMimes, result.MimeTypes)
+	}
+	if !result.EnabledPlugin || result.Suffixes != "pdf" || !result.PDFViewer {
+		t.Errorf("MIME types should be shared and enabled by the first plugin, got %+v", result)
+	}
+}
+
+func TestGetInjectionScriptWithClientHints(t *testing.T) {
+	script := NewInjector(NewChrome134Android()).GetInjectionScript()
+
+	for _, part := range []string{
+		"NavigatorUAData.prototype, 'getHighEntropyValues'",
+		"NavigatorUAData.prototype, 'brands'",
+		`"model":"Pixel 8a"`,
+		`"brand":"Google Chrome","version":"134"`,
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	worker, err := NewInjector(NewChrome134Android()).BuildWorkerScript()
+	if err != nil {
+		t.Fatalf("BuildWorkerScript failed: %v", err)
+	}
+	if !strings.Contains(worker, "WorkerNavigator.prototype : null") {
+		t.Error("Worker script should patch userAgentData")
+	}
+}
+
+func TestUserAgentOverrideMetadata(t *testing.T) {
+	params := NewInjector(NewChrome134Android()).userAgentOverride()
+	if params.UserAgentMetadata == nil {
+		t.Fatal("Override should contain userAgentMetadata")
+	}
+	metadata := params.UserAgentMetadata
+	if metadata.Platform != "Android" || !metadata.Mobile || metadata.Model != "Pixel 8a" || len(metadata.Brands) != 3 {
+		t.Errorf("Unexpected userAgentMetadata: %+v", metadata)
+	}
+	if metadata.FormFactors[0] != "Mobile" {
+		t.Errorf("Expected Mobile form factor, got %v", metadata.FormFactors)
+	}
+
+	if NewInjector(NewSafari17iOS()).userAgentOverride().UserAgentMetadata != nil {
+		t.Error("Safari override should not contain userAgentMetadata")
+	}
+}
+
+func TestGetInjectionScriptWithTimezone(t *testing.T) {
+	script := NewInjector(NewDefaultFingerprint()).GetInjectionScript()
+
+	for _, part := range []string{
+		`"timezoneTransitions":[[0,300],[9961200000,240]`,
+		"utils.replaceConstructor(Intl, 'DateTimeFormat'",
+		"utils.replaceMethod(NativeDate.prototype, 'toTimeString'",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+	if strings.Contains(script, "'resolvedOptions'") {
+		t.Error("Script should not replace resolvedOptions")
+	}
+
+	fp := NewDefaultFingerprint()
+	fp.Timezone.ID = "Mars/Olympus_Mons"
+	if strings.Contains(NewInjector(fp).GetInjectionScript(), `"timezoneTransitions":`) {
+		t.Error("Unknown timezone should fall back to the constant offset")
+	}
+}
+
+func TestGetInjectionScriptWithLocale(t *testing.T) {
+	fp := NewDefaultFingerprint()
+	fp.Language = "de-DE"
+	fp.Languages = []string{"de-DE", "de", "en-US", "en"}
+
+	inj := NewInjector(fp)
+	script := inj.GetInjectionScript()
+	for _, part := range []string{
+		"Intl.getCanonicalLocales(cfg.language)",
+		"'RelativeTimeFormat'",
+		"String.prototype, 'localeCompare'",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Script should contain '%s'", part)
+		}
+	}
+
+	// navigator.languages и Accept-Language строятся из одного списка
+	params := inj.userAgentOverride()
+	if params.AcceptLanguage != "de-DE,de,en-US,en" {
+		t.Errorf("Expected accept language list from Languages, got %q", params.AcceptLanguage)
+	}
+}
+
+// newTestBrowser запускает headless Chrome для проверок в браузере.
+// Без Chrome и в режиме -short тест пропускается.
+func newTestBrowser(t *testing.T, flags ...chromedp.ExecAllocatorOption) context.Context {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("browser tests are skipped in short mode")
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.NoSandbox)
+	opts = append(opts, flags...)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancelTimeout := context.WithTimeout(allocCtx, time.Minute)
+	ctx, cancelCtx := chromedp.NewContext(ctx)
+	t.Cleanup(func() {
+		cancelCtx()
+		cancelTimeout()
+		cancelAlloc()
+	})
+
+	if err := chromedp.Run(ctx); err != nil {
+		t.Skipf("Chrome is not available: %v", err)
+	}
+	return ctx
+}
+
+// newTestServer отдает страницы, которые запоминают значения, видимые
+// первому скрипту документа
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".js") {
+			// Воркер сообщает значения, прочитанные кодом верхнего уровня
+			w.Header().Set("Content-Type", "text/javascript")
+			w.Write([]byte(`
+				const values = {
+					userAgent: navigator.userAgent,
+					timeZone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+					hardwareConcurrency: navigator.hardwareConcurrency
+				};
+				if ('onconnect' in self) {
+					self.onconnect = function(e) { e.ports[0].postMessage(values); };
+				} else if ('clients' in self) {
+					self.onmessage = function(e) { e.ports[0].postMessage(values); };
+				} else {
+					postMessage(values);
+				}
+			`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><script>
+			window.firstValues = {
+				userAgent: navigator.userAgent,
+				timeZone: Intl.DateTimeFormat().resolvedOptions().timeZone
+			};
+			if (parent !== window) {
+				parent.postMessage(window.firstValues, '*');
+			}
+			addEventListener('message', function(e) {
+				window.frameValues = e.data;
+			});
+		</script></head><body></body></html>`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// firstValues возвращает значения, которые увидел первый скрипт документа
+func firstValues(ctx context.Context) (map[string]string, error) {
+	var values map[string]string
+	err := chromedp.Run(ctx, chromedp.Poll(`window.firstValues`, &values, chromedp.WithPollingTimeout(10*time.Second)))
+	return values, err
+}
+
+func TestAutoAttachPopup(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Android()
+	inj := NewInjector(fp, WithAutoAttach())
+	defer inj.StopAutoAttach()
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	// chromedp.WaitNewTarget пропускает цели, к которым уже подключились
+	popup := make(chan target.ID, 1)
+	lctx, stop := context.WithCancel(ctx)
+	defer stop()
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		if e, ok := ev.(*target.EventTargetInfoChanged); ok && strings.HasSuffix(e.TargetInfo.URL, "/popup") {
+			select {
+			case popup <- e.TargetInfo.TargetID:
+			default:
+			}
+		}
+	})
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`window.open('/popup'), true`, nil)); err != nil {
+		t.Fatalf("window.open failed: %v", err)
+	}
+
+	var id target.ID
+	select {
+	case id = <-popup:
+	case <-ctx.Done():
+		t.Fatal("Popup was not opened")
+	}
+	pctx, cancel := chromedp.NewContext(ctx, chromedp.WithTargetID(id))
+	defer cancel()
+
+	values, err := firstValues(pctx)
+	if err != nil {
+		t.Fatalf("Popup did not load: %v", err)
+	}
+	if values["userAgent"] != fp.UserAgent || values["timeZone"] != fp.Timezone.ID {
+		t.Errorf("Popup document should start with the fingerprint, got %v", values)
+	}
+
+	var platform string
+	if err := chromedp.Run(pctx, chromedp.Evaluate(`navigator.platform`, &platform)); err != nil || platform != fp.Platform {
+		t.Errorf("Popup should get the injection script, got platform %q (%v)", platform, err)
+	}
+}
+
+func TestAutoAttachIframe(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Windows11()
+	inj := NewInjector(fp, WithAutoAttach())
+	defer inj.StopAutoAttach()
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	// localhost и 127.0.0.1 - разные сайты, iframe выполняется в отдельном процессе
+	frameURL := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/frame"
+	var values map[string]string
+	err := chromedp.Run(ctx,
+		chromedp.Evaluate(`document.body.appendChild(Object.assign(document.createElement('iframe'), {src: `+"`"+frameURL+"`"+`})), true`, nil),
+		chromedp.Poll(`window.frameValues`, &values, chromedp.WithPollingTimeout(10*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("Iframe did not load: %v", err)
+	}
+	if values["userAgent"] != fp.UserAgent || values["timeZone"] != fp.Timezone.ID {
+		t.Errorf("Iframe document should start with the fingerprint, got %v", values)
+	}
+}
+
+func TestAutoAttachWorkers(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Android()
+	inj := NewInjector(fp, WithAutoAttach())
+	defer inj.StopAutoAttach()
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		description string
+		start       string
+	}{
+		{"dedicated worker", `new Worker('/dedicated.js').onmessage = function(e) { window.workerValues = e.data; }`},
+		{"service worker", `navigator.serviceWorker.register('/service.js').then(function() {
+			return navigator.serviceWorker.ready;
+		}).then(function(reg) {
+			const channel = new MessageChannel();
+			channel.port1.onmessage = function(e) { window.workerValues = e.data; };
+			reg.active.postMessage(null, [channel.port2]);
+		})`},
+		// Shared Worker Chrome не ставит на паузу, хотя сообщает
+		// waitingForDebugger, поэтому его код верхнего уровня видит
+		// исходные значения (см. README)
+	} {
+		var values map[string]interface{}
+		err := chromedp.Run(ctx,
+			chromedp.Evaluate(`delete window.workerValues; `+tt.start+`; true`, nil),
+			chromedp.Poll(`window.workerValues`, &values, chromedp.WithPollingTimeout(10*time.Second)),
+		)
+		if err != nil {
+			t.Fatalf("%s did not respond: %v", tt.description, err)
+		}
+		if !workerMatches(values, fp) {
+			t.Errorf("%s top-level code should see the fingerprint, got %v", tt.description, values)
+		}
+	}
+}
+
+func TestWorkerWrapper(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Android()
+	inj := NewInjector(fp, WithWorkerWrapper())
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	var values map[string]interface{}
+	err := chromedp.Run(ctx,
+		chromedp.Evaluate(`new Worker('/dedicated.js').onmessage = function(e) { window.workerValues = e.data; }; true`, nil),
+		chromedp.Poll(`window.workerValues`, &values, chromedp.WithPollingTimeout(10*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("Worker did not respond: %v", err)
+	}
+	if !workerMatches(values, fp) {
+		t.Errorf("Wrapped worker should see the fingerprint, got %v", values)
+	}
+}
+
+func TestAutomationPermissions(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	inj := NewInjector(NewChrome134Windows11())
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	var result struct {
+		Native     bool   `json:"native"`
+		Listeners  bool   `json:"listeners"`
+		State      string `json:"state"`
+		Permission string `json:"permission"`
+		Other      string `json:"other"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(`Promise.all([
+		navigator.permissions.query({ name: 'notifications' }),
+		navigator.permissions.query({ name: 'geolocation' })
+	]).then(function(statuses) {
+		const status = statuses[0];
+		return {
+			native: status instanceof PermissionStatus,
+			listeners: typeof status.addEventListener === 'function' && 'onchange' in status,
+			state: status.state,
+			permission: Notification.permission,
+			other: statuses[1].state
+		};
+	})`, &result, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if !result.Native || !result.Listeners {
+		t.Error("permissions.query should resolve to a real PermissionStatus")
+	}
+	want := result.Permission
+	if want == "default" {
+		want = "prompt"
+	}
+	if result.State != want {
+		t.Errorf("Notifications state %q should match Notification.permission %q", result.State, result.Permission)
+	}
+	if result.Other == "" {
+		t.Error("Other permissions should keep their state")
+	}
+}
+
+func TestWebRTCRewriter(t *testing.T) {
+	ctx := newTestBrowser(t)
+
+	const (
+		host  = "candidate:842163049 1 udp 2122260223 192.168.1.23 54321 typ host generation 0 network-id 1"
+		srflx = "candidate:1 1 udp 1686052607 203.0.113.7 60000 typ srflx raddr 192.168.1.23 rport 54321 generation 0"
+		relay = "candidate:2 1 udp 41885439 198.51.100.2 3478 typ relay raddr 203.0.113.7 rport 60000"
+		sdp   = "v=0\r\n" +
+			"m=audio 60000 UDP/TLS/RTP/SAVPF 111\r\n" +
+			"c=IN IP4 192.168.1.23\r\n" +
+			"a=rtcp:9 IN IP4 0.0.0.0\r\n" +
+			"a=candidate:842163049 1 udp 2122260223 192.168.1.23 54321 typ host generation 0\r\n" +
+			"a=candidate:1 1 udp 1686052607 203.0.113.7 60000 typ srflx raddr 192.168.1.23 rport 54321\r\n"
+	)
+	mdns := regexp.MustCompile(` [0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.local 54321 `)
+
+	fp := NewChrome134Windows11()
+	fp.WebRTC = &WebRTC{Mode: WebRTCModeMask, LocalIP: "10.0.0.5", PublicIP: "198.18.0.1"}
+	for _, tt := range []struct {
+		candidate, expected string
+	}{
+		{host, "candidate:842163049 1 udp 2122260223 10.0.0.5 54321 typ host generation 0 network-id 1"},
+		{"a=" + host, "a=candidate:842163049 1 udp 2122260223 10.0.0.5 54321 typ host generation 0 network-id 1"},
+		{srflx, "candidate:1 1 udp 1686052607 198.18.0.1 60000 typ srflx raddr 0.0.0.0 rport 0 generation 0"},
+		{relay, "candidate:2 1 udp 41885439 198.51.100.2 3478 typ relay raddr 0.0.0.0 rport 0"},
+		{"", ""},
+		{"candidate:broken", "candidate:broken"},
+	} {
+		if got := rewriteWithScript(t, ctx, fp, "rewriteCandidate", tt.candidate); got != tt.expected {
+			t.Errorf("rewriteCandidate(%q) = %q, expected %q", tt.candidate, got, tt.expected)
+		}
+	}
+
+	// Без LocalIP host-кандидат получает постоянное имя mDNS, в том числе
+	// для User-Agent с символами вне ASCII
+	fp.WebRTC.LocalIP = ""
+	fp.UserAgent += " Ü"
+	got := rewriteWithScript(t, ctx, fp, "rewriteCandidate", host)
+	if !mdns.MatchString(got) || got != rewriteWithScript(t, ctx, fp, "rewriteCandidate", host) {
+		t.Errorf("Host candidate should get a stable mDNS name, got %q", got)
+	}
+	if rewriteWithScript(t, ctx, fp, "rewriteCandidate", got) != got {
+		t.Error("mDNS candidate should not be rewritten again")
+	}
+
+	fp.WebRTC = &WebRTC{Mode: WebRTCModeProxyOnly, LocalIP: "10.0.0.5"}
+	expected := "v=0\r\n" +
+		"m=audio 60000 UDP/TLS/RTP/SAVPF 111\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"a=rtcp:9 IN IP4 0.0.0.0\r\n" +
+		"a=candidate:842163049 1 udp 2122260223 10.0.0.5 54321 typ host generation 0\r\n" +
+		"a=candidate:1 1 udp 1686052607 203.0.113.7 60000 typ srflx raddr 0.0.0.0 rport 0\r\n"
+	if got := rewriteWithScript(t, ctx, fp, "rewriteSDP", sdp); got != expected {
+		t.Errorf("rewriteSDP returned:\n%q\nexpected:\n%q", got, expected)
+	}
+
+	fp.WebRTC.PublicIP = "2001:db8::1"
+	if got := rewriteWithScript(t, ctx, fp, "rewriteSDP", "c=IN IP4 192.168.1.23\n"); got != "c=IN IP6 2001:db8::1\n" {
+		t.Errorf("Connection address should be replaced with PublicIP, got %q", got)
+	}
+
+	// Некорректные адреса считаются незаданными
+	fp.WebRTC = &WebRTC{Mode: WebRTCModeMask, LocalIP: "10.0.0", PublicIP: "not-an-ip"}
+	if got := rewriteWithScript(t, ctx, fp, "rewriteSDP", "c=IN IP4 192.168.1.23\n"); got != "c=IN IP4 0.0.0.0\n" {
+		t.Errorf("Invalid PublicIP should not be used, got %q", got)
+	}
+	if got := rewriteWithScript(t, ctx, fp, "rewriteCandidate", srflx); !strings.Contains(got, " 203.0.113.7 ") {
+		t.Errorf("Invalid PublicIP should not replace the address, got %q", got)
+	}
+	if got := rewriteWithScript(t, ctx, fp, "rewriteCandidate", host); !mdns.MatchString(got) {
+		t.Errorf("Invalid LocalIP should fall back to mDNS, got %q", got)
+	}
+}
+
+func TestWebRTCPatch(t *testing.T) {
+	ctx := newTestBrowser(t)
+	srv := newTestServer(t)
+
+	fp := NewChrome134Windows11()
+	fp.WebRTC = &WebRTC{Mode: WebRTCModeMask, PublicIP: "198.18.0.1"}
+	inj := NewInjector(fp)
+	if err := chromedp.Run(ctx, inj.ApplyAll(ctx), chromedp.Navigate(srv.URL+"/page")); err != nil {
+		t.Fatalf("ApplyAll failed: %v", err)
+	}
+
+	var result []string
+	err := chromedp.Run(ctx, chromedp.Evaluate(`(function() {
+		const c = new RTCIceCandidate({
+			candidate: 'candidate:1 1 udp 1686052607 203.0.113.7 60000 typ srflx raddr 192.168.1.23 rport 54321',
+			sdpMid: '0'
+		});
+		return [c.candidate, c.address, c.relatedAddress, JSON.stringify(c.toJSON())];
+	})()`, &result))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	const masked = "candidate:1 1 udp 1686052607 198.18.0.1 60000 typ srflx raddr 0.0.0.0 rport 0"
+	if result[0] != masked || result[1] != "198.18.0.1" || result[2] != "0.0.0.0" || !strings.Contains(result[3], masked) {
+		t.Errorf("RTCIceCandidate should be masked, got %q", result)
+	}
+}
+
+// rewriteWithScript вызывает функцию маскировки WebRTC из скрипта
+// инжектирования для fingerprint
+func rewriteWithScript(t *testing.T, ctx context.Context, fp *Fingerprint, fn, input string) string {
+	t.Helper()
+	config, err := jsLiteral(newScriptConfig(fp))
+	if err != nil {
+		t.Fatalf("jsLiteral failed: %v", err)
+	}
+	arg, _ := jsLiteral(input)
+
+	var result string
+	script := "(function() {\n'use strict';\nconst cfg = " + config + ";\n" + nativeUtilsScript +
+		webrtcRewriteScript + "\nreturn " + fn + "(" + arg + ");\n})()"
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &result)); err != nil {
+		t.Fatalf("%s(%q) failed: %v", fn, input, err)
+	}
+	return result
+}
+
+// workerMatches сравнивает значения, прочитанные воркером, с fingerprint
+func workerMatches(values map[string]interface{}, fp *Fingerprint) bool {
+	return values["userAgent"] == fp.UserAgent &&
+		values["timeZone"] == fp.Timezone.ID &&
+		values["hardwareConcurrency"] == float64(fp.HardwareConcurrency)
+}
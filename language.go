@@ -0,0 +1,46 @@
+package fingerprint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AcceptLanguage строит заголовок Accept-Language из списка языков так же,
+// как Chrome: первый язык без q-значения, у каждого следующего q меньше
+// на 0.1, но не меньше 0.1.
+// Например, [de-DE de en-US en] дает "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7".
+func AcceptLanguage(languages []string) string {
+	var b strings.Builder
+	q := 10
+	for _, lang := range languages {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		if q == 10 {
+			b.WriteString(lang)
+		} else {
+			fmt.Fprintf(&b, ",%s;q=0.%d", lang, q)
+		}
+		if q > 1 {
+			q--
+		}
+	}
+	return b.String()
+}
+
+// AcceptLanguage возвращает заголовок Accept-Language fingerprint для
+// подмены заголовков в перехвате запросов (Fetch, прокси). Переопределение
+// User-Agent через CDP передает Chrome список языков, и браузер строит
+// из него тот же заголовок.
+func (f *Fingerprint) AcceptLanguage() string {
+	return AcceptLanguage(f.languageList())
+}
+
+// languageList возвращает Languages или, если список пуст, Language
+func (f *Fingerprint) languageList() []string {
+	if len(f.Languages) == 0 && f.Language != "" {
+		return []string{f.Language}
+	}
+	return f.Languages
+}
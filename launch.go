@@ -0,0 +1,28 @@
+package fingerprint
+
+import "github.com/chromedp/chromedp"
+
+// AllocatorOptions возвращает флаги запуска Chrome для fingerprint.
+// Их нужно передать в chromedp.NewExecAllocator: часть настроек, например
+// политику WebRTC, pointer, hover и dynamic-range, нельзя изменить через
+// CDP после запуска браузера. Флаг --blink-settings заменяет такой же
+// флаг, переданный раньше.
+//
+//	opts := append(chromedp.DefaultExecAllocatorOptions[:], fingerprint.AllocatorOptions()...)
+//	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
+func (f *Fingerprint) AllocatorOptions() []chromedp.ExecAllocatorOption {
+	var opts []chromedp.ExecAllocatorOption
+	if policy := f.WebRTC.ipHandlingPolicy(); policy != "" {
+		opts = append(opts, chromedp.Flag("force-webrtc-ip-handling-policy", policy))
+	}
+	if m := f.MediaFeatures; m != nil {
+		if settings := m.blinkSettings(); settings != "" {
+			opts = append(opts, chromedp.Flag("blink-settings", settings))
+		}
+		// HDR-профиль дисплея: dynamic-range зависит только от экрана
+		if m.DynamicRange == "high" {
+			opts = append(opts, chromedp.Flag("force-color-profile", "hdr10"))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,157 @@
+package fingerprint
+
+import "strings"
+
+// Виды устройств MediaDeviceInfo.kind
+const (
+	MediaAudioInput  = "audioinput"
+	MediaVideoInput  = "videoinput"
+	MediaAudioOutput = "audiooutput"
+)
+
+// Ответы decodingInfo: аппаратное и программное декодирование
+var (
+	codecHardware    = MediaCodec{Supported: true, Smooth: true, PowerEfficient: true}
+	codecSoftware    = MediaCodec{Supported: true, Smooth: true}
+	codecUnsupported = MediaCodec{}
+)
+
+// newMediaDevices создает устройства и ответы mediaCapabilities для
+// браузера на платформе. model - название устройства ("MacBook Pro",
+// "iPhone 15 Pro"), webcam - есть ли у компьютера веб-камера.
+func newMediaDevices(browser, platform, model string, webcam bool, gpuRenderer string) *MediaDevices {
+	return &MediaDevices{
+		Devices: mediaDeviceList(browser, platform, model, webcam),
+		Codecs:  mediaCodecs(browser, platform, gpuRenderer),
+	}
+}
+
+// mediaDeviceList возвращает список enumerateDevices в порядке браузера:
+// микрофоны, камеры, устройства вывода. Chrome добавляет на Windows
+// записи "default" и "communications", на macOS и Linux - "default".
+func mediaDeviceList(browser, platform, model string, webcam bool) []MediaDevice {
+	chrome := browser == "Chrome"
+	var devices []MediaDevice
+	// add добавляет устройство и записи по умолчанию Chrome для него
+	add := func(kind, label string, group int, defaults ...string) {
+		if chrome {
+			for _, id := range defaults {
+				prefix := "Default"
+				if id == "communications" {
+					prefix = "Communications"
+				}
+				devices = append(devices, MediaDevice{Kind: kind, Label: prefix + " - " + label, DeviceID: id, Group: group})
+			}
+		}
+		devices = append(devices, MediaDevice{Kind: kind, Label: label, Group: group})
+	}
+
+	switch platformOS(platform) {
+	case "ios":
+		device := "iPhone"
+		if platform == "iPad" {
+			device = "iPad"
+		}
+		devices = append(devices,
+			MediaDevice{Kind: MediaAudioInput, Label: device + " Microphone", Group: 1},
+			MediaDevice{Kind: MediaVideoInput, Label: "Front Camera", Group: 2, FacingMode: "user"},
+			MediaDevice{Kind: MediaVideoInput, Label: "Back Camera", Group: 3, FacingMode: "environment"},
+		)
+		if strings.Contains(model, "Pro") {
+			devices = append(devices,
+				MediaDevice{Kind: MediaVideoInput, Label: "Back Ultra Wide Camera", Group: 4, FacingMode: "environment"},
+				MediaDevice{Kind: MediaVideoInput, Label: "Back Telephoto Camera", Group: 5, FacingMode: "environment"},
+			)
+		}
+	case "android":
+		devices = append(devices,
+			MediaDevice{Kind: MediaAudioInput, Label: "Default", DeviceID: "default", Group: 1},
+			MediaDevice{Kind: MediaVideoInput, Label: "camera2 1, facing front", Group: 2, FacingMode: "user"},
+			MediaDevice{Kind: MediaVideoInput, Label: "camera2 0, facing back", Group: 3, FacingMode: "environment"},
+		)
+	case "macos":
+		if model == "" {
+			model = "MacBook Pro"
+		}
+		suffix := ""
+		if chrome {
+			suffix = " (Built-in)"
+		}
+		add(MediaAudioInput, model+" Microphone"+suffix, 1, "default")
+		add(MediaVideoInput, "FaceTime HD Camera", 2)
+		if chrome {
+			add(MediaAudioOutput, model+" Speakers"+suffix, 3, "default")
+		}
+	case "windows":
+		add(MediaAudioInput, "Microphone (Realtek(R) Audio)", 1, "default", "communications")
+		if webcam {
+			add(MediaAudioInput, "Microphone (HD Pro Webcam C920)", 2)
+			label := "HD Pro Webcam C920"
+			if chrome {
+				label += " (046d:082d)"
+			}
+			add(MediaVideoInput, label, 2)
+		}
+		if chrome {
+			add(MediaAudioOutput, "Speakers (Realtek(R) Audio)", 1, "default", "communications")
+		}
+	default:
+		add(MediaAudioInput, "Built-in Audio Analog Stereo", 1, "default")
+		if webcam {
+			add(MediaVideoInput, "Integrated_Webcam_HD: Integrated_Webcam_HD (0c45:6713)", 2)
+		}
+		if chrome {
+			add(MediaAudioOutput, "Built-in Audio Analog Stereo", 1, "default")
+		}
+	}
+	return devices
+}
+
+// mediaCodecs возвращает ответы decodingInfo по кодекам: аппаратное
+// декодирование зависит от платформы и видеокарты
+func mediaCodecs(browser, platform, gpuRenderer string) map[string]MediaCodec {
+	os := platformOS(platform)
+	hardware := func(ok bool) MediaCodec {
+		if ok {
+			return codecHardware
+		}
+		return codecSoftware
+	}
+	// AV1 аппаратно декодируют RTX 30+, RX 6000+, Intel Xe, Apple M3 и A17 Pro
+	av1 := false
+	for _, gpu := range []string{"RTX 30", "RTX 40", "RX 6", "RX 7", "Iris Xe", "UHD Graphics 7", "M3", "A17"} {
+		if strings.Contains(gpuRenderer, gpu) {
+			av1 = true
+		}
+	}
+
+	h264 := hardware(os != "linux")
+	vp9 := hardware(os != "linux" && browser != "Firefox")
+	hevc := codecHardware
+	vorbis := codecHardware
+	switch {
+	case os == "linux" || browser == "Firefox":
+		hevc = codecUnsupported
+	case browser == "Safari" || os == "ios":
+		vorbis = codecUnsupported
+		vp9 = codecSoftware
+	}
+	av01 := hardware(av1)
+	if (browser == "Safari" || os == "ios") && !av1 {
+		av01 = codecUnsupported
+	}
+
+	return map[string]MediaCodec{
+		"avc1":   h264,
+		"vp8":    codecSoftware,
+		"vp9":    vp9,
+		"vp09":   vp9,
+		"av01":   av01,
+		"hev1":   hevc,
+		"hvc1":   hevc,
+		"opus":   codecHardware,
+		"mp4a":   codecHardware,
+		"flac":   codecHardware,
+		"vorbis": vorbis,
+	}
+}
@@ -0,0 +1,182 @@
+package fingerprint
+
+import (
+	"fmt"
+)
+
+// Patch часть скрипта инжектирования, переопределяющая одну группу API.
+// Тело патча выполняется внутри общего скрипта, где доступен объект cfg
+// (JSON-сериализация Fingerprint).
+type Patch interface {
+	// Name уникальное имя патча
+	Name() string
+	// Dependencies имена патчей, которые должны быть подключены раньше
+	Dependencies() []string
+	// Script JavaScript код патча
+	Script() string
+	// Enabled сообщает, нужно ли подключать патч для данного fingerprint
+	Enabled(fp *Fingerprint) bool
+}
+
+// WorkerPatch патч, который также выполняется внутри Web Worker'ов.
+// WorkerScript выполняется в глобальном контексте воркера (self),
+// где доступны cfg и utils, но нет window и document.
+type WorkerPatch interface {
+	Patch
+	WorkerScript() string
+}
+
+// ScriptPatch простая реализация Patch и WorkerPatch
+type ScriptPatch struct {
+	PatchName  string
+	Requires   []string
+	Body       string
+	WorkerBody string                     // пусто - патч не выполняется в воркерах
+	When       func(fp *Fingerprint) bool // nil - патч включен всегда
+}
+
+// Name возвращает имя патча
+func (p *ScriptPatch) Name() string {
+	return p.PatchName
+}
+
+// Dependencies возвращает зависимости патча
+func (p *ScriptPatch) Dependencies() []string {
+	return p.Requires
+}
+
+// Script возвращает JavaScript код патча
+func (p *ScriptPatch) Script() string {
+	return p.Body
+}
+
+// WorkerScript возвращает JavaScript код патча для воркеров
+func (p *ScriptPatch) WorkerScript() string {
+	return p.WorkerBody
+}
+
+// Enabled сообщает, включен ли патч для fingerprint
+func (p *ScriptPatch) Enabled(fp *Fingerprint) bool {
+	if p.When == nil {
+		return true
+	}
+	return p.When(fp)
+}
+
+// PatchRegistry набор патчей, из которых собирается скрипт
+type PatchRegistry struct {
+	patches map[string]Patch
+	order   []string
+}
+
+// NewPatchRegistry создает реестр с заданными патчами
+func NewPatchRegistry(patches ...Patch) *PatchRegistry {
+	r := &PatchRegistry{
+		patches: make(map[string]Patch),
+	}
+	for _, p := range patches {
+		r.Register(p)
+	}
+	return r
+}
+
+// DefaultPatchRegistry создает реестр со встроенными патчами
+func DefaultPatchRegistry() *PatchRegistry {
+	return NewPatchRegistry(builtinPatches()...)
+}
+
+// Register добавляет патч в реестр.
+// Патч с уже существующим именем заменяет старый, сохраняя его позицию.
+func (r *PatchRegistry) Register(p Patch) {
+	name := p.Name()
+	if _, ok := r.patches[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.patches[name] = p
+}
+
+// Unregister удаляет патч из реестра
+func (r *PatchRegistry) Unregister(name string) {
+	if _, ok := r.patches[name]; !ok {
+		return
+	}
+	delete(r.patches, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get возвращает патч по имени
+func (r *PatchRegistry) Get(name string) (Patch, bool) {
+	p, ok := r.patches[name]
+	return p, ok
+}
+
+// Names возвращает имена патчей в порядке регистрации
+func (r *PatchRegistry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Resolve возвращает включенные для fingerprint патчи в порядке зависимостей.
+// Патчи из without, выключенные для fingerprint, а также патчи, зависящие
+// от них, пропускаются. Неизвестная зависимость или цикл приводят к ошибке.
+// При отсутствии зависимостей сохраняется порядок регистрации.
+func (r *PatchRegistry) Resolve(fp *Fingerprint, without ...string) ([]Patch, error) {
+	skipped := make(map[string]bool, len(without))
+	for _, name := range without {
+		skipped[name] = true
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(r.order))
+	active := make(map[string]bool, len(r.order))
+	var result []Patch
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("patch dependency cycle: %v", append(path, name))
+		case done:
+			return nil
+		}
+
+		p, ok := r.patches[name]
+		if !ok {
+			return fmt.Errorf("patch %q: unknown dependency %q", path[len(path)-1], name)
+		}
+
+		state[name] = visiting
+		enabled := !skipped[name] && p.Enabled(fp)
+		for _, dep := range p.Dependencies() {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+			if !active[dep] {
+				enabled = false
+			}
+		}
+		state[name] = done
+
+		if enabled {
+			active[name] = true
+			result = append(result, p)
+		}
+		return nil
+	}
+
+	for _, name := range r.order {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package fingerprint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func patchNames(patches []Patch) []string {
+	names := make([]string, 0, len(patches))
+	for _, p := range patches {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+func TestPatchRegistryResolveOrder(t *testing.T) {
+	r := NewPatchRegistry(
+		&ScriptPatch{PatchName: "c", Requires: []string{"b"}},
+		&ScriptPatch{PatchName: "a"},
+		&ScriptPatch{PatchName: "b", Requires: []string{"a"}},
+		&ScriptPatch{PatchName: "d"},
+	)
+
+	patches, err := r.Resolve(NewDefaultFingerprint())
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	expected := []string{"a", "b", "c", "d"}
+	if got := patchNames(patches); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+}
+
+func TestPatchRegistryResolveSkipsDependents(t *testing.T) {
+	r := NewPatchRegistry(
+		&ScriptPatch{PatchName: "base", When: func(fp *Fingerprint) bool { return fp.WebGL != nil }},
+		&ScriptPatch{PatchName: "extra", Requires: []string{"base"}},
+		&ScriptPatch{PatchName: "other"},
+	)
+
+	fp := NewDefaultFingerprint()
+	fp.WebGL = nil
+
+	patches, err := r.Resolve(fp)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got := patchNames(patches); !reflect.DeepEqual(got, []string{"other"}) {
+		t.Errorf("Expected only 'other', got %v", got)
+	}
+
+	patches, err = r.Resolve(NewDefaultFingerprint(), "base")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got := patchNames(patches); !reflect.DeepEqual(got, []string{"other"}) {
+		t.Errorf("Expected only 'other' without 'base', got %v", got)
+	}
+}
+
+func TestPatchRegistryResolveErrors(t *testing.T) {
+	unknown := NewPatchRegistry(&ScriptPatch{PatchName: "a", Requires: []string{"missing"}})
+	if _, err := unknown.Resolve(NewDefaultFingerprint()); err == nil {
+		t.Error("Expected error for unknown dependency")
+	}
+
+	cycle := NewPatchRegistry(
+		&ScriptPatch{PatchName: "a", Requires: []string{"b"}},
+		&ScriptPatch{PatchName: "b", Requires: []string{"a"}},
+	)
+	if _, err := cycle.Resolve(NewDefaultFingerprint()); err == nil {
+		t.Error("Expected error for dependency cycle")
+	}
+}
+
+func TestPatchRegistryReplaceAndUnregister(t *testing.T) {
+	r := NewPatchRegistry(
+		&ScriptPatch{PatchName: "a", Body: "old"},
+		&ScriptPatch{PatchName: "b"},
+	)
+	r.Register(&ScriptPatch{PatchName: "a", Body: "new"})
+
+	if got := r.Names(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Replacing a patch should keep its position, got %v", got)
+	}
+	if p, _ := r.Get("a"); p.Script() != "new" {
+		t.Error("Register should replace patch with the same name")
+	}
+
+	r.Unregister("a")
+	if _, ok := r.Get("a"); ok {
+		t.Error("Unregister should remove patch")
+	}
+	if got := r.Names(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Expected [b], got %v", got)
+	}
+}
+
+func TestInjectorWithPatches(t *testing.T) {
+	custom := &ScriptPatch{
+		PatchName: "internal-site",
+		Requires:  []string{PatchNavigator},
+		Body:      "window.__internalSitePatch = true;",
+	}
+
+	script := NewInjector(NewDefaultFingerprint(), WithPatches(custom)).GetInjectionScript()
+	if !strings.Contains(script, "window.__internalSitePatch = true;") {
+		t.Error("Script should contain custom patch")
+	}
+	if strings.Index(script, "navigator.userAgent") > strings.Index(script, "__internalSitePatch") {
+		t.Error("Custom patch should come after its dependency")
+	}
+}
+
+func TestInjectorWithoutPatches(t *testing.T) {
+	script := NewInjector(NewDefaultFingerprint(), WithoutPatches(PatchWebGL, PatchPlugins)).GetInjectionScript()
+
+	if script == "" {
+		t.Fatal("Injection script should not be empty")
+	}
+	if strings.Contains(script, "getParameter") {
+		t.Error("Script should not contain WebGL patch")
+	}
+	if strings.Contains(script, "'plugins'") {
+		t.Error("Script should not contain plugins patch")
+	}
+	if !strings.Contains(script, "navigator.userAgent") {
+		t.Error("Script should still contain navigator patch")
+	}
+}
@@ -0,0 +1,2268 @@
+package fingerprint
+
+// Имена встроенных патчей
+const (
+	PatchNavigator   = "navigator"
+	PatchScreen      = "screen"
+	PatchWebGL       = "webgl"
+	PatchWebGPU      = "webgpu"
+	PatchCanvas      = "canvas"
+	PatchWebRTC      = "webrtc"
+	PatchBattery     = "battery"
+	PatchTimezone    = "timezone"
+	PatchAutomation  = "automation"
+	PatchPlugins     = "plugins"
+	PatchWorkers     = "workers"
+	PatchAudio       = "audio"
+	PatchFonts       = "fonts"
+	PatchClientHints = "clienthints"
+	PatchLocale      = "locale"
+	PatchMedia       = "media"
+	PatchConnection  = "connection"
+)
+
+// builtinPatches возвращает встроенные патчи в порядке подключения
+func builtinPatches() []Patch {
+	return []Patch{
+		&ScriptPatch{
+			PatchName:  PatchNavigator,
+			Body:       navigatorPatchScript,
+			WorkerBody: workerNavigatorPatchScript,
+		},
+		&ScriptPatch{
+			PatchName:  PatchClientHints,
+			Body:       clientHintsPatchScript,
+			WorkerBody: clientHintsPatchScript,
+		},
+		&ScriptPatch{
+			PatchName: PatchScreen,
+			Body:      screenPatchScript,
+			When:      func(fp *Fingerprint) bool { return fp.Screen != nil },
+		},
+		&ScriptPatch{
+			PatchName:  PatchWebGL,
+			Body:       webglPatchScript,
+			WorkerBody: webglPatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.WebGL != nil },
+		},
+		&ScriptPatch{
+			PatchName:  PatchWebGPU,
+			Body:       webgpuPatchScript,
+			WorkerBody: webgpuPatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.WebGPU != nil },
+		},
+		&ScriptPatch{
+			PatchName:  PatchCanvas,
+			Body:       canvasPatchScript + canvasElementPatchScript,
+			WorkerBody: canvasPatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.Canvas != nil && fp.Canvas.Noise > 0 },
+		},
+		&ScriptPatch{
+			PatchName: PatchAudio,
+			Body:      audioPatchScript,
+			When:      func(fp *Fingerprint) bool { return fp.Audio != nil },
+		},
+		&ScriptPatch{
+			PatchName: PatchFonts,
+			Body:      fontsPatchScript,
+			When:      func(fp *Fingerprint) bool { return len(fp.Fonts) > 0 },
+		},
+		&ScriptPatch{
+			PatchName: PatchWebRTC,
+			Body:      webrtcRewriteScript + webrtcPatchScript,
+			When:      func(fp *Fingerprint) bool { return fp.WebRTC.mode() != "" },
+		},
+		&ScriptPatch{
+			PatchName: PatchMedia,
+			Body:      mediaPatchScript,
+			When:      func(fp *Fingerprint) bool { return fp.MediaDevices != nil },
+		},
+		&ScriptPatch{
+			PatchName: PatchBattery,
+			Body:      batteryPatchScript,
+			When:      func(fp *Fingerprint) bool { return fp.Battery != nil },
+		},
+		&ScriptPatch{
+			PatchName:  PatchConnection,
+			Body:       connectionPatchScript,
+			WorkerBody: connectionPatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.Connection != nil },
+		},
+		&ScriptPatch{
+			PatchName:  PatchLocale,
+			Body:       localePatchScript,
+			WorkerBody: localePatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.Language != "" },
+		},
+		&ScriptPatch{
+			PatchName:  PatchTimezone,
+			Body:       timezonePatchScript,
+			WorkerBody: timezonePatchScript,
+			When:       func(fp *Fingerprint) bool { return fp.Timezone != nil },
+		},
+		&ScriptPatch{
+			PatchName: PatchAutomation,
+			Body:      automationPatchScript,
+		},
+		&ScriptPatch{
+			PatchName: PatchPlugins,
+			Body:      pluginsPatchScript,
+		},
+		&ScriptPatch{
+			PatchName:  PatchWorkers,
+			Body:       workersPatchScript,
+			WorkerBody: workersPatchScript,
+		},
+	}
+}
+
+const navigatorPatchScript = `
+		// Свойства navigator переопределяются на Navigator.prototype,
+		// где находятся нативные атрибуты
+
+		// Переопределяем navigator.userAgent
+		utils.replaceGetter(Navigator.prototype, 'userAgent', function() {
+			return cfg.userAgent;
+		});
+
+		// Переопределяем navigator.platform
+		utils.replaceGetter(Navigator.prototype, 'platform', function() {
+			return cfg.platform;
+		});
+
+		// Переопределяем navigator.vendor
+		utils.replaceGetter(Navigator.prototype, 'vendor', function() {
+			return cfg.vendor;
+		});
+
+		// Переопределяем navigator.language
+		utils.replaceGetter(Navigator.prototype, 'language', function() {
+			return cfg.language;
+		});
+
+		// Переопределяем navigator.languages (нативный геттер каждый раз
+		// возвращает один и тот же замороженный массив)
+		const languages = Object.freeze((cfg.languages || []).slice());
+		utils.replaceGetter(Navigator.prototype, 'languages', function() {
+			return languages;
+		});
+
+		// Переопределяем navigator.hardwareConcurrency
+		utils.replaceGetter(Navigator.prototype, 'hardwareConcurrency', function() {
+			return cfg.hardwareConcurrency;
+		});
+
+		// Переопределяем navigator.deviceMemory
+		utils.replaceGetter(Navigator.prototype, 'deviceMemory', function() {
+			return cfg.deviceMemory;
+		});
+`
+
+const workerNavigatorPatchScript = `
+		// В воркерах свойства navigator находятся на WorkerNavigator.prototype
+		if (typeof WorkerNavigator !== 'undefined') {
+			const languages = Object.freeze((cfg.languages || []).slice());
+			const workerValues = {
+				userAgent: cfg.userAgent,
+				platform: cfg.platform,
+				language: cfg.language,
+				languages: languages,
+				hardwareConcurrency: cfg.hardwareConcurrency,
+				deviceMemory: cfg.deviceMemory
+			};
+			Object.keys(workerValues).forEach(function(prop) {
+				if (prop in WorkerNavigator.prototype) {
+					utils.replaceGetter(WorkerNavigator.prototype, prop, function() {
+						return workerValues[prop];
+					});
+				}
+			});
+		}
+`
+
+const clientHintsPatchScript = `
+		// navigator.userAgentData из cfg.clientHints. Те же значения Chrome
+		// отправляет в заголовках Sec-CH-UA* после Emulation.setUserAgentOverride
+		// с userAgentMetadata
+		const uaNavigator = typeof Navigator !== 'undefined' ? Navigator.prototype :
+			(typeof WorkerNavigator !== 'undefined' ? WorkerNavigator.prototype : null);
+		if (uaNavigator && 'userAgentData' in uaNavigator) {
+			const hints = cfg.clientHints;
+			if (!hints) {
+				// Firefox и Safari не поддерживают userAgentData
+				if (cfg.userAgent.indexOf('Chrome/') === -1) {
+					delete uaNavigator.userAgentData;
+				}
+			} else if (typeof NavigatorUAData !== 'undefined') {
+				const copyBrands = function(list) {
+					return Object.freeze((list || []).map(function(b) {
+						return Object.freeze({ brand: b.brand, version: b.version });
+					}));
+				};
+				const brands = copyBrands(hints.brands);
+				const lowEntropy = function() {
+					return { brands: copyBrands(hints.brands), mobile: hints.mobile, platform: hints.platform };
+				};
+				const highEntropy = {
+					architecture: function() { return hints.architecture; },
+					bitness: function() { return hints.bitness; },
+					formFactors: function() { return [hints.mobile ? 'Mobile' : 'Desktop']; },
+					fullVersionList: function() { return copyBrands(hints.fullVersionList); },
+					model: function() { return hints.model; },
+					platformVersion: function() { return hints.platformVersion; },
+					uaFullVersion: function() {
+						const chrome = (hints.fullVersionList || []).filter(function(b) {
+							return b.brand === 'Google Chrome' || b.brand === 'Chromium';
+						})[0];
+						return chrome ? chrome.version : '';
+					},
+					wow64: function() { return hints.wow64; }
+				};
+
+				utils.replaceGetter(NavigatorUAData.prototype, 'brands', function() {
+					return brands;
+				});
+				utils.replaceGetter(NavigatorUAData.prototype, 'mobile', function() {
+					return hints.mobile;
+				});
+				utils.replaceGetter(NavigatorUAData.prototype, 'platform', function() {
+					return hints.platform;
+				});
+				utils.replaceMethod(NavigatorUAData.prototype, 'toJSON', function() {
+					return function toJSON() {
+						return lowEntropy();
+					};
+				});
+				utils.replaceMethod(NavigatorUAData.prototype, 'getHighEntropyValues', function(original) {
+					return function getHighEntropyValues(requested) {
+						// Нативный метод проверяет this и аргументы и отклоняет
+						// промис с теми же ошибками
+						return original.apply(this, arguments).then(function() {
+							const result = lowEntropy();
+							Array.prototype.forEach.call(requested, function(name) {
+								if (Object.prototype.hasOwnProperty.call(highEntropy, name)) {
+									result[name] = highEntropy[name]();
+								}
+							});
+							return result;
+						});
+					};
+				});
+			}
+		}
+`
+
+const screenPatchScript = `
+		// Переопределяем screen.width, screen.height, screen.availWidth,
+		// screen.availHeight, screen.colorDepth и screen.pixelDepth на Screen.prototype
+		['width', 'height', 'availWidth', 'availHeight', 'colorDepth', 'pixelDepth'].forEach(function(prop) {
+			utils.replaceGetter(Screen.prototype, prop, function() {
+				return cfg.screen[prop];
+			});
+		});
+
+		// devicePixelRatio - собственное свойство window
+		utils.replaceGetter(window, 'devicePixelRatio', function() {
+			return cfg.screen.devicePixelRatio;
+		});
+
+		// Внешний размер и положение окна - собственные свойства window;
+		// innerWidth и innerHeight задает Emulation.setDeviceMetricsOverride
+		const windowValues = cfg.window ? {
+			outerWidth: cfg.window.outerWidth,
+			outerHeight: cfg.window.outerHeight,
+			screenX: cfg.window.screenX,
+			screenY: cfg.window.screenY,
+			screenLeft: cfg.window.screenX,
+			screenTop: cfg.window.screenY
+		} : null;
+		if (windowValues) {
+			Object.keys(windowValues).forEach(function(prop) {
+				utils.replaceGetter(window, prop, function() {
+					return windowValues[prop];
+				});
+			});
+		}
+
+		// screen.orientation: без явного типа - по ширине и высоте экрана,
+		// как Screen.orientation в Go
+		if (typeof ScreenOrientation !== 'undefined') {
+			const orientation = {
+				type: cfg.screen.orientation ||
+					(cfg.screen.height > cfg.screen.width ? 'portrait-primary' : 'landscape-primary'),
+				angle: cfg.screen.orientationAngle || 0
+			};
+			const proto = ScreenOrientation.prototype;
+			const nativeType = Object.getOwnPropertyDescriptor(proto, 'type').get;
+			const nativeAngle = Object.getOwnPropertyDescriptor(proto, 'angle').get;
+			['type', 'angle'].forEach(function(prop) {
+				utils.replaceGetter(proto, prop, function() {
+					return orientation[prop];
+				});
+			});
+
+			// Поворот (Injector.Rotate) меняет ориентацию через
+			// Emulation.setDeviceMetricsOverride. Когда меняется исходная
+			// ориентация, размеры экрана и окна поворачиваются вслед за ней
+			// до обработчиков страницы: слушатели патча добавлены первыми,
+			// а orientationchange, change и resize приходят в разном порядке.
+			const screenOrientation = window.screen.orientation;
+			let nativeState = nativeType.call(screenOrientation) + nativeAngle.call(screenOrientation);
+			const sync = function() {
+				const type = nativeType.call(screenOrientation);
+				const angle = nativeAngle.call(screenOrientation);
+				if (type + angle === nativeState) {
+					return;
+				}
+				nativeState = type + angle;
+
+				const landscape = type.indexOf('landscape') === 0;
+				const s = cfg.screen;
+				if (s.width !== s.height && landscape !== (s.width > s.height)) {
+					[s.width, s.height] = [s.height, s.width];
+					[s.availWidth, s.availHeight] = [s.availHeight, s.availWidth];
+					if (windowValues) {
+						[windowValues.outerWidth, windowValues.outerHeight] =
+							[windowValues.outerHeight, windowValues.outerWidth];
+					}
+				}
+				orientation.type = type;
+				orientation.angle = angle;
+			};
+			screenOrientation.addEventListener('change', sync);
+			window.addEventListener('orientationchange', sync);
+			window.addEventListener('resize', sync);
+		}
+`
+
+const webglPatchScript = `
+		// WebGL: VENDOR, RENDERER, VERSION и SHADING_LANGUAGE_VERSION,
+		// UNMASKED_* из WEBGL_debug_renderer_info, лимиты getParameter,
+		// список расширений, точность шейдеров и атрибуты контекста.
+		// Оригиналы вызываются первыми, чтобы сохранить проверки аргументов,
+		// ошибки и поведение потерянного контекста.
+		const webgl = cfg.webgl;
+		const UNMASKED_VENDOR_WEBGL = 0x9245;
+		const UNMASKED_RENDERER_WEBGL = 0x9246;
+
+		// Значения WebGLShaderPrecisionFormat, возвращенных патчем
+		const precisionValues = new WeakMap();
+		if (typeof WebGLShaderPrecisionFormat !== 'undefined') {
+			['rangeMin', 'rangeMax', 'precision'].forEach(function(prop) {
+				utils.replaceGetter(WebGLShaderPrecisionFormat.prototype, prop, function(original) {
+					const value = precisionValues.get(this);
+					return value ? value[prop] : original.call(this);
+				});
+			});
+		}
+
+		const patchContext = function(Context, webgl2) {
+			const proto = Context.prototype;
+			const has = function(value) {
+				return value !== undefined && value !== null && value !== '';
+			};
+
+			// Значения getParameter по номеру константы; константы,
+			// которых нет в этой версии WebGL, пропускаются
+			const parameters = new Map();
+			const set = function(name, value) {
+				if (typeof Context[name] === 'number' && has(value)) {
+					parameters.set(Context[name], value);
+				}
+			};
+			Object.keys(webgl.parameters || {}).forEach(function(name) {
+				set(name, webgl.parameters[name]);
+			});
+			// MAX_VIEWPORT_DIMS - Int32Array, диапазоны - Float32Array
+			Object.keys(webgl.arrayParameters || {}).forEach(function(name) {
+				const Type = name === 'MAX_VIEWPORT_DIMS' ? Int32Array : Float32Array;
+				set(name, new Type(webgl.arrayParameters[name]));
+			});
+			set('VENDOR', webgl.vendor);
+			set('RENDERER', webgl.renderer);
+			if (!webgl2) {
+				set('VERSION', webgl.version);
+				set('SHADING_LANGUAGE_VERSION', webgl.shadingLanguageVersion);
+			}
+
+			const extensions = webgl2 ? webgl.webgl2Extensions : webgl.extensions;
+			const supported = extensions && extensions.length ? extensions.slice() : null;
+			const isSupported = function(name) {
+				if (!supported) {
+					return true;
+				}
+				const lower = String(name).toLowerCase();
+				return supported.some(function(ext) {
+					return ext.toLowerCase() === lower;
+				});
+			};
+			const debugInfo = isSupported('WEBGL_debug_renderer_info');
+			parameters.set(UNMASKED_VENDOR_WEBGL, webgl.unmaskedVendor);
+			parameters.set(UNMASKED_RENDERER_WEBGL, webgl.unmaskedRenderer);
+
+			utils.replaceMethod(proto, 'getParameter', function(original) {
+				return function getParameter(pname) {
+					const result = original.apply(this, arguments);
+					const key = Number(pname);
+					if (key === UNMASKED_VENDOR_WEBGL || key === UNMASKED_RENDERER_WEBGL) {
+						if (!debugInfo) {
+							return null;
+						}
+					}
+					if (!parameters.has(key) || !has(parameters.get(key))) {
+						return result;
+					}
+					const value = parameters.get(key);
+					// Каждый вызов возвращает новый массив, как в браузере
+					return ArrayBuffer.isView(value) ? value.slice() : value;
+				};
+			});
+
+			if (supported) {
+				utils.replaceMethod(proto, 'getSupportedExtensions', function(original) {
+					return function getSupportedExtensions() {
+						// null у потерянного контекста
+						const result = original.apply(this, arguments);
+						return result === null ? null : supported.slice();
+					};
+				});
+				utils.replaceMethod(proto, 'getExtension', function(original) {
+					return function getExtension(name) {
+						if (arguments.length > 0 && !isSupported(name)) {
+							// Проверка this без включения расширения
+							original.call(this, '');
+							return null;
+						}
+						return original.apply(this, arguments);
+					};
+				});
+			}
+
+			const precision = new Map();
+			Object.keys(webgl.shaderPrecision || {}).forEach(function(name) {
+				if (typeof Context[name] === 'number') {
+					precision.set(Context[name], webgl.shaderPrecision[name]);
+				}
+			});
+			if (precision.size) {
+				utils.replaceMethod(proto, 'getShaderPrecisionFormat', function(original) {
+					return function getShaderPrecisionFormat(shaderType, precisionType) {
+						const result = original.apply(this, arguments);
+						const value = precision.get(Number(precisionType));
+						if (result && value) {
+							precisionValues.set(result, value);
+						}
+						return result;
+					};
+				});
+			}
+
+			if (webgl.contextAttributes) {
+				utils.replaceMethod(proto, 'getContextAttributes', function(original) {
+					return function getContextAttributes() {
+						const result = original.apply(this, arguments);
+						return result && Object.assign(result, webgl.contextAttributes);
+					};
+				});
+			}
+		};
+
+		if (typeof WebGLRenderingContext !== 'undefined') {
+			patchContext(WebGLRenderingContext, false);
+		}
+		if (typeof WebGL2RenderingContext !== 'undefined') {
+			patchContext(WebGL2RenderingContext, true);
+		}
+`
+
+const webgpuPatchScript = `
+		// WebGPU: адаптер той же видеокарты, что и в WebGL. Патч меняет
+		// только то, что сообщает настоящий адаптер, поэтому requestDevice
+		// и отрисовка продолжают работать
+		const webgpu = cfg.webgpu;
+		const gpuScope = typeof window !== 'undefined' ? window : self;
+		const gpuNavigator = typeof Navigator !== 'undefined' ? Navigator.prototype :
+			(typeof WorkerNavigator !== 'undefined' ? WorkerNavigator.prototype : null);
+
+		if (webgpu.disable) {
+			// Как в браузерах без WebGPU: нет ни navigator.gpu, ни интерфейсов GPU*
+			if (gpuNavigator) {
+				delete gpuNavigator.gpu;
+			}
+			Object.getOwnPropertyNames(gpuScope).forEach(function(name) {
+				if (/^GPU/.test(name) || name === 'WGSLLanguageFeatures') {
+					delete gpuScope[name];
+				}
+			});
+		} else if (typeof GPU !== 'undefined') {
+			utils.replaceMethod(GPU.prototype, 'requestAdapter', function(original) {
+				return function requestAdapter() {
+					const result = original.apply(this, arguments);
+					return webgpu.noAdapter ? result.then(function() { return null; }) : result;
+				};
+			});
+			if (webgpu.preferredCanvasFormat) {
+				utils.replaceMethod(GPU.prototype, 'getPreferredCanvasFormat', function(original) {
+					return function getPreferredCanvasFormat() {
+						original.apply(this, arguments);
+						return webgpu.preferredCanvasFormat;
+					};
+				});
+			}
+
+			// GPUAdapterInfo из adapter.info, requestAdapterInfo()
+			// и device.adapterInfo. Пустые device и description тоже
+			// подменяются, чтобы не раскрыть настоящую видеокарту
+			if (webgpu.vendor && typeof GPUAdapterInfo !== 'undefined') {
+				['vendor', 'architecture', 'device', 'description'].forEach(function(prop) {
+					if (prop in GPUAdapterInfo.prototype) {
+						utils.replaceGetter(GPUAdapterInfo.prototype, prop, function() {
+							return webgpu[prop];
+						});
+					}
+				});
+			}
+
+			// Лимиты и функции подменяются только у адаптера: у устройства
+			// они зависят от запрошенных в requestDevice
+			const adapterLimits = new WeakSet();
+			const adapterFeatures = new WeakSet();
+			if (typeof GPUAdapter !== 'undefined') {
+				utils.replaceGetter(GPUAdapter.prototype, 'limits', function(original) {
+					const limits = original.call(this);
+					adapterLimits.add(limits);
+					return limits;
+				});
+				utils.replaceGetter(GPUAdapter.prototype, 'features', function(original) {
+					const features = original.call(this);
+					adapterFeatures.add(features);
+					return features;
+				});
+			}
+
+			if (webgpu.limits && typeof GPUSupportedLimits !== 'undefined') {
+				Object.keys(webgpu.limits).forEach(function(name) {
+					if (name in GPUSupportedLimits.prototype) {
+						utils.replaceGetter(GPUSupportedLimits.prototype, name, function(original) {
+							const value = original.call(this);
+							return adapterLimits.has(this) ? webgpu.limits[name] : value;
+						});
+					}
+				});
+			}
+
+			if (webgpu.features && typeof GPUSupportedFeatures !== 'undefined') {
+				const features = new Set(webgpu.features);
+				const proto = GPUSupportedFeatures.prototype;
+				const setMethod = function(name, impl) {
+					utils.replaceMethod(proto, name, function(original) {
+						return function() {
+							const result = original.apply(this, arguments);
+							return adapterFeatures.has(this) ? impl.apply(this, arguments) : result;
+						};
+					});
+				};
+				setMethod('has', function(name) { return features.has(String(name)); });
+				setMethod('values', function() { return features.values(); });
+				setMethod('keys', function() { return features.keys(); });
+				setMethod('entries', function() { return features.entries(); });
+				setMethod('forEach', function(callback, thisArg) {
+					const set = this;
+					features.forEach(function(value) {
+						callback.call(thisArg, value, value, set);
+					});
+				});
+				utils.replaceGetter(proto, 'size', function(original) {
+					const size = original.call(this);
+					return adapterFeatures.has(this) ? features.size : size;
+				});
+				// Symbol.iterator у setlike - та же функция, что и values
+				const values = Object.getOwnPropertyDescriptor(proto, 'values');
+				if (values && proto[Symbol.iterator]) {
+					Object.defineProperty(proto, Symbol.iterator, Object.assign(
+						{}, Object.getOwnPropertyDescriptor(proto, Symbol.iterator), { value: values.value }));
+				}
+			}
+		}
+`
+
+const canvasPatchScript = `
+		// Шум Canvas детерминирован: он зависит от seed fingerprint и координат
+		// пикселя, поэтому повторные чтения совпадают. toDataURL, toBlob и
+		// convertToBlob кодируют зашумленную копию, canvas страницы не меняется.
+		// Выполняется и в воркерах (OffscreenCanvas).
+		const canvasNoise = cfg.canvas.noise || 0;
+		const canvasSeed = utils.hash('canvas|' + (cfg.canvas.seed || cfg.userAgent));
+
+		// addNoise меняет на 1 часть каналов непрозрачных пикселей.
+		// left и top - положение данных на canvas, width - ширина canvas.
+		const addNoise = function(imageData, left, top, width) {
+			const data = imageData.data;
+			for (let i = 0; i < data.length; i += 4) {
+				if (data[i + 3] === 0) {
+					continue;
+				}
+				const p = i / 4;
+				const x = left + p % imageData.width;
+				const y = top + Math.floor(p / imageData.width);
+				const base = (y * width + x) * 4;
+				for (let c = 0; c < 3; c++) {
+					const r = utils.noise(canvasSeed, base + c);
+					if (r < canvasNoise) {
+						data[i + c] += r < canvasNoise / 2 ? 1 : -1;
+					}
+				}
+			}
+			return imageData;
+		};
+
+		const context2D = typeof CanvasRenderingContext2D !== 'undefined' ? CanvasRenderingContext2D.prototype : null;
+		const offscreen2D = typeof OffscreenCanvasRenderingContext2D !== 'undefined' ? OffscreenCanvasRenderingContext2D.prototype : null;
+		const nativeGetImageData = context2D && context2D.getImageData;
+		const nativeOffscreenGetImageData = offscreen2D && offscreen2D.getImageData;
+
+		// noisedCopy возвращает копию canvas с шумом или null,
+		// если копию сделать нельзя (пустой или tainted canvas)
+		const noisedCopy = function(canvas) {
+			try {
+				const width = canvas.width;
+				const height = canvas.height;
+				if (!(width > 0 && height > 0)) {
+					return null;
+				}
+				let copy;
+				let getImageData;
+				if (typeof OffscreenCanvas !== 'undefined' && canvas instanceof OffscreenCanvas) {
+					copy = new OffscreenCanvas(width, height);
+					getImageData = nativeOffscreenGetImageData;
+				} else {
+					copy = document.createElement('canvas');
+					copy.width = width;
+					copy.height = height;
+					getImageData = nativeGetImageData;
+				}
+				const context = copy.getContext('2d');
+				context.drawImage(canvas, 0, 0);
+				context.putImageData(addNoise(getImageData.call(context, 0, 0, width, height), 0, 0, width), 0, 0);
+				return copy;
+			} catch (e) {
+				return null;
+			}
+		};
+
+		[context2D, offscreen2D].forEach(function(proto) {
+			utils.replaceMethod(proto, 'getImageData', function(original) {
+				return function getImageData(sx, sy, sw, sh) {
+					const imageData = original.apply(this, arguments);
+					const left = Math.floor(sw < 0 ? sx + sw : sx);
+					const top = Math.floor(sh < 0 ? sy + sh : sy);
+					return addNoise(imageData, left, top, this.canvas.width);
+				};
+			});
+		});
+
+		if (typeof OffscreenCanvas !== 'undefined') {
+			utils.replaceMethod(OffscreenCanvas.prototype, 'convertToBlob', function(original) {
+				return function convertToBlob() {
+					const copy = this instanceof OffscreenCanvas ? noisedCopy(this) : null;
+					return original.apply(copy || this, arguments);
+				};
+			});
+		}
+`
+
+// canvasElementPatchScript шум для HTMLCanvasElement, выполняется
+// после canvasPatchScript только в окне
+const canvasElementPatchScript = `
+		if (typeof HTMLCanvasElement !== 'undefined') {
+			['toDataURL', 'toBlob'].forEach(function(name) {
+				utils.replaceMethod(HTMLCanvasElement.prototype, name, function(original) {
+					return function() {
+						const copy = this instanceof HTMLCanvasElement ? noisedCopy(this) : null;
+						return original.apply(copy || this, arguments);
+					};
+				});
+			});
+		}
+`
+
+const audioPatchScript = `
+		// Шум для audio fingerprinting. Шум детерминирован для fingerprint
+		// и применяется к каждому буферу один раз, поэтому повторные чтения
+		// совпадают. Тишина (нулевые отсчеты) остается тишиной.
+		const audioNoise = cfg.audio.noise || 0;
+		const audioSeed = utils.hash('audio|' + cfg.userAgent + '|' + audioNoise);
+		const noisedChannels = new WeakSet();
+
+		if (audioNoise > 0 && typeof AudioBuffer !== 'undefined') {
+			utils.replaceMethod(AudioBuffer.prototype, 'getChannelData', function(original) {
+				return function getChannelData(channel) {
+					const data = original.apply(this, arguments);
+					if (!noisedChannels.has(data)) {
+						noisedChannels.add(data);
+						const seed = audioSeed ^ Math.imul(channel + 1, 0x27d4eb2d);
+						for (let i = 0; i < data.length; i++) {
+							if (data[i] !== 0) {
+								data[i] *= 1 + (utils.noise(seed, i) - 0.5) * audioNoise * 1e-3;
+							}
+						}
+					}
+					return data;
+				};
+			});
+
+			// copyFromChannel копирует данные канала, поэтому сначала
+			// применяем к каналу тот же шум, что и getChannelData
+			utils.replaceMethod(AudioBuffer.prototype, 'copyFromChannel', function(original) {
+				return function copyFromChannel(destination, channel) {
+					if (channel >= 0 && channel < this.numberOfChannels) {
+						this.getChannelData(channel);
+					}
+					return original.apply(this, arguments);
+				};
+			});
+		}
+
+		if (audioNoise > 0 && typeof AnalyserNode !== 'undefined') {
+			utils.replaceMethod(AnalyserNode.prototype, 'getFloatFrequencyData', function(original) {
+				return function getFloatFrequencyData(array) {
+					const result = original.apply(this, arguments);
+					if (array && array.length) {
+						for (let i = 0; i < array.length; i++) {
+							if (isFinite(array[i])) {
+								array[i] += (utils.noise(audioSeed, i) - 0.5) * audioNoise;
+							}
+						}
+					}
+					return result;
+				};
+			});
+
+			utils.replaceMethod(AnalyserNode.prototype, 'getByteFrequencyData', function(original) {
+				return function getByteFrequencyData(array) {
+					const result = original.apply(this, arguments);
+					if (array && array.length) {
+						for (let i = 0; i < array.length; i++) {
+							if (array[i] > 0 && array[i] < 255 && utils.noise(audioSeed, i) < audioNoise) {
+								array[i] += utils.noise(audioSeed ^ 1, i) < 0.5 ? -1 : 1;
+							}
+						}
+					}
+					return result;
+				};
+			});
+		}
+
+		// Параметры реального AudioContext; у OfflineAudioContext они
+		// заданы конструктором и не меняются
+		if (typeof AudioContext !== 'undefined') {
+			if (cfg.audio.sampleRate > 0 && typeof BaseAudioContext !== 'undefined') {
+				utils.replaceGetter(BaseAudioContext.prototype, 'sampleRate', function(original) {
+					return this instanceof AudioContext ? cfg.audio.sampleRate : original.call(this);
+				});
+			}
+			if (cfg.audio.baseLatency > 0 && 'baseLatency' in AudioContext.prototype) {
+				utils.replaceGetter(AudioContext.prototype, 'baseLatency', function() {
+					return cfg.audio.baseLatency;
+				});
+			}
+			if (cfg.audio.maxChannelCount > 0 && typeof AudioDestinationNode !== 'undefined') {
+				utils.replaceGetter(AudioDestinationNode.prototype, 'maxChannelCount', function(original) {
+					return this.context instanceof AudioContext ? cfg.audio.maxChannelCount : original.call(this);
+				});
+			}
+		}
+`
+
+const fontsPatchScript = `
+		// Доступны только шрифты из cfg.fonts.
+		// Для известных системных шрифтов вне списка в document.fonts
+		// добавляется FontFace без символов (missingSource): такое семейство
+		// затеняет установленный шрифт, и при измерении (offsetWidth,
+		// measureText) используется запасной шрифт. Шрифты из списка,
+		// которых нет в системе, подменяются похожим локальным шрифтом.
+		const genericFamilies = [
+			'serif', 'sans-serif', 'monospace', 'cursive', 'fantasy', 'system-ui', 'math',
+			'emoji', 'fangsong', 'ui-serif', 'ui-sans-serif', 'ui-monospace', 'ui-rounded'
+		];
+		const knownFonts = [
+			// Windows
+			'Arial', 'Arial Black', 'Arial Narrow', 'Bahnschrift', 'Calibri', 'Calibri Light', 'Cambria',
+			'Cambria Math', 'Candara', 'Comic Sans MS', 'Consolas', 'Constantia', 'Corbel', 'Courier New',
+			'Ebrima', 'Franklin Gothic Medium', 'Gabriola', 'Gadugi', 'Georgia', 'HoloLens MDL2 Assets',
+			'Impact', 'Ink Free', 'Javanese Text', 'Leelawadee UI', 'Lucida Console', 'Lucida Sans Unicode',
+			'Malgun Gothic', 'Marlett', 'Microsoft Himalaya', 'Microsoft JhengHei', 'Microsoft New Tai Lue',
+			'Microsoft PhagsPa', 'Microsoft Sans Serif', 'Microsoft Tai Le', 'Microsoft YaHei',
+			'Microsoft Yi Baiti', 'MingLiU-ExtB', 'Mongolian Baiti', 'MS Gothic', 'MS PGothic', 'MS UI Gothic',
+			'MV Boli', 'Myanmar Text', 'Nirmala UI', 'Palatino Linotype', 'Segoe MDL2 Assets', 'Segoe Print',
+			'Segoe Script', 'Segoe UI', 'Segoe UI Emoji', 'Segoe UI Historic', 'Segoe UI Symbol', 'SimSun',
+			'Sitka', 'Sylfaen', 'Symbol', 'Tahoma', 'Times New Roman', 'Trebuchet MS', 'Verdana', 'Webdings',
+			'Wingdings', 'Yu Gothic',
+			// macOS и iOS
+			'American Typewriter', 'Andale Mono', 'Apple Chancery', 'Apple Color Emoji', 'Apple SD Gothic Neo',
+			'AppleGothic', 'Avenir', 'Avenir Next', 'Baskerville', 'Big Caslon', 'Brush Script MT', 'Chalkboard',
+			'Chalkboard SE', 'Charter', 'Cochin', 'Copperplate', 'Didot', 'Futura', 'Geneva', 'Gill Sans',
+			'Helvetica', 'Helvetica Neue', 'Herculanum', 'Hoefler Text', 'Lucida Grande', 'Luminari', 'Marker Felt',
+			'Menlo', 'Monaco', 'Noteworthy', 'Optima', 'Palatino', 'Papyrus', 'PingFang SC', 'Rockwell',
+			'San Francisco', 'SF Pro', 'SF Pro Display', 'SF Pro Text', 'SF Mono', 'Skia', 'Snell Roundhand',
+			'Superclarendon', 'Thonburi', 'Times', 'Trattatello', 'Zapfino',
+			// Linux и Android
+			'Bitstream Vera Sans', 'Cantarell', 'DejaVu Sans', 'DejaVu Sans Mono', 'DejaVu Serif', 'Droid Sans',
+			'Droid Sans Mono', 'Droid Serif', 'FreeMono', 'FreeSans', 'FreeSerif', 'Liberation Mono',
+			'Liberation Sans', 'Liberation Serif', 'Noto Color Emoji', 'Noto Mono', 'Noto Sans', 'Noto Serif',
+			'Open Sans', 'Oxygen', 'Roboto', 'Roboto Mono', 'Source Code Pro', 'Ubuntu', 'Ubuntu Mono'
+		];
+		// Локальные шрифты, которыми подменяются отсутствующие в системе шрифты из списка
+		const aliasSource = [
+			'Arial', 'Helvetica', 'Liberation Sans', 'DejaVu Sans', 'Noto Sans', 'Roboto'
+		].map(function(name) { return "local('" + name + "')"; }).join(', ');
+		// Источник, затеняющий шрифты вне списка: минимальный TrueType-шрифт,
+		// в котором есть только пустой .notdef. Он загружается успешно, но не
+		// содержит ни одного символа, поэтому текст рисуется следующим шрифтом
+		// из font-family. Незагружаемый источник не подходит: после ошибки
+		// загрузки Chrome использует установленный шрифт с тем же именем
+		const missingSource = 'url(data:font/ttf;base64,' +
+			'AAEAAAAKAIAAAwAgT1MvMlPBUOwAAACsAAAAYGNtYXAADAAmAAABDAAAACRnbHlmAAAAAAAAATAAAAAMaGVhZF8eQOUAAAE8' +
+			'AAAANmhoZWEDIf86AAABdAAAACRobXR4AAAAAAAAAZgAAAAEbG9jYQAAAAYAAAGcAAAABG1heHAAAgABAAABoAAAACBuYW1l' +
+			'BOQUkQAAAcAAAABicG9zdAADAAAAAAIkAAAAIAADAAABkAAFAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA' +
+			'AAAAAAAAAAAAAAAAAABOT05FAED//wAAAyD/OAAAAyAAyAAAAAEAAAAAAAAAAAAAAAAAAAAAAAEAAwABAAAADAAEABgAAAAC' +
+			'AAIAAAAA//8AAP//AAEAAAAAAAAAAAAAAAAAAAABAAAAAQAAL6tRtV8PPPUACwPoAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA' +
+			'AAAACAACAAAAAAAAAAEAAAMg/zgAAAAAAAAAAAAAAAEAAAAAAAAAAAAAAAAAAAABAAAAAAAAAAYAAQAAAAEAAAAAAAAAAAAB' +
+			'AAAAAAAAAAAAAAAAAAAAAAAAAAQANgADAAEECQABAAoAAAADAAEECQACAA4ACgADAAEECQAEAAoAGAADAAEECQAGAAoAIgBC' +
+			'AGwAYQBuAGsAUgBlAGcAdQBsAGEAcgBCAGwAYQBuAGsAQgBsAGEAbgBrAAAAAwAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA' +
+			'AAAAAA==' +
+			')';
+		// Источник для FontFace страницы: загрузка завершается ошибкой,
+		// как для шрифта, которого нет в системе
+		const unloadableSource = "local('.missing-font')";
+
+		const normalize = function(name) {
+			return String(name).trim().replace(/^['"]|['"]$/g, '').trim().toLowerCase();
+		};
+		const allowed = new Set((cfg.fonts || []).map(normalize));
+		const isGeneric = function(name) {
+			return genericFamilies.indexOf(normalize(name)) !== -1;
+		};
+		const isAllowedFamily = function(name) {
+			return isGeneric(name) || allowed.has(normalize(name));
+		};
+		// Имена local() - полные имена начертаний ("Arial Bold", "Arial-BoldMT")
+		const isAllowedFace = function(name) {
+			const n = normalize(name);
+			for (const family of allowed) {
+				if (n === family || n.indexOf(family + ' ') === 0 || n.indexOf(family.replace(/ /g, '') + '-') === 0) {
+					return true;
+				}
+			}
+			return false;
+		};
+		// parseFamilies извлекает семейства из CSS-шортхенда font
+		const parseFamilies = function(font) {
+			const match = /(?:^|\s)(?:[\d.]+(?:px|pt|pc|em|rem|ex|ch|vw|vh|vmin|vmax|cm|mm|in|q|%)|xx-small|x-small|small|medium|large|x-large|xx-large|xxx-large|smaller|larger)(?:\s*\/\s*\S+)?\s+(.+)$/i.exec(String(font).trim());
+			if (!match) {
+				return null;
+			}
+			return match[1].split(',').map(normalize).filter(Boolean);
+		};
+
+		// В Chromium нет глобального FontFaceSet, прототип берется у document.fonts
+		const fontSet = typeof document !== 'undefined' ? document.fonts : null;
+		if (fontSet && typeof FontFace !== 'undefined') {
+			const NativeFontFace = FontFace;
+			const ownFaces = new WeakSet();
+			const addFace = function(family, source) {
+				const face = new NativeFontFace(family, source);
+				ownFaces.add(face);
+				fontSet.add(face);
+				face.load().catch(function() {});
+			};
+
+			knownFonts.forEach(function(family) {
+				if (!isAllowedFamily(family)) {
+					addFace(family, missingSource);
+				}
+			});
+			(cfg.fonts || []).forEach(function(family) {
+				if (!isGeneric(family)) {
+					addFace(family, "local('" + String(family).replace(/['\\]/g, '') + "'), " + aliasSource);
+				}
+			});
+
+			// Служебные FontFace не видны странице
+			const proto = Object.getPrototypeOf(fontSet);
+			const nativeValues = proto.values;
+			const visibleFaces = function(set) {
+				const result = [];
+				for (const face of nativeValues.call(set)) {
+					if (!ownFaces.has(face)) {
+						result.push(face);
+					}
+				}
+				return result;
+			};
+
+			utils.replaceGetter(proto, 'size', function() {
+				return visibleFaces(this).length;
+			});
+			utils.replaceMethod(proto, 'values', function() {
+				return function values() {
+					return visibleFaces(this).values();
+				};
+			});
+			utils.replaceMethod(proto, 'keys', function() {
+				return function keys() {
+					return visibleFaces(this).values();
+				};
+			});
+			utils.replaceMethod(proto, 'entries', function() {
+				return function entries() {
+					return visibleFaces(this).map(function(face) { return [face, face]; }).values();
+				};
+			});
+			const iteratorDesc = Object.getOwnPropertyDescriptor(proto, Symbol.iterator);
+			if (iteratorDesc) {
+				Object.defineProperty(proto, Symbol.iterator, Object.assign({}, iteratorDesc, { value: proto.values }));
+			}
+			utils.replaceMethod(proto, 'forEach', function() {
+				return function forEach(callback, thisArg) {
+					const set = this;
+					visibleFaces(set).forEach(function(face) {
+						callback.call(thisArg, face, face, set);
+					});
+				};
+			});
+			utils.replaceMethod(proto, 'has', function(original) {
+				return function has(face) {
+					return !ownFaces.has(face) && original.apply(this, arguments);
+				};
+			});
+			utils.replaceMethod(proto, 'load', function(original) {
+				return function load() {
+					return original.apply(this, arguments).then(function(faces) {
+						return faces.filter(function(face) { return !ownFaces.has(face); });
+					});
+				};
+			});
+
+			// document.fonts.check: шрифт доступен, только если все семейства есть в списке
+			utils.replaceMethod(proto, 'check', function(original) {
+				return function check(font) {
+					const result = original.apply(this, arguments);
+					const families = parseFamilies(font);
+					if (families && !families.every(isAllowedFamily)) {
+						return false;
+					}
+					return result;
+				};
+			});
+
+			// FontFace с источником local(): шрифты вне списка не загружаются,
+			// шрифты из списка загружаются даже при отсутствии в системе
+			utils.replaceConstructor(self, 'FontFace', function(original) {
+				return function(args, newTarget) {
+					const wrapped = Array.prototype.slice.call(args);
+					if (typeof wrapped[1] === 'string') {
+						wrapped[1] = wrapped[1].replace(/local\(\s*(['"]?)([^'")]+)\1\s*\)/gi, function(match, quote, name) {
+							return isAllowedFace(name) ? match + ', ' + aliasSource : unloadableSource;
+						});
+					}
+					return Reflect.construct(original, wrapped, newTarget);
+				};
+			});
+		}
+`
+
+const webrtcRewriteScript = `
+		// Маскировка адресов ICE-кандидатов и SDP для режимов mask и
+		// proxy-only. Некорректный адрес из cfg.webrtc считается незаданным
+		// (о нем сообщает Fingerprint.Validate)
+		const rtc = cfg.webrtc;
+		const validIp = function(ip) {
+			if (typeof ip !== 'string') {
+				return false;
+			}
+			const v4 = ip.split('.');
+			if (v4.length === 4) {
+				return v4.every(function(part) {
+					return /^\d{1,3}$/.test(part) && Number(part) <= 255;
+				});
+			}
+			// IPv6: группы до 4 шестнадцатеричных знаков, не более одного "::"
+			const groups = ip.split('::');
+			if (groups.length > 2 || !/^[0-9a-fA-F:]+$/.test(ip)) {
+				return false;
+			}
+			const count = groups.reduce(function(n, part) {
+				if (part === '') {
+					return n;
+				}
+				const hextets = part.split(':');
+				return hextets.every(function(h) { return /^[0-9a-fA-F]{1,4}$/.test(h); }) ? n + hextets.length : Infinity;
+			}, 0);
+			return groups.length === 2 ? count < 8 : count === 8;
+		};
+		const rtcLocalIp = validIp(rtc.localIp) ? rtc.localIp : '';
+		const rtcPublicIp = validIp(rtc.publicIp) ? rtc.publicIp : '';
+
+		// mdnsName - имя "<uuid>.local", которым Chrome скрывает адрес
+		// host-кандидата; постоянно для адреса и fingerprint
+		const mdnsName = function(address) {
+			let hex = '';
+			for (let i = 0; i < 4; i++) {
+				hex += ('0000000' + utils.hash('webrtc|' + cfg.userAgent + '|' + address + '|' + i).toString(16)).slice(-8);
+			}
+			hex = hex.slice(0, 12) + '4' + hex.slice(13, 16) +
+				'89ab'[parseInt(hex[16], 16) & 3] + hex.slice(17);
+			return hex.slice(0, 8) + '-' + hex.slice(8, 12) + '-' + hex.slice(12, 16) + '-' +
+				hex.slice(16, 20) + '-' + hex.slice(20) + '.local';
+		};
+
+		// rewriteCandidate заменяет адрес по типу кандидата и скрывает
+		// raddr/rport с адресом базового кандидата
+		const rewriteCandidate = function(candidate) {
+			const fields = String(candidate).split(' ');
+			if (fields.length < 8 || fields[6] !== 'typ') {
+				return candidate;
+			}
+			if (fields[7] === 'host') {
+				if (rtcLocalIp) {
+					fields[4] = rtcLocalIp;
+				} else if (!/\.local$/.test(fields[4])) {
+					fields[4] = mdnsName(fields[4]);
+				}
+			} else if ((fields[7] === 'srflx' || fields[7] === 'prflx') && rtcPublicIp) {
+				fields[4] = rtcPublicIp;
+			}
+			for (let i = 8; i + 1 < fields.length; i += 2) {
+				if (fields[i] === 'raddr') {
+					fields[i + 1] = '0.0.0.0';
+				} else if (fields[i] === 'rport') {
+					fields[i + 1] = '0';
+				}
+			}
+			return fields.join(' ');
+		};
+
+		// rewriteConnection заменяет адрес строк c= и a=rtcp:
+		const rewriteConnection = function(line) {
+			const fields = line.split(' ');
+			const n = fields.length;
+			if (n < 3 || (fields[n - 2] !== 'IP4' && fields[n - 2] !== 'IP6') ||
+				fields[n - 1] === '0.0.0.0' || fields[n - 1] === '::') {
+				return line;
+			}
+			if (rtcPublicIp) {
+				fields[n - 2] = rtcPublicIp.indexOf(':') === -1 ? 'IP4' : 'IP6';
+				fields[n - 1] = rtcPublicIp;
+			} else {
+				fields[n - 1] = fields[n - 2] === 'IP4' ? '0.0.0.0' : '::';
+			}
+			return fields.join(' ');
+		};
+
+		const rewriteSDP = function(sdp) {
+			return String(sdp).split('\n').map(function(line) {
+				const cr = line.slice(-1) === '\r';
+				if (cr) {
+					line = line.slice(0, -1);
+				}
+				if (line.indexOf('a=candidate:') === 0) {
+					line = 'a=' + rewriteCandidate(line.slice(2));
+				} else if (line.indexOf('c=') === 0 || line.indexOf('a=rtcp:') === 0) {
+					line = rewriteConnection(line);
+				}
+				return cr ? line + '\r' : line;
+			}).join('\n');
+		};
+`
+
+const webrtcPatchScript = `
+		// WebRTC по режиму cfg.webrtc.mode. Флаг запуска из AllocatorOptions
+		// задает ту же политику в сетевом стеке Chrome
+		const rtcMode = rtc.mode || (rtc.disable ? 'disable' : '');
+
+		if (rtcMode === 'disable') {
+			// Интерфейсы удаляются, как в браузере, собранном без WebRTC
+			['RTCPeerConnection', 'webkitRTCPeerConnection', 'RTCSessionDescription',
+				'RTCIceCandidate', 'RTCDataChannel', 'RTCPeerConnectionIceEvent'].forEach(function(name) {
+				delete window[name];
+			});
+			// Без cfg.mediaDevices список устройств пуст, иначе его задает патч media
+			if (typeof MediaDevices !== 'undefined' && !cfg.mediaDevices) {
+				utils.replaceMethod(MediaDevices.prototype, 'enumerateDevices', function(original) {
+					return function enumerateDevices() {
+						return original.apply(this, arguments).then(function() { return []; });
+					};
+				});
+			}
+		} else if ((rtcMode === 'mask' || rtcMode === 'proxy-only') && typeof RTCPeerConnection !== 'undefined') {
+			// Поля RTCIceCandidate выводятся из замаскированной строки
+			if (typeof RTCIceCandidate !== 'undefined') {
+				const proto = RTCIceCandidate.prototype;
+				const parsed = function(candidate) {
+					const fields = rewriteCandidate(candidate).split(' ');
+					const result = { address: fields[4] };
+					for (let i = 8; i + 1 < fields.length; i += 2) {
+						result[fields[i]] = fields[i + 1];
+					}
+					return result;
+				};
+				utils.replaceGetter(proto, 'candidate', function(original) {
+					return rewriteCandidate(original.call(this));
+				});
+				utils.replaceGetter(proto, 'address', function(original) {
+					const value = original.call(this);
+					return value === null ? null : parsed(this.candidate).address;
+				});
+				utils.replaceGetter(proto, 'relatedAddress', function(original) {
+					const value = original.call(this);
+					return value === null ? null : parsed(this.candidate).raddr;
+				});
+				utils.replaceGetter(proto, 'relatedPort', function(original) {
+					const value = original.call(this);
+					return value === null ? null : Number(parsed(this.candidate).rport);
+				});
+				utils.replaceMethod(proto, 'toJSON', function(original) {
+					return function toJSON() {
+						const result = original.apply(this, arguments);
+						if (result && typeof result.candidate === 'string') {
+							result.candidate = rewriteCandidate(result.candidate);
+						}
+						return result;
+					};
+				});
+			}
+
+			// SDP локального описания
+			if (typeof RTCSessionDescription !== 'undefined') {
+				['localDescription', 'currentLocalDescription', 'pendingLocalDescription'].forEach(function(prop) {
+					utils.replaceGetter(RTCPeerConnection.prototype, prop, function(original) {
+						const desc = original.call(this);
+						return desc && new RTCSessionDescription({ type: desc.type, sdp: rewriteSDP(desc.sdp) });
+					});
+				});
+			}
+
+			// Адреса local-candidate в getStats
+			utils.replaceMethod(RTCPeerConnection.prototype, 'getStats', function(original) {
+				return function getStats() {
+					return original.apply(this, arguments).then(function(report) {
+						report.forEach(function(stat) {
+							if (stat.type !== 'local-candidate') {
+								return;
+							}
+							const fields = rewriteCandidate('candidate:0 1 udp 0 ' + stat.address + ' ' +
+								stat.port + ' typ ' + stat.candidateType +
+								(stat.relatedAddress ? ' raddr ' + stat.relatedAddress + ' rport ' + stat.relatedPort : '')).split(' ');
+							stat.address = fields[4];
+							if ('ip' in stat) {
+								stat.ip = fields[4];
+							}
+							if (stat.relatedAddress) {
+								stat.relatedAddress = fields[9];
+								stat.relatedPort = 0;
+							}
+						});
+						return report;
+					});
+				};
+			});
+
+			// proxy-only: только relay-кандидаты, как с iceTransportPolicy 'relay'
+			if (rtcMode === 'proxy-only') {
+				const relayOnly = function(config) {
+					return Object.assign({}, config, { iceTransportPolicy: 'relay' });
+				};
+				utils.replaceMethod(RTCPeerConnection.prototype, 'setConfiguration', function(original) {
+					return function setConfiguration(config) {
+						return original.call(this, relayOnly(config));
+					};
+				});
+				// webkitRTCPeerConnection - тот же объект интерфейса
+				const legacy = window.webkitRTCPeerConnection === window.RTCPeerConnection;
+				utils.replaceConstructor(window, 'RTCPeerConnection', function(original) {
+					return function(args, newTarget) {
+						const list = Array.prototype.slice.call(args);
+						list[0] = relayOnly(list[0]);
+						return Reflect.construct(original, list, newTarget);
+					};
+				});
+				if (legacy) {
+					window.webkitRTCPeerConnection = window.RTCPeerConnection;
+				}
+			}
+		}
+`
+
+const mediaPatchScript = `
+		// navigator.mediaDevices и mediaCapabilities из cfg.mediaDevices.
+		// Списки устройств создаются от нативных прототипов MediaDeviceInfo
+		// и InputDeviceInfo; для настоящих объектов вызываются оригиналы.
+		// Камера и микрофон по-прежнему открываются настоящие, а треки
+		// сообщают метку и идентификаторы устройства из fingerprint.
+		const media = cfg.mediaDevices;
+		if (typeof MediaDevices !== 'undefined' && typeof MediaDeviceInfo !== 'undefined') {
+			const mediaSeed = String(media.seed || cfg.userAgent);
+			const mediaOrigin = typeof location !== 'undefined' ? location.origin : '';
+			// deviceId и groupId Chrome - 64 шестнадцатеричных знака,
+			// разные для разных сайтов
+			const mediaId = function(key) {
+				let hex = '';
+				for (let i = 0; i < 8; i++) {
+					hex += ('0000000' + utils.hash(key + '|' + i).toString(16)).slice(-8);
+				}
+				return hex;
+			};
+			const devices = (media.devices || []).map(function(d, i) {
+				return {
+					kind: d.kind,
+					label: d.label,
+					facingMode: d.facingMode || '',
+					deviceId: d.deviceId || mediaId('media|' + mediaSeed + '|' + mediaOrigin + '|' + d.kind + '|' + i),
+					groupId: mediaId('media-group|' + mediaSeed + '|' + mediaOrigin + '|' + d.group)
+				};
+			});
+			const hidden = function(d) {
+				return { kind: d.kind, label: '', deviceId: '', groupId: '', facingMode: '' };
+			};
+
+			const deviceData = new WeakMap();
+			const defineGetter = function(proto, prop) {
+				const desc = Object.getOwnPropertyDescriptor(proto, prop);
+				const original = desc && desc.get;
+				const fake = Object.getOwnPropertyDescriptor({
+					get [prop]() {
+						if (deviceData.has(this)) {
+							return deviceData.get(this)[prop];
+						}
+						if (original) {
+							return original.call(this);
+						}
+						throw new TypeError('Illegal invocation');
+					}
+				}, prop).get;
+				utils.mask(fake, original, 'get ' + prop, 0);
+				Object.defineProperty(proto, prop, {
+					get: fake,
+					set: undefined,
+					enumerable: desc ? desc.enumerable : true,
+					configurable: true
+				});
+			};
+			const defineMethod = function(proto, name, impl) {
+				utils.replaceMethod(proto, name, function(original) {
+					return function() {
+						return deviceData.has(this) ? impl.call(this, deviceData.get(this)) : original.apply(this, arguments);
+					};
+				});
+			};
+
+			['deviceId', 'kind', 'label', 'groupId'].forEach(function(prop) {
+				defineGetter(MediaDeviceInfo.prototype, prop);
+			});
+			defineMethod(MediaDeviceInfo.prototype, 'toJSON', function(d) {
+				return { deviceId: d.deviceId, kind: d.kind, label: d.label, groupId: d.groupId };
+			});
+			// Возможности устройств, как у встроенных камер и микрофонов
+			const capabilities = function(d) {
+				if (!d.deviceId) {
+					return {};
+				}
+				if (d.kind === 'videoinput') {
+					return {
+						aspectRatio: { max: 1280, min: 0.001 },
+						deviceId: d.deviceId,
+						facingMode: d.facingMode ? [d.facingMode] : [],
+						frameRate: { max: 30, min: 1 },
+						groupId: d.groupId,
+						height: { max: 720, min: 1 },
+						resizeMode: ['none', 'crop-and-scale'],
+						width: { max: 1280, min: 1 }
+					};
+				}
+				return {
+					autoGainControl: [true, false],
+					channelCount: { max: 2, min: 1 },
+					deviceId: d.deviceId,
+					echoCancellation: [true, false],
+					groupId: d.groupId,
+					latency: { max: 0.01, min: 0.01 },
+					noiseSuppression: [true, false],
+					sampleRate: { max: 48000, min: 48000 },
+					sampleSize: { max: 16, min: 16 }
+				};
+			};
+			if (typeof InputDeviceInfo !== 'undefined') {
+				defineMethod(InputDeviceInfo.prototype, 'getCapabilities', capabilities);
+			}
+
+			const makeInfo = function(d) {
+				const proto = d.kind !== 'audiooutput' && typeof InputDeviceInfo !== 'undefined' ?
+					InputDeviceInfo.prototype : MediaDeviceInfo.prototype;
+				const info = Object.create(proto);
+				deviceData.set(info, d);
+				return info;
+			};
+
+			// Устройства видны после разрешения: камера открывает videoinput,
+			// микрофон - audioinput и audiooutput
+			const granted = { audio: false, video: false };
+			const permission = function(name) {
+				try {
+					return navigator.permissions.query({ name: name }).then(function(status) {
+						return status.state === 'granted';
+					}, function() {
+						return false;
+					});
+				} catch (e) {
+					return Promise.resolve(false);
+				}
+			};
+
+			utils.replaceMethod(MediaDevices.prototype, 'enumerateDevices', function(original) {
+				return function enumerateDevices() {
+					return Promise.all([
+						original.apply(this, arguments),
+						granted.video || permission('camera'),
+						granted.audio || permission('microphone')
+					]).then(function(values) {
+						const shown = {};
+						const list = [];
+						devices.forEach(function(d) {
+							if (d.kind === 'videoinput' ? values[1] : values[2]) {
+								list.push(makeInfo(d));
+							} else if (!shown[d.kind]) {
+								// Без разрешения - одна пустая запись каждого вида
+								shown[d.kind] = true;
+								list.push(makeInfo(hidden(d)));
+							}
+						});
+						return list;
+					});
+				};
+			});
+
+			// getUserMedia: идентификаторы из fingerprint убираются из
+			// ограничений, а треки связываются с выбранным устройством
+			const trackDevice = new WeakMap();
+			const constraintValues = function(value) {
+				if (value && typeof value === 'object' && !Array.isArray(value)) {
+					value = value.exact !== undefined ? value.exact : value.ideal;
+				}
+				return [].concat(value === undefined ? [] : value).map(String);
+			};
+			const pickDevice = function(kind, constraint) {
+				const candidates = devices.filter(function(d) {
+					return d.kind === kind && d.deviceId !== 'default' && d.deviceId !== 'communications';
+				});
+				if (!constraint || typeof constraint !== 'object') {
+					return candidates[0];
+				}
+				const ids = constraintValues(constraint.deviceId);
+				const facing = constraintValues(constraint.facingMode);
+				return candidates.filter(function(d) {
+					return ids.indexOf(d.deviceId) !== -1;
+				})[0] || candidates.filter(function(d) {
+					return facing.indexOf(d.facingMode) !== -1;
+				})[0] || candidates[0];
+			};
+			const isFakeId = function(id) {
+				return devices.some(function(d) {
+					return d.deviceId === id || d.groupId === id;
+				});
+			};
+
+			utils.replaceMethod(MediaDevices.prototype, 'getUserMedia', function(original) {
+				return function getUserMedia(constraints) {
+					const args = Array.prototype.slice.call(arguments);
+					const chosen = {};
+					if (constraints && typeof constraints === 'object') {
+						args[0] = Object.assign({}, constraints);
+						[['audio', 'audioinput'], ['video', 'videoinput']].forEach(function(pair) {
+							const c = constraints[pair[0]];
+							if (!c) {
+								return;
+							}
+							chosen[pair[0]] = pickDevice(pair[1], c);
+							if (typeof c === 'object') {
+								const copy = Object.assign({}, c);
+								['deviceId', 'groupId'].forEach(function(prop) {
+									if (copy[prop] !== undefined && constraintValues(copy[prop]).every(isFakeId)) {
+										delete copy[prop];
+									}
+								});
+								args[0][pair[0]] = Object.keys(copy).length ? copy : true;
+							}
+						});
+					}
+					return original.apply(this, args).then(function(stream) {
+						stream.getTracks().forEach(function(track) {
+							granted[track.kind] = true;
+							if (chosen[track.kind]) {
+								trackDevice.set(track, chosen[track.kind]);
+							}
+						});
+						return stream;
+					});
+				};
+			});
+
+			if (typeof MediaStreamTrack !== 'undefined') {
+				utils.replaceGetter(MediaStreamTrack.prototype, 'label', function(original) {
+					const d = trackDevice.get(this);
+					return d ? d.label : original.call(this);
+				});
+				['getSettings', 'getCapabilities'].forEach(function(name) {
+					utils.replaceMethod(MediaStreamTrack.prototype, name, function(original) {
+						return function() {
+							const result = original.apply(this, arguments);
+							const d = trackDevice.get(this);
+							if (d && result) {
+								result.deviceId = d.deviceId;
+								result.groupId = d.groupId;
+							}
+							return result;
+						};
+					});
+				});
+				utils.replaceMethod(MediaStreamTrack.prototype, 'clone', function(original) {
+					return function clone() {
+						const track = original.apply(this, arguments);
+						if (trackDevice.has(this)) {
+							trackDevice.set(track, trackDevice.get(this));
+						}
+						return track;
+					};
+				});
+			}
+		}
+
+		// decodingInfo: ответ по кодеку из contentType ("avc1" для
+		// avc1.42E01E) или по MIME-типу; неизвестные кодеки - как в браузере
+		if (media.codecs && typeof MediaCapabilities !== 'undefined') {
+			const codecInfo = function(contentType) {
+				const type = String(contentType || '');
+				const keys = [];
+				const match = /codecs\s*=\s*"?([^",;]+)/i.exec(type);
+				if (match) {
+					const codec = match[1].trim().toLowerCase();
+					keys.push(codec, codec.split('.')[0]);
+				}
+				keys.push(type.split(';')[0].trim().toLowerCase());
+				for (let i = 0; i < keys.length; i++) {
+					if (Object.prototype.hasOwnProperty.call(media.codecs, keys[i])) {
+						return media.codecs[keys[i]];
+					}
+				}
+				return null;
+			};
+			utils.replaceMethod(MediaCapabilities.prototype, 'decodingInfo', function(original) {
+				return function decodingInfo(config) {
+					return original.apply(this, arguments).then(function(info) {
+						const answers = [config.video, config.audio].filter(Boolean).map(function(c) {
+							return codecInfo(c.contentType);
+						});
+						if (!answers.length || answers.indexOf(null) !== -1) {
+							return info;
+						}
+						const all = function(prop) {
+							return answers.every(function(a) { return a.supported && a[prop]; });
+						};
+						info.supported = all('supported');
+						info.smooth = all('smooth');
+						info.powerEfficient = all('powerEfficient');
+						return info;
+					});
+				};
+			});
+		}
+`
+
+const batteryPatchScript = `
+		// Battery API: navigator.getBattery возвращает один BatteryManager
+		// на страницу. Это настоящий EventTarget с прототипом BatteryManager;
+		// уровень меняется по симулированным часам шагами 0.01, как в Chrome,
+		// с событиями levelchange, chargingtimechange и dischargingtimechange.
+		// Состояние считается так же, как Battery.At в Go.
+		if (typeof BatteryManager !== 'undefined') {
+			const battery = cfg.battery;
+			const speed = battery.speed > 0 ? battery.speed : 1;
+			const start = battery.startTime || Date.now();
+			const state = function() {
+				const elapsed = Math.max(Date.now() - start, 0) * speed / 1000;
+				let level = battery.level;
+				let chargingTime = Infinity;
+				let dischargingTime = Infinity;
+				let rate = 0;
+				if (battery.charging && level >= 1) {
+					level = 1;
+					chargingTime = 0;
+				} else if (battery.charging && battery.chargingTime > 0) {
+					rate = (1 - battery.level) / battery.chargingTime;
+					level = Math.min(battery.level + rate * elapsed, 1);
+					chargingTime = Math.round((1 - level) / rate);
+				} else if (!battery.charging && battery.dischargingTime > 0) {
+					rate = battery.level / battery.dischargingTime;
+					level = Math.max(battery.level - rate * elapsed, 0.01);
+					dischargingTime = Math.round(level / rate);
+				}
+				return {
+					charging: battery.charging,
+					chargingTime: chargingTime,
+					dischargingTime: dischargingTime,
+					level: Math.round(level * 100) / 100,
+					// время в мс до следующего шага уровня; 0 - уровень не меняется
+					step: rate && chargingTime !== 0 && level > 0.01 ? 0.0025 / rate / speed * 1000 : 0
+				};
+			};
+
+			const managers = new WeakMap();
+			const proto = BatteryManager.prototype;
+			// Нативные геттеры вызываются только для настоящих объектов:
+			// для подмененного они бросают "Illegal invocation"
+			['charging', 'chargingTime', 'dischargingTime', 'level'].forEach(function(prop) {
+				const desc = Object.getOwnPropertyDescriptor(proto, prop);
+				const original = desc && desc.get;
+				const fake = Object.getOwnPropertyDescriptor({
+					get [prop]() {
+						if (managers.has(this)) {
+							return managers.get(this).current[prop];
+						}
+						if (original) {
+							return original.call(this);
+						}
+						throw new TypeError('Illegal invocation');
+					}
+				}, prop).get;
+				utils.mask(fake, original, 'get ' + prop, 0);
+				Object.defineProperty(proto, prop, {
+					get: fake,
+					set: undefined,
+					enumerable: desc ? desc.enumerable : true,
+					configurable: true
+				});
+			});
+
+			// Обработчики onlevelchange и другие: нативные аксессоры проверяют,
+			// что this - настоящий BatteryManager, поэтому заменяются
+			const events = ['chargingchange', 'chargingtimechange', 'dischargingtimechange', 'levelchange'];
+			events.forEach(function(type) {
+				const prop = 'on' + type;
+				const desc = Object.getOwnPropertyDescriptor(proto, prop);
+				if (!desc || !desc.get) {
+					return;
+				}
+				const accessors = Object.getOwnPropertyDescriptor({
+					get [prop]() {
+						return managers.has(this) ? managers.get(this).handlers[type] : desc.get.call(this);
+					},
+					set [prop](value) {
+						if (!managers.has(this)) {
+							return desc.set.call(this, value);
+						}
+						const data = managers.get(this);
+						data.handlers[type] = typeof value === 'function' || (value && typeof value === 'object') ? value : null;
+						if (!data.listening[type]) {
+							// Обработчик вызывается в порядке первого присваивания
+							data.listening[type] = true;
+							EventTarget.prototype.addEventListener.call(this, type, function(event) {
+								const handler = data.handlers[type];
+								if (typeof handler === 'function') {
+									return handler.call(this, event);
+								}
+							});
+						}
+					}
+				}, prop);
+				utils.mask(accessors.get, desc.get, 'get ' + prop, 0);
+				utils.mask(accessors.set, desc.set, 'set ' + prop, 1);
+				Object.defineProperty(proto, prop, {
+					get: accessors.get,
+					set: accessors.set,
+					enumerable: desc.enumerable,
+					configurable: desc.configurable
+				});
+			});
+
+			let promise = null;
+			utils.replaceMethod(Navigator.prototype, 'getBattery', function() {
+				return function getBattery() {
+					if (promise) {
+						return promise;
+					}
+					const manager = new EventTarget();
+					Object.setPrototypeOf(manager, proto);
+					const data = { current: state(), handlers: {}, listening: {} };
+					managers.set(manager, data);
+
+					// tick обновляет состояние и отправляет события об изменениях
+					const tick = function() {
+						const next = state();
+						const changed = [];
+						if (next.level !== data.current.level) {
+							changed.push('levelchange');
+						}
+						if (next.chargingTime !== data.current.chargingTime) {
+							changed.push('chargingtimechange');
+						}
+						if (next.dischargingTime !== data.current.dischargingTime) {
+							changed.push('dischargingtimechange');
+						}
+						if (changed.indexOf('levelchange') !== -1 || !next.step) {
+							data.current = next;
+							changed.forEach(function(type) {
+								manager.dispatchEvent(new Event(type));
+							});
+						}
+						if (next.step) {
+							setTimeout(tick, Math.max(next.step, 1000));
+						}
+					};
+					if (data.current.step) {
+						setTimeout(tick, Math.max(data.current.step, 1000));
+					}
+					promise = Promise.resolve(manager);
+					return promise;
+				};
+			});
+		}
+`
+
+const connectionPatchScript = `
+		// navigator.connection из cfg.connection. Как в Chrome, rtt и downlink
+		// умножаются на множитель origin из [0.9, 1.1] и округляются до 50 мс
+		// и 25 кбит/с с ограничением 3000 мс и 10 Мбит/с. type и downlinkMax
+		// есть только у Chrome на Android.
+		const connection = cfg.connection;
+		const netScope = typeof window !== 'undefined' ? window : self;
+		const netNavigator = typeof Navigator !== 'undefined' ? Navigator.prototype :
+			(typeof WorkerNavigator !== 'undefined' ? WorkerNavigator.prototype : null);
+
+		if (connection.disable) {
+			if (netNavigator) {
+				delete netNavigator.connection;
+			}
+			delete netScope.NetworkInformation;
+		} else if (typeof NetworkInformation !== 'undefined') {
+			// Значения вычисляются при первом обращении: в воркере, который
+			// подключен на паузе, location блокирует поток до загрузки скрипта
+			let netValues = null;
+			const netValue = function(prop) {
+				if (!netValues) {
+					const netOrigin = typeof location !== 'undefined' ? location.origin : '';
+					const multiplier = 0.9 + (utils.hash('connection|' + netOrigin) % 2001) / 10000;
+					netValues = {
+						effectiveType: connection.effectiveType,
+						rtt: Math.min(Math.round(connection.rtt * multiplier / 50) * 50, 3000),
+						downlink: Math.min(Math.round(connection.downlink * multiplier * 40) / 40, 10),
+						saveData: !!connection.saveData,
+						type: connection.type,
+						downlinkMax: connection.downlinkMax > 0 ? connection.downlinkMax : Infinity
+					};
+				}
+				return netValues[prop];
+			};
+			const netProto = NetworkInformation.prototype;
+			const netProps = ['effectiveType', 'rtt', 'downlink', 'saveData'];
+			if (connection.type) {
+				netProps.push('type', 'downlinkMax');
+			} else {
+				delete netProto.type;
+				delete netProto.downlinkMax;
+			}
+			netProps.forEach(function(prop) {
+				if (connection[prop] !== '') {
+					utils.replaceGetter(netProto, prop, function() {
+						return netValue(prop);
+					});
+				}
+			});
+		}
+`
+
+const localePatchScript = `
+		// Локаль по умолчанию. SetLocaleOverride (Emulation.setLocaleOverride)
+		// меняет локаль ICU во всем движке, и тогда патч не нужен. Иначе Intl,
+		// toLocale*String и localeCompare без явной локали используют
+		// cfg.language, как navigator.language и Accept-Language
+		let locale = null;
+		try {
+			locale = Intl.getCanonicalLocales(cfg.language)[0];
+		} catch (e) {}
+		if (locale && new Intl.NumberFormat().resolvedOptions().locale !== locale) {
+			const withLocale = function(args) {
+				const result = Array.prototype.slice.call(args);
+				if (result[0] === undefined) {
+					result[0] = locale;
+				}
+				return result;
+			};
+
+			// Collator, DateTimeFormat и NumberFormat можно вызывать без new
+			const callable = { Collator: true, DateTimeFormat: true, NumberFormat: true };
+			['Collator', 'DateTimeFormat', 'NumberFormat', 'PluralRules', 'RelativeTimeFormat',
+				'ListFormat', 'DisplayNames', 'Segmenter'].forEach(function(name) {
+				utils.replaceConstructor(Intl, name, function(original) {
+					return function(args, newTarget) {
+						return Reflect.construct(original, withLocale(args), newTarget);
+					};
+				}, callable[name] ? function(original) {
+					return function(args, thisArg) {
+						return original.apply(thisArg, withLocale(args));
+					};
+				} : null);
+			});
+
+			// Методы, принимающие локаль первым аргументом
+			[
+				[Date.prototype, ['toLocaleString', 'toLocaleDateString', 'toLocaleTimeString']],
+				[Number.prototype, ['toLocaleString']],
+				[typeof BigInt !== 'undefined' ? BigInt.prototype : null, ['toLocaleString']],
+				[String.prototype, ['toLocaleLowerCase', 'toLocaleUpperCase']]
+			].forEach(function(entry) {
+				entry[1].forEach(function(name) {
+					utils.replaceMethod(entry[0], name, function(original) {
+						return function() {
+							return original.apply(this, withLocale(arguments));
+						};
+					});
+				});
+			});
+
+			// localeCompare(that, locales, options)
+			utils.replaceMethod(String.prototype, 'localeCompare', function(original) {
+				return function(that, locales, options) {
+					return original.call(this, that, locales === undefined ? locale : locales, options);
+				};
+			});
+		}
+`
+
+const timezonePatchScript = `
+		// Временная зона. SetTimezoneOverride (Emulation.setTimezoneOverride)
+		// меняет зону во всем движке, и тогда Date и Intl уже согласованы.
+		// Иначе смещение каждой даты берется из таблицы переходов IANA
+		// cfg.timezoneTransitions, а Intl.DateTimeFormat и toLocale*String
+		// по умолчанию форматируют в зоне cfg.timezone.id
+		const NativeDate = Date;
+		const NativeDateTimeFormat = Intl.DateTimeFormat;
+		const zoneId = cfg.timezone.id;
+		if (new NativeDateTimeFormat().resolvedOptions().timeZone !== zoneId) {
+			const transitions = cfg.timezoneTransitions || [];
+			const nativeGetTime = NativeDate.prototype.getTime;
+			const nativeSetTime = NativeDate.prototype.setTime;
+			const nativeOffset = NativeDate.prototype.getTimezoneOffset;
+			const nativeParse = NativeDate.parse;
+			const nativeUTC = NativeDate.UTC;
+			const day = 86400000;
+
+			let zoneSupported = true;
+			try {
+				new NativeDateTimeFormat('en-US', { timeZone: zoneId });
+			} catch (e) {
+				zoneSupported = false;
+			}
+
+			// offsetAt возвращает смещение (как getTimezoneOffset) в момент ms
+			const offsetAt = function(ms) {
+				if (!transitions.length) {
+					return cfg.timezone.offset;
+				}
+				let lo = 0;
+				let hi = transitions.length - 1;
+				while (lo < hi) {
+					const mid = (lo + hi + 1) >> 1;
+					if (transitions[mid][0] <= ms) {
+						lo = mid;
+					} else {
+						hi = mid - 1;
+					}
+				}
+				return transitions[lo][1];
+			};
+
+			// toLocal переводит момент в локальное время зоны,
+			// записанное как время UTC
+			const toLocal = function(ms) {
+				return ms - offsetAt(ms) * 60000;
+			};
+
+			// fromLocal переводит локальное время в момент. Как и в V8,
+			// неоднозначное время при переводе часов назад дает более ранний
+			// момент, а несуществующее время сдвигается вперед на разрыв
+			const fromLocal = function(local) {
+				if (isNaN(local)) {
+					return NaN;
+				}
+				const before = offsetAt(local - day);
+				const after = offsetAt(local + day);
+				let result = NaN;
+				[before, after].forEach(function(offset) {
+					const ms = local + offset * 60000;
+					if (offsetAt(ms) === offset && !(ms >= result)) {
+						result = ms;
+					}
+				});
+				return isNaN(result) ? local + before * 60000 : result;
+			};
+
+			// localDate возвращает дату, UTC-поля которой равны локальным
+			const localDate = function(date) {
+				const ms = nativeGetTime.call(date);
+				return new NativeDate(isNaN(ms) ? NaN : toLocal(ms));
+			};
+
+			utils.replaceMethod(NativeDate.prototype, 'getTimezoneOffset', function() {
+				return function getTimezoneOffset() {
+					const ms = nativeGetTime.call(this);
+					return isNaN(ms) ? NaN : offsetAt(ms);
+				};
+			});
+
+			// Локальные геттеры и сеттеры работают через UTC-версии над
+			// локальным временем
+			['FullYear', 'Month', 'Date', 'Day', 'Hours', 'Minutes', 'Seconds', 'Milliseconds'].forEach(function(field) {
+				const getUTC = NativeDate.prototype['getUTC' + field];
+				utils.replaceMethod(NativeDate.prototype, 'get' + field, function() {
+					return function() {
+						return getUTC.call(localDate(this));
+					};
+				});
+				const setUTC = NativeDate.prototype['setUTC' + field];
+				if (setUTC) {
+					utils.replaceMethod(NativeDate.prototype, 'set' + field, function() {
+						return function() {
+							const local = localDate(this);
+							return nativeSetTime.call(this, fromLocal(setUTC.apply(local, arguments)));
+						};
+					});
+				}
+			});
+			utils.replaceMethod(NativeDate.prototype, 'getYear', function() {
+				return function getYear() {
+					return localDate(this).getUTCFullYear() - 1900;
+				};
+			});
+
+			const pad = function(value, length) {
+				let result = String(Math.abs(value));
+				while (result.length < length) {
+					result = '0' + result;
+				}
+				return result;
+			};
+			const weekdays = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
+			const months = ['Jan', 'Feb', 'Mar', 'Apr', 'May', 'Jun', 'Jul', 'Aug', 'Sep', 'Oct', 'Nov', 'Dec'];
+
+			// Название зоны в скобках Date.prototype.toString, как в V8,
+			// на языке по умолчанию
+			let zoneNames = null;
+			if (zoneSupported) {
+				try {
+					zoneNames = new NativeDateTimeFormat(undefined, { timeZone: zoneId, timeZoneName: 'long' });
+				} catch (e) {}
+			}
+			const zoneName = function(ms, offset) {
+				if (zoneNames) {
+					const part = zoneNames.formatToParts(new NativeDate(ms)).filter(function(p) {
+						return p.type === 'timeZoneName';
+					})[0];
+					if (part) {
+						return part.value;
+					}
+				}
+				return 'GMT' + (offset > 0 ? '-' : '+') + pad(Math.floor(Math.abs(offset) / 60), 2) +
+					':' + pad(Math.abs(offset) % 60, 2);
+			};
+
+			const dateString = function(local) {
+				const year = local.getUTCFullYear();
+				return weekdays[local.getUTCDay()] + ' ' + months[local.getUTCMonth()] + ' ' +
+					pad(local.getUTCDate(), 2) + ' ' + (year < 0 ? '-' : '') + pad(year, 4);
+			};
+			const timeString = function(ms, local) {
+				const offset = offsetAt(ms);
+				return pad(local.getUTCHours(), 2) + ':' + pad(local.getUTCMinutes(), 2) + ':' +
+					pad(local.getUTCSeconds(), 2) + ' GMT' + (offset > 0 ? '-' : '+') +
+					pad(Math.floor(Math.abs(offset) / 60), 2) + pad(Math.abs(offset) % 60, 2) +
+					' (' + zoneName(ms, offset) + ')';
+			};
+
+			utils.replaceMethod(NativeDate.prototype, 'toString', function() {
+				return function toString() {
+					const ms = nativeGetTime.call(this);
+					if (isNaN(ms)) {
+						return 'Invalid Date';
+					}
+					const local = new NativeDate(toLocal(ms));
+					return dateString(local) + ' ' + timeString(ms, local);
+				};
+			});
+			utils.replaceMethod(NativeDate.prototype, 'toDateString', function() {
+				return function toDateString() {
+					const ms = nativeGetTime.call(this);
+					return isNaN(ms) ? 'Invalid Date' : dateString(new NativeDate(toLocal(ms)));
+				};
+			});
+			utils.replaceMethod(NativeDate.prototype, 'toTimeString', function() {
+				return function toTimeString() {
+					const ms = nativeGetTime.call(this);
+					return isNaN(ms) ? 'Invalid Date' : timeString(ms, new NativeDate(toLocal(ms)));
+				};
+			});
+
+			// hasZone проверяет, что строка даты задает смещение или зону.
+			// Строки ISO только с датой разбираются как UTC
+			const isoDate = /^[+-]?\d{4,6}(-\d\d(-\d\d)?)?$/;
+			const explicitZone = /(Z|[+-]\d\d(:?\d\d)?|\b(GMT|UTC|UT|[ECMP][SD]T))\s*(\(.*\))?\s*$/i;
+			const parseLocal = function(string) {
+				string = String(string);
+				const ms = nativeParse(string);
+				if (isNaN(ms) || isoDate.test(string.trim()) || explicitZone.test(string.trim())) {
+					return ms;
+				}
+				// Движок разобрал строку в настоящей зоне системы
+				return fromLocal(ms - nativeOffset.call(new NativeDate(ms)) * 60000);
+			};
+			utils.replaceMethod(NativeDate, 'parse', function() {
+				return function parse(string) {
+					return parseLocal(string);
+				};
+			});
+
+			// Конструктор Date: компоненты и строки разбираются в эмулируемой
+			// зоне, вызов без new возвращает строку текущего времени
+			utils.replaceConstructor(self, 'Date', function(original) {
+				return function(args, newTarget) {
+					if (args.length === 1 && typeof args[0] === 'string') {
+						return Reflect.construct(original, [parseLocal(args[0])], newTarget);
+					}
+					if (args.length >= 2) {
+						const utc = nativeUTC.apply(null, args);
+						return Reflect.construct(original, [fromLocal(utc)], newTarget);
+					}
+					return Reflect.construct(original, args, newTarget);
+				};
+			}, function(original) {
+				return function() {
+					return Date.prototype.toString.call(new original());
+				};
+			});
+
+			if (zoneSupported) {
+				// withZone добавляет зону в опции, если она не задана явно
+				const withZone = function(options) {
+					if (options === undefined) {
+						return { timeZone: zoneId };
+					}
+					if (options === null || Object(options).timeZone !== undefined) {
+						return options;
+					}
+					return Object.assign({}, options, { timeZone: zoneId });
+				};
+
+				['toLocaleString', 'toLocaleDateString', 'toLocaleTimeString'].forEach(function(name) {
+					utils.replaceMethod(NativeDate.prototype, name, function(original) {
+						return function(locales, options) {
+							return original.call(this, locales, withZone(options));
+						};
+					});
+				});
+
+				// Intl.DateTimeFormat с зоной по умолчанию: resolvedOptions и все
+				// остальные поля формата остаются нативными
+				utils.replaceConstructor(Intl, 'DateTimeFormat', function(original) {
+					return function(args, newTarget) {
+						return Reflect.construct(original, [args[0], withZone(args[1])], newTarget);
+					};
+				}, function(original) {
+					return function(args) {
+						return new original(args[0], withZone(args[1]));
+					};
+				});
+			}
+		}
+`
+
+const automationPatchScript = `
+		// Скрываем navigator.webdriver: без автоматизации Chrome возвращает false
+		utils.replaceGetter(Navigator.prototype, 'webdriver', function() {
+			return false;
+		});
+
+		// Удаляем chrome.runtime
+		if (window.chrome && window.chrome.runtime) {
+			delete window.chrome.runtime;
+		}
+
+		// Переопределяем permissions. query возвращает настоящий
+		// PermissionStatus, для уведомлений подменяется только state:
+		// он согласуется с Notification.permission, как в обычном Chrome
+		if (typeof Permissions !== 'undefined' && typeof PermissionStatus !== 'undefined') {
+			const notificationStatuses = new WeakSet();
+			utils.replaceMethod(Permissions.prototype, 'query', function(original) {
+				return function query(parameters) {
+					const result = original.apply(this, arguments);
+					if (!parameters || parameters.name !== 'notifications') {
+						return result;
+					}
+					return result.then(function(status) {
+						notificationStatuses.add(status);
+						return status;
+					});
+				};
+			});
+			utils.replaceGetter(PermissionStatus.prototype, 'state', function(original) {
+				if (!notificationStatuses.has(this)) {
+					return original.call(this);
+				}
+				if (typeof Notification === 'undefined' || Notification.permission === 'denied') {
+					return 'denied';
+				}
+				return Notification.permission === 'granted' ? 'granted' : 'prompt';
+			});
+		}
+`
+
+const pluginsPatchScript = `
+		// navigator.plugins и navigator.mimeTypes из cfg.plugins.
+		// Объекты создаются от нативных прототипов PluginArray, Plugin,
+		// MimeTypeArray и MimeType, а геттеры и методы прототипов отдают
+		// их данные. Для настоящих объектов вызываются оригиналы.
+		if (typeof PluginArray !== 'undefined' && typeof MimeTypeArray !== 'undefined') {
+			const pluginData = new WeakMap();
+
+			const defineGetter = function(proto, prop) {
+				const desc = Object.getOwnPropertyDescriptor(proto, prop);
+				const original = desc && desc.get;
+				const fake = Object.getOwnPropertyDescriptor({
+					get [prop]() {
+						if (pluginData.has(this)) {
+							return pluginData.get(this)[prop];
+						}
+						if (original) {
+							return original.call(this);
+						}
+						throw new TypeError('Illegal invocation');
+					}
+				}, prop).get;
+				utils.mask(fake, original, 'get ' + prop, 0);
+				Object.defineProperty(proto, prop, {
+					get: fake,
+					set: undefined,
+					enumerable: desc ? desc.enumerable : true,
+					configurable: true
+				});
+			};
+
+			const defineMethod = function(proto, iface, name, impl) {
+				utils.replaceMethod(proto, name, function(original) {
+					return function() {
+						if (!pluginData.has(this)) {
+							return original.apply(this, arguments);
+						}
+						if (arguments.length < impl.length) {
+							throw new TypeError("Failed to execute '" + name + "' on '" + iface + "': " +
+								impl.length + " argument required, but only " + arguments.length + " present.");
+						}
+						return impl.apply(this, arguments);
+					};
+				});
+			};
+
+			// fill заполняет коллекцию: индексы перечислимы, именованные
+			// свойства нет, как у нативных коллекций
+			const fill = function(obj, props, items) {
+				props.items = items;
+				props.length = items.length;
+				pluginData.set(obj, props);
+				items.forEach(function(entry, i) {
+					Object.defineProperty(obj, i, { value: entry, writable: false, enumerable: true, configurable: true });
+				});
+				items.forEach(function(entry) {
+					const key = pluginData.get(entry).key;
+					if (!(key in obj)) {
+						Object.defineProperty(obj, key, { value: entry, writable: false, enumerable: false, configurable: true });
+					}
+				});
+				return obj;
+			};
+
+			// MimeType общий для всех плагинов, enabledPlugin - первый
+			// плагин, который его объявил
+			const mimeTypes = [];
+			const mimeByType = {};
+			const plugins = (cfg.plugins || []).map(function(p) {
+				const plugin = Object.create(Plugin.prototype);
+				const own = [];
+				(p.mimeTypes || []).forEach(function(m) {
+					let mime = mimeByType[m.type];
+					if (!mime) {
+						mime = Object.create(MimeType.prototype);
+						pluginData.set(mime, {
+							key: m.type,
+							type: m.type,
+							suffixes: m.suffixes,
+							description: m.description,
+							enabledPlugin: plugin
+						});
+						mimeByType[m.type] = mime;
+						mimeTypes.push(mime);
+					}
+					own.push(mime);
+				});
+				return fill(plugin, {
+					key: p.name,
+					name: p.name,
+					filename: p.filename,
+					description: p.description
+				}, own);
+			});
+			const pluginArray = fill(Object.create(PluginArray.prototype), {}, plugins);
+			const mimeTypeArray = fill(Object.create(MimeTypeArray.prototype), {}, mimeTypes);
+
+			const item = function(index) {
+				return pluginData.get(this).items[Number(index) >>> 0] || null;
+			};
+			const namedItem = function(name) {
+				const items = pluginData.get(this).items;
+				for (let i = 0; i < items.length; i++) {
+					if (pluginData.get(items[i]).key === String(name)) {
+						return items[i];
+					}
+				}
+				return null;
+			};
+
+			defineGetter(PluginArray.prototype, 'length');
+			defineMethod(PluginArray.prototype, 'PluginArray', 'item', item);
+			defineMethod(PluginArray.prototype, 'PluginArray', 'namedItem', namedItem);
+			defineMethod(PluginArray.prototype, 'PluginArray', 'refresh', function() {});
+			['name', 'filename', 'description', 'length'].forEach(function(prop) {
+				defineGetter(Plugin.prototype, prop);
+			});
+			defineMethod(Plugin.prototype, 'Plugin', 'item', item);
+			defineMethod(Plugin.prototype, 'Plugin', 'namedItem', namedItem);
+			defineGetter(MimeTypeArray.prototype, 'length');
+			defineMethod(MimeTypeArray.prototype, 'MimeTypeArray', 'item', item);
+			defineMethod(MimeTypeArray.prototype, 'MimeTypeArray', 'namedItem', namedItem);
+			['type', 'suffixes', 'description', 'enabledPlugin'].forEach(function(prop) {
+				defineGetter(MimeType.prototype, prop);
+			});
+
+			utils.replaceGetter(Navigator.prototype, 'plugins', function() {
+				return pluginArray;
+			});
+			utils.replaceGetter(Navigator.prototype, 'mimeTypes', function() {
+				return mimeTypeArray;
+			});
+			// Встроенный просмотрщик PDF есть, если плагины объявляют application/pdf
+			utils.replaceGetter(Navigator.prototype, 'pdfViewerEnabled', function() {
+				return Boolean(mimeByType['application/pdf']);
+			});
+		}
+`
+
+const workersPatchScript = `
+		// Dedicated Worker запускается из blob: сначала выполняется прелюдия
+		// с переопределениями fingerprint, затем исходный скрипт воркера.
+		// SharedWorker не оборачивается, чтобы не ломать общий экземпляр
+		// между вкладками - он обрабатывается через подключение к цели.
+		// location читается при создании воркера: в воркере, который
+		// подключен на паузе, он блокирует поток до загрузки скрипта
+		const baseURL = function() {
+			return workerURL || self.location.href;
+		};
+		const preludeFor = function(url) {
+			return workerPrelude + '(' + JSON.stringify(workerPrelude) + ', ' + JSON.stringify(url) + ');\n';
+		};
+		// blob URL не отзываются: воркер загружает скрипт асинхронно
+		const blobURL = function(source) {
+			return URL.createObjectURL(new Blob([source], { type: 'text/javascript' }));
+		};
+
+		if (typeof Worker !== 'undefined' && typeof Blob !== 'undefined' && URL.createObjectURL) {
+			utils.replaceConstructor(self, 'Worker', function(original) {
+				return function(args, newTarget) {
+					let url;
+					try {
+						url = new URL(String(args[0]), baseURL()).href;
+					} catch (e) {
+						return Reflect.construct(original, args, newTarget);
+					}
+
+					const options = args[1];
+					let source;
+					if (options && options.type === 'module') {
+						source = 'import ' + JSON.stringify(blobURL(preludeFor(url))) + ';\n' +
+							'import ' + JSON.stringify(url) + ';\n';
+					} else {
+						source = preludeFor(url) + 'importScripts(' + JSON.stringify(url) + ');\n';
+					}
+
+					try {
+						const wrapped = Array.prototype.slice.call(args);
+						wrapped[0] = blobURL(source);
+						return Reflect.construct(original, wrapped, newTarget);
+					} catch (e) {
+						// Например, CSP запрещает blob: воркеры
+						return Reflect.construct(original, args, newTarget);
+					}
+				};
+			});
+		}
+
+		// Внутри обернутого воркера относительные URL и location
+		// разрешаются от исходного URL скрипта, а не от blob
+		if (workerURL) {
+			const base = new URL(workerURL);
+			const resolve = function(url) {
+				try {
+					return new URL(String(url), base).href;
+				} catch (e) {
+					return url;
+				}
+			};
+
+			if (typeof WorkerLocation !== 'undefined') {
+				['href', 'origin', 'protocol', 'host', 'hostname', 'port', 'pathname', 'search', 'hash'].forEach(function(prop) {
+					utils.replaceGetter(WorkerLocation.prototype, prop, function() {
+						return base[prop];
+					});
+				});
+				utils.replaceMethod(WorkerLocation.prototype, 'toString', function() {
+					return function toString() { return base.href; };
+				});
+			}
+
+			utils.replaceMethod(utils.ownerOf(self, 'importScripts'), 'importScripts', function(original) {
+				return function importScripts() {
+					return original.apply(this, Array.prototype.map.call(arguments, resolve));
+				};
+			});
+
+			if (typeof fetch === 'function') {
+				utils.replaceMethod(utils.ownerOf(self, 'fetch'), 'fetch', function(original) {
+					return function fetch(input) {
+						const args = Array.prototype.slice.call(arguments);
+						if (typeof input === 'string' || input instanceof URL) {
+							args[0] = resolve(input);
+						}
+						return original.apply(this, args);
+					};
+				});
+			}
+
+			if (typeof XMLHttpRequest !== 'undefined') {
+				utils.replaceMethod(XMLHttpRequest.prototype, 'open', function(original) {
+					return function open(method, url) {
+						const args = Array.prototype.slice.call(arguments);
+						if (args.length > 1) {
+							args[1] = resolve(url);
+						}
+						return original.apply(this, args);
+					};
+				});
+			}
+		}
+`
@@ -17,17 +17,29 @@ func NewChrome119Windows11() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 1.0,
 		},
+		Window: &Window{
+			InnerWidth:     1920,
+			InnerHeight:    969,
+			OuterWidth:     1920,
+			OuterHeight:    1040,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 17,
+		},
+		MediaFeatures: newMediaFeatures("Win32", "Intel(R) UHD Graphics 630"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
-		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (Intel)",
-			Renderer:               "ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)",
-			UnmaskedVendor:         "Intel Inc.",
-			UnmaskedRenderer:       "Intel(R) UHD Graphics 630",
+			Offset: 240,
+		},
+		WebGL: webglCapsIntel.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (Intel)",
+			UnmaskedRenderer:       "ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: webgpuCapsDesktop.adapter("intel", "gen-9"),
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -38,11 +50,14 @@ func NewChrome119Windows11() *Fingerprint {
 			"Arial", "Courier New", "Georgia", "Times New Roman",
 			"Verdana", "Trebuchet MS", "Comic Sans MS", "Segoe UI",
 		},
-		Plugins:             []Plugin{},
+		Plugins:             ChromePDFPlugins(),
 		HardwareConcurrency: 8,
 		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.01,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        true,
@@ -50,6 +65,9 @@ func NewChrome119Windows11() *Fingerprint {
 			DischargingTime: 0,
 			Level:           1.0,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Win32", "", false, "Intel(R) UHD Graphics 630"),
+		Connection:   &Connection{EffectiveType: EffectiveType4G, RTT: 50, Downlink: 10},
+		ClientHints:  newChromeClientHints(119, "119.0.6045.159", "Windows", "15.0.0", "x86", "", false),
 	}
 }
 
@@ -70,17 +88,29 @@ func NewChrome119MacOS() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 2.0,
 		},
+		Window: &Window{
+			InnerWidth:     2560,
+			InnerHeight:    1338,
+			OuterWidth:     2560,
+			OuterHeight:    1417,
+			ScreenX:        0,
+			ScreenY:        23,
+			ScrollbarWidth: 0,
+		},
+		MediaFeatures: newMediaFeatures("MacIntel", "Apple M1 Pro"),
 		Timezone: &Timezone{
 			ID:     "America/Los_Angeles",
-			Offset: -420,
-		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (Apple)",
-			Renderer:               "ANGLE (Apple, Apple M1 Pro, OpenGL 4.1 Metal - 83.1)",
-			UnmaskedVendor:         "Apple Inc.",
-			UnmaskedRenderer:       "Apple M1 Pro",
+			Offset: 420,
+		},
+		WebGL: webglCapsAppleSilicon.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (Apple)",
+			UnmaskedRenderer:       "ANGLE (Apple, ANGLE Metal Renderer: Apple M1 Pro, Unspecified Version)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: webgpuCapsAppleSilicon.adapter("apple", "metal-3"),
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -91,18 +121,24 @@ func NewChrome119MacOS() *Fingerprint {
 			"Arial", "Courier New", "Georgia", "Times New Roman",
 			"Verdana", "Trebuchet MS", "Helvetica Neue", "Menlo",
 		},
-		Plugins:             []Plugin{},
+		Plugins:             ChromePDFPlugins(),
 		HardwareConcurrency: 10,
 		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      44100,
+			BaseLatency:     0.005804988662131519,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        true,
-			ChargingTime:    0,
+			ChargingTime:    450,
 			DischargingTime: 0,
 			Level:           0.95,
 		},
+		MediaDevices: newMediaDevices("Chrome", "MacIntel", "MacBook Pro", true, "Apple M1 Pro"),
+		Connection:   &Connection{EffectiveType: EffectiveType4G, RTT: 100, Downlink: 8.5},
+		ClientHints:  newChromeClientHints(119, "119.0.6045.159", "macOS", "14.0.0", "arm", "", false),
 	}
 }
 
@@ -123,17 +159,29 @@ func NewChrome119Linux() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 1.0,
 		},
+		Window: &Window{
+			InnerWidth:     1920,
+			InnerHeight:    982,
+			OuterWidth:     1920,
+			OuterHeight:    1053,
+			ScreenX:        0,
+			ScreenY:        27,
+			ScrollbarWidth: 15,
+		},
+		MediaFeatures: newMediaFeatures("Linux x86_64", "NVIDIA GeForce GTX 1080 Ti"),
 		Timezone: &Timezone{
 			ID:     "Europe/London",
 			Offset: 0,
 		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (NVIDIA)",
-			Renderer:               "ANGLE (NVIDIA, NVIDIA GeForce GTX 1080 Ti (0x00001B06) Direct3D11 vs_5_0 ps_5_0, D3D11)",
-			UnmaskedVendor:         "NVIDIA Corporation",
-			UnmaskedRenderer:       "NVIDIA GeForce GTX 1080 Ti/PCIe/SSE2",
+		WebGL: webglCapsDesktop.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (NVIDIA Corporation)",
+			UnmaskedRenderer:       "ANGLE (NVIDIA Corporation, NVIDIA GeForce GTX 1080 Ti/PCIe/SSE2, OpenGL 4.5.0)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: &WebGPU{NoAdapter: true},
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -144,11 +192,14 @@ func NewChrome119Linux() *Fingerprint {
 			"Arial", "Courier New", "Georgia", "Times New Roman",
 			"Verdana", "Trebuchet MS", "Liberation Sans", "Ubuntu",
 		},
-		Plugins:             []Plugin{},
+		Plugins:             ChromePDFPlugins(),
 		HardwareConcurrency: 12,
-		DeviceMemory:        16,
+		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.005333333333333333,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        true,
@@ -156,6 +207,9 @@ func NewChrome119Linux() *Fingerprint {
 			DischargingTime: 0,
 			Level:           1.0,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Linux x86_64", "", false, "NVIDIA GeForce GTX 1080 Ti"),
+		Connection:   &Connection{EffectiveType: EffectiveType4G, RTT: 50, Downlink: 10},
+		ClientHints:  newChromeClientHints(119, "119.0.6045.159", "Linux", "", "x86", "", false),
 	}
 }
 
@@ -176,17 +230,29 @@ func NewChrome119Android() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 2.625,
 		},
+		Window: &Window{
+			InnerWidth:     412,
+			InnerHeight:    859,
+			OuterWidth:     412,
+			OuterHeight:    915,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 0,
+		},
+		MediaFeatures: newMediaFeatures("Linux armv8l", "Adreno (TM) 730"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
-		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (Qualcomm)",
-			Renderer:               "ANGLE (Qualcomm, Adreno (TM) 730, OpenGL ES 3.2)",
-			UnmaskedVendor:         "Qualcomm",
-			UnmaskedRenderer:       "Adreno (TM) 730",
+			Offset: 240,
+		},
+		WebGL: webglCapsAdreno.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (Qualcomm)",
+			UnmaskedRenderer:       "ANGLE (Qualcomm, Adreno (TM) 730, OpenGL ES 3.2)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: &WebGPU{Disable: true}, // WebGPU на Android с Chrome 121
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -198,7 +264,10 @@ func NewChrome119Android() *Fingerprint {
 		HardwareConcurrency: 8,
 		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.01,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        false,
@@ -206,6 +275,9 @@ func NewChrome119Android() *Fingerprint {
 			DischargingTime: 18000,
 			Level:           0.75,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Linux armv8l", "Pixel 7", true, "Adreno (TM) 730"),
+		Connection:   &Connection{Type: "cellular", EffectiveType: EffectiveType4G, RTT: 150, Downlink: 3.2, DownlinkMax: 100},
+		ClientHints:  newChromeClientHints(119, "119.0.6045.163", "Android", "13.0.0", "", "Pixel 7", true),
 	}
 }
 
@@ -226,17 +298,29 @@ func NewSafari17iOS() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 3.0,
 		},
+		Window: &Window{
+			InnerWidth:     390,
+			InnerHeight:    714,
+			OuterWidth:     390,
+			OuterHeight:    844,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 0,
+		},
+		MediaFeatures: newMediaFeatures("iPhone", "Apple GPU"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
+			Offset: 240,
 		},
-		WebGL: &WebGL{
-			Vendor:                 "Apple Inc.",
-			Renderer:               "Apple GPU",
+		WebGL: webglCapsAppleMobile.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
 			UnmaskedVendor:         "Apple Inc.",
-			UnmaskedRenderer:       "Apple A16 GPU",
+			UnmaskedRenderer:       "Apple GPU",
+			Version:                "WebGL 1.0",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (1.0)",
-		},
+		}),
+		WebGPU: &WebGPU{Disable: true},
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -246,9 +330,11 @@ func NewSafari17iOS() *Fingerprint {
 		Fonts:               []string{"SF Pro Text", "SF Pro Display", "Helvetica Neue"},
 		Plugins:             []Plugin{},
 		HardwareConcurrency: 6,
-		DeviceMemory:        6,
+		DeviceMemory:        4,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        false,
@@ -256,6 +342,8 @@ func NewSafari17iOS() *Fingerprint {
 			DischargingTime: 14400,
 			Level:           0.80,
 		},
+		MediaDevices: newMediaDevices("Safari", "iPhone", "iPhone", true, "Apple GPU"),
+		Connection:   &Connection{Disable: true},
 	}
 }
 
@@ -264,7 +352,7 @@ func NewChrome119iOS() *Fingerprint {
 	return &Fingerprint{
 		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.109 Mobile/15E148 Safari/604.1",
 		Platform:  "iPhone",
-		Vendor:    "Google Inc.",
+		Vendor:    "Apple Computer, Inc.",
 		Language:  "en-US",
 		Languages: []string{"en-US", "en"},
 		Screen: &Screen{
@@ -276,17 +364,29 @@ func NewChrome119iOS() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 3.0,
 		},
+		Window: &Window{
+			InnerWidth:     390,
+			InnerHeight:    714,
+			OuterWidth:     390,
+			OuterHeight:    844,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 0,
+		},
+		MediaFeatures: newMediaFeatures("iPhone", "Apple GPU"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
+			Offset: 240,
 		},
-		WebGL: &WebGL{
-			Vendor:                 "Apple Inc.",
-			Renderer:               "Apple GPU",
+		WebGL: webglCapsAppleMobile.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
 			UnmaskedVendor:         "Apple Inc.",
-			UnmaskedRenderer:       "Apple A16 GPU",
+			UnmaskedRenderer:       "Apple GPU",
+			Version:                "WebGL 1.0",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (1.0)",
-		},
+		}),
+		WebGPU: &WebGPU{Disable: true},
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -296,9 +396,11 @@ func NewChrome119iOS() *Fingerprint {
 		Fonts:               []string{"SF Pro Text", "SF Pro Display", "Helvetica Neue"},
 		Plugins:             []Plugin{},
 		HardwareConcurrency: 6,
-		DeviceMemory:        6,
+		DeviceMemory:        4,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        false,
@@ -306,6 +408,8 @@ func NewChrome119iOS() *Fingerprint {
 			DischargingTime: 14400,
 			Level:           0.80,
 		},
+		MediaDevices: newMediaDevices("Chrome", "iPhone", "iPhone", true, "Apple GPU"),
+		Connection:   &Connection{Disable: true},
 	}
 }
 
@@ -318,25 +422,37 @@ func NewChrome134Android() *Fingerprint {
 		Language:  "en-US",
 		Languages: []string{"en-US", "en"},
 		Screen: &Screen{
-			Width:            1080,
-			Height:           2400,
-			AvailWidth:       1080,
-			AvailHeight:      2340,
+			Width:            412,
+			Height:           915,
+			AvailWidth:       412,
+			AvailHeight:      915,
 			ColorDepth:       24,
 			PixelDepth:       24,
-			DevicePixelRatio: 2.75,
+			DevicePixelRatio: 2.625,
 		},
+		Window: &Window{
+			InnerWidth:     412,
+			InnerHeight:    859,
+			OuterWidth:     412,
+			OuterHeight:    915,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 0,
+		},
+		MediaFeatures: newMediaFeatures("Linux armv8l", "Adreno (TM) 740"),
 		Timezone: &Timezone{
 			ID:     "Europe/Berlin",
 			Offset: -60,
 		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (Qualcomm)",
-			Renderer:               "ANGLE (Qualcomm, Adreno (TM) 740, OpenGL ES 3.2)",
-			UnmaskedVendor:         "Qualcomm",
-			UnmaskedRenderer:       "Adreno (TM) 740",
+		WebGL: webglCapsAdreno.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (Qualcomm)",
+			UnmaskedRenderer:       "ANGLE (Qualcomm, Adreno (TM) 740, OpenGL ES 3.2)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: webgpuCapsAndroid.adapter("qualcomm", "adreno-7xx"),
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -350,7 +466,10 @@ func NewChrome134Android() *Fingerprint {
 		HardwareConcurrency: 8,
 		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.01,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        false,
@@ -358,6 +477,9 @@ func NewChrome134Android() *Fingerprint {
 			DischargingTime: 7200,
 			Level:           0.87,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Linux armv8l", "Pixel 8a", true, "Adreno (TM) 740"),
+		Connection:   &Connection{Type: "cellular", EffectiveType: EffectiveType4G, RTT: 150, Downlink: 3.2, DownlinkMax: 100},
+		ClientHints:  newChromeClientHints(134, "134.0.6998.135", "Android", "14.0.0", "", "Pixel 8a", true),
 	}
 }
 
@@ -378,17 +500,29 @@ func NewChrome134Windows11() *Fingerprint {
 			PixelDepth:       24,
 			DevicePixelRatio: 1.0,
 		},
+		Window: &Window{
+			InnerWidth:     1920,
+			InnerHeight:    969,
+			OuterWidth:     1920,
+			OuterHeight:    1040,
+			ScreenX:        0,
+			ScreenY:        0,
+			ScrollbarWidth: 17,
+		},
+		MediaFeatures: newMediaFeatures("Win32", "NVIDIA GeForce RTX 3060"),
 		Timezone: &Timezone{
 			ID:     "America/New_York",
-			Offset: -240,
-		},
-		WebGL: &WebGL{
-			Vendor:                 "Google Inc. (NVIDIA)",
-			Renderer:               "ANGLE (NVIDIA, NVIDIA GeForce RTX 3060 Direct3D11 vs_5_0 ps_5_0, D3D11)",
-			UnmaskedVendor:         "NVIDIA Corporation",
-			UnmaskedRenderer:       "NVIDIA GeForce RTX 3060/PCIe/SSE2",
+			Offset: 240,
+		},
+		WebGL: webglCapsDesktop.applyTo(&WebGL{
+			Vendor:                 "WebKit",
+			Renderer:               "WebKit WebGL",
+			UnmaskedVendor:         "Google Inc. (NVIDIA)",
+			UnmaskedRenderer:       "ANGLE (NVIDIA, NVIDIA GeForce RTX 3060 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			Version:                "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
 			ShadingLanguageVersion: "WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)",
-		},
+		}),
+		WebGPU: webgpuCapsDesktop.adapter("nvidia", "ampere"),
 		Canvas: &Canvas{
 			Noise: 0.01,
 		},
@@ -399,11 +533,14 @@ func NewChrome134Windows11() *Fingerprint {
 			"Arial", "Calibri", "Cambria", "Consolas", "Courier New",
 			"Georgia", "Segoe UI", "Times New Roman", "Verdana", "Trebuchet MS",
 		},
-		Plugins:             []Plugin{},
+		Plugins:             ChromePDFPlugins(),
 		HardwareConcurrency: 12,
-		DeviceMemory:        16,
+		DeviceMemory:        8,
 		Audio: &Audio{
-			Noise: 0.01,
+			Noise:           0.01,
+			SampleRate:      48000,
+			BaseLatency:     0.01,
+			MaxChannelCount: 2,
 		},
 		Battery: &Battery{
 			Charging:        true,
@@ -411,5 +548,34 @@ func NewChrome134Windows11() *Fingerprint {
 			DischargingTime: 0,
 			Level:           1.0,
 		},
+		MediaDevices: newMediaDevices("Chrome", "Win32", "", true, "NVIDIA GeForce RTX 3060"),
+		Connection:   &Connection{EffectiveType: EffectiveType4G, RTT: 50, Downlink: 10},
+		ClientHints:  newChromeClientHints(134, "134.0.6998.118", "Windows", "15.0.0", "x86", "", false),
+	}
+}
+
+// ChromePDFPlugins возвращает плагины современного desktop Chrome:
+// пять записей встроенного просмотрщика PDF с типами application/pdf и text/pdf
+func ChromePDFPlugins() []Plugin {
+	names := []string{
+		"PDF Viewer",
+		"Chrome PDF Viewer",
+		"Chromium PDF Viewer",
+		"Microsoft Edge PDF Viewer",
+		"WebKit built-in PDF",
+	}
+
+	plugins := make([]Plugin, 0, len(names))
+	for _, name := range names {
+		plugins = append(plugins, Plugin{
+			Name:        name,
+			Description: "Portable Document Format",
+			Filename:    "internal-pdf-viewer",
+			MimeTypes: []MimeType{
+				{Type: "application/pdf", Description: "Portable Document Format", Suffixes: "pdf"},
+				{Type: "text/pdf", Description: "Portable Document Format", Suffixes: "pdf"},
+			},
+		})
 	}
+	return plugins
 }
@@ -0,0 +1,222 @@
+package fingerprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ProfileVersion текущая версия схемы сохраненного профиля.
+// Увеличивается при несовместимых изменениях формата; новые
+// необязательные поля Fingerprint версию не меняют.
+//
+// Версия 2: WebGL.Vendor и WebGL.Renderer хранят VENDOR и RENDERER,
+// а строки ANGLE перенесены в UnmaskedVendor и UnmaskedRenderer.
+//
+// Версия 3: Timezone.Offset имеет знак Date.getTimezoneOffset
+// (положительно к западу от UTC), раньше знак был обратным.
+const ProfileVersion = 3
+
+// Profile сохраненный fingerprint с метаданными
+type Profile struct {
+	Version     int          `json:"version"`          // версия схемы (ProfileVersion)
+	CreatedAt   time.Time    `json:"createdAt"`        // время создания профиля
+	Seed        int64        `json:"seed,omitempty"`   // seed генератора (GenerateOptions.Seed)
+	Source      string       `json:"source,omitempty"` // происхождение: "generator", имя preset и т.п.
+	Fingerprint *Fingerprint `json:"fingerprint"`
+}
+
+// NewProfile создает профиль текущей версии для fingerprint
+func NewProfile(fp *Fingerprint) *Profile {
+	return &Profile{
+		Version:     ProfileVersion,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		Fingerprint: fp,
+	}
+}
+
+// FromJSON восстанавливает Fingerprint из JSON, полученного через ToJSON.
+// Неизвестные поля и значения неверного типа приводят к ошибке.
+// Отсутствующие поля остаются нулевыми, поэтому JSON, сохраненный
+// старой версией библиотеки, читается и после добавления новых полей.
+// JSON без версии читается в текущем формате: смещение временной зоны
+// со старым знаком переводится только в профилях (см. ProfileVersion).
+func FromJSON(data string) (*Fingerprint, error) {
+	var fp Fingerprint
+	if err := decodeStrict([]byte(data), &fp); err != nil {
+		return nil, fmt.Errorf("failed to decode fingerprint: %w", err)
+	}
+	return &fp, nil
+}
+
+// SaveToFile сохраняет fingerprint в файл как профиль текущей версии
+func (f *Fingerprint) SaveToFile(path string) error {
+	return SaveProfile(path, NewProfile(f))
+}
+
+// LoadFromFile загружает fingerprint из файла профиля.
+// Поддерживается и JSON без метаданных (результат ToJSON).
+func LoadFromFile(path string) (*Fingerprint, error) {
+	profile, err := LoadProfile(path)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Fingerprint, nil
+}
+
+// SaveProfile сохраняет профиль в файл
+func SaveProfile(path string, profile *Profile) error {
+	if err := profile.validate(); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// LoadProfile загружает профиль из файла.
+// Для JSON без метаданных возвращается профиль с Version 0.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	profile, err := ParseProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile %s: %w", path, err)
+	}
+	return profile, nil
+}
+
+// ParseProfile разбирает профиль из JSON
+func ParseProfile(data []byte) (*Profile, error) {
+	// Профиль отличается от голого fingerprint наличием версии
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, describeJSONError(err)
+	}
+
+	if probe.Version == nil {
+		var fp Fingerprint
+		if err := decodeStrict(data, &fp); err != nil {
+			return nil, err
+		}
+		migrateWebGL(&fp)
+		return &Profile{Fingerprint: &fp}, nil
+	}
+
+	var profile Profile
+	if err := decodeStrict(data, &profile); err != nil {
+		return nil, err
+	}
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+	if profile.Version < 2 {
+		migrateWebGL(profile.Fingerprint)
+	}
+	if profile.Version < 3 {
+		migrateTimezone(profile.Fingerprint)
+	}
+	profile.Version = ProfileVersion
+	return &profile, nil
+}
+
+// migrateWebGL переводит WebGL из формата версии 1, где Vendor и Renderer
+// хранили UNMASKED_* строки, а Unmasked* - имя видеокарты.
+// WebGL в новом формате не меняется.
+func migrateWebGL(fp *Fingerprint) {
+	w := fp.WebGL
+	if w == nil || w.Vendor == "" {
+		return
+	}
+	strs := browserWebGLStrings(fp.UserAgent)
+	if w.Vendor == strs.vendor || w.Renderer == strs.renderer {
+		return
+	}
+
+	w.UnmaskedVendor, w.UnmaskedRenderer = w.Vendor, w.Renderer
+	w.Vendor, w.Renderer = strs.vendor, strs.renderer
+	if w.Version == "" {
+		w.Version = strs.version
+	}
+}
+
+// migrateTimezone переводит Timezone.Offset из формата версии 2, где
+// смещение имело знак, обратный Date.getTimezoneOffset. Вручную заданное
+// смещение могло уже иметь новый знак, поэтому он меняется, только если
+// смещение не встречается среди переходов зоны Timezone.ID (1970–2049), а
+// обратное встречается. Результат не зависит от даты загрузки. Смещение
+// неизвестной зоны не меняется.
+func migrateTimezone(fp *Fingerprint) {
+	tz := fp.Timezone
+	if tz == nil || tz.Offset == 0 {
+		return
+	}
+	transitions, err := tz.transitions()
+	if err != nil {
+		return
+	}
+
+	offsets := make(map[int64]bool, len(transitions))
+	for _, tr := range transitions {
+		offsets[tr[1]] = true
+	}
+	if !offsets[int64(tz.Offset)] && offsets[-int64(tz.Offset)] {
+		tz.Offset = -tz.Offset
+	}
+}
+
+// validate проверяет метаданные профиля
+func (p *Profile) validate() error {
+	switch {
+	case p.Version < 1:
+		return fmt.Errorf("invalid profile version %d", p.Version)
+	case p.Version > ProfileVersion:
+		return fmt.Errorf("profile version %d is newer than supported version %d", p.Version, ProfileVersion)
+	case p.Fingerprint == nil:
+		return errors.New("profile has no fingerprint")
+	}
+	return nil
+}
+
+// decodeStrict декодирует JSON, отклоняя неизвестные поля
+// и данные после значения
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return describeJSONError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// describeJSONError делает ошибку декодирования понятнее,
+// указывая путь к полю
+func describeJSONError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("invalid value for field %q: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("invalid JSON at offset %d: %w", syntaxErr.Offset, err)
+	}
+	return err
+}
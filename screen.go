@@ -0,0 +1,198 @@
+package fingerprint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Значения screen.orientation.type
+const (
+	OrientationPortraitPrimary    = "portrait-primary"
+	OrientationPortraitSecondary  = "portrait-secondary"
+	OrientationLandscapePrimary   = "landscape-primary"
+	OrientationLandscapeSecondary = "landscape-secondary"
+)
+
+// cdpOrientationTypes типы ориентации Emulation.setDeviceMetricsOverride
+var cdpOrientationTypes = map[string]emulation.OrientationType{
+	OrientationPortraitPrimary:    emulation.OrientationTypePortraitPrimary,
+	OrientationPortraitSecondary:  emulation.OrientationTypePortraitSecondary,
+	OrientationLandscapePrimary:   emulation.OrientationTypeLandscapePrimary,
+	OrientationLandscapeSecondary: emulation.OrientationTypeLandscapeSecondary,
+}
+
+// orientation возвращает тип и угол screen.orientation. Без Orientation
+// тип выводится из ширины и высоты экрана.
+func (s *Screen) orientation() (string, int) {
+	if s.Orientation != "" {
+		return s.Orientation, s.OrientationAngle
+	}
+	if s.Height > s.Width {
+		return OrientationPortraitPrimary, s.OrientationAngle
+	}
+	return OrientationLandscapePrimary, s.OrientationAngle
+}
+
+// screenOrientation возвращает ориентацию для Emulation.setDeviceMetricsOverride
+func (s *Screen) screenOrientation() *emulation.ScreenOrientation {
+	orientation, angle := s.orientation()
+	return &emulation.ScreenOrientation{
+		Type:  cdpOrientationTypes[orientation],
+		Angle: int64(angle),
+	}
+}
+
+// emulatedFeatures возвращает медиа-функции для Emulation.setEmulatedMedia;
+// пустые значения не переопределяются. pointer, hover и dynamic-range
+// Chrome в этой команде игнорирует, они задаются флагами запуска
+// (см. blinkSettings и Fingerprint.AllocatorOptions).
+func (m *MediaFeatures) emulatedFeatures() []*emulation.MediaFeature {
+	var features []*emulation.MediaFeature
+	for _, f := range []struct{ name, value string }{
+		{"prefers-color-scheme", m.ColorScheme},
+		{"prefers-reduced-motion", m.ReducedMotion},
+		{"color-gamut", m.ColorGamut},
+	} {
+		if f.value != "" {
+			features = append(features, &emulation.MediaFeature{Name: f.name, Value: f.value})
+		}
+	}
+	return features
+}
+
+// Значения типов указателя и hover в настройках Blink
+var (
+	blinkPointerTypes = map[string]int{"none": 1, "coarse": 2, "fine": 4}
+	blinkHoverTypes   = map[string]int{"none": 1, "hover": 2}
+)
+
+// blinkSettings возвращает значение флага --blink-settings, задающее
+// pointer и hover (а также any-pointer и any-hover) для всех вкладок;
+// пустая строка - флаг не нужен
+func (m *MediaFeatures) blinkSettings() string {
+	var settings []string
+	if v, ok := blinkPointerTypes[m.Pointer]; ok {
+		settings = append(settings, fmt.Sprintf("primaryPointerType=%d,availablePointerTypes=%d", v, v))
+	}
+	if v, ok := blinkHoverTypes[m.Hover]; ok {
+		settings = append(settings, fmt.Sprintf("primaryHoverType=%d,availableHoverTypes=%d", v, v))
+	}
+	return strings.Join(settings, ",")
+}
+
+// newMediaFeatures возвращает медиа-функции устройства: телефоны и
+// планшеты без мыши, у дисплеев Apple и Android-телефонов широкий охват
+// P3 и HDR, у остальных компьютеров sRGB
+func newMediaFeatures(platform, gpuRenderer string) *MediaFeatures {
+	m := &MediaFeatures{
+		ColorScheme:   "light",
+		ReducedMotion: "no-preference",
+		Pointer:       "fine",
+		Hover:         "hover",
+		ColorGamut:    "srgb",
+		DynamicRange:  "standard",
+	}
+	switch platformOS(platform) {
+	case "ios", "android":
+		m.Pointer = "coarse"
+		m.Hover = "none"
+		m.ColorGamut = "p3"
+		m.DynamicRange = "high"
+	case "macos":
+		// Встроенные дисплеи Mac на Apple Silicon - P3 и XDR
+		m.ColorGamut = "p3"
+		if strings.Contains(gpuRenderer, "Apple") {
+			m.DynamicRange = "high"
+		}
+	}
+	return m
+}
+
+// rotate поворачивает экран в альбомную или портретную ориентацию:
+// меняет местами ширину и высоту (и доступную область) и задает тип и
+// угол screen.orientation. Естественная ориентация телефона и планшета -
+// портретная, поэтому альбомная получает угол 90. Возвращает false, если
+// экран уже в нужной ориентации.
+func (s *Screen) rotate(landscape bool) bool {
+	orientation, _ := s.orientation()
+	isLandscape := orientation == OrientationLandscapePrimary || orientation == OrientationLandscapeSecondary
+	if isLandscape == landscape {
+		return false
+	}
+
+	if landscape != (s.Width > s.Height) {
+		s.Width, s.Height = s.Height, s.Width
+		s.AvailWidth, s.AvailHeight = s.AvailHeight, s.AvailWidth
+	}
+	if landscape {
+		s.Orientation, s.OrientationAngle = OrientationLandscapePrimary, 90
+	} else {
+		s.Orientation, s.OrientationAngle = OrientationPortraitPrimary, 0
+	}
+	return true
+}
+
+// rotated возвращает копию fingerprint с повернутыми экраном и окном
+// (см. Screen.rotate) или nil, если экран уже в нужной ориентации.
+// Остальные поля копия разделяет с f.
+func (f *Fingerprint) rotated(landscape bool) *Fingerprint {
+	if f.Screen == nil {
+		return nil
+	}
+	screen := *f.Screen
+	if !screen.rotate(landscape) {
+		return nil
+	}
+
+	fp := *f
+	fp.Screen = &screen
+	if f.Window != nil {
+		window := *f.Window
+		window.rotate()
+		fp.Window = &window
+	}
+	return &fp
+}
+
+// Rotate поворачивает эмулируемое мобильное устройство в альбомную
+// (landscape) или портретную ориентацию посреди сессии. Инжектор переходит
+// на копию fingerprint с повернутыми Screen и Window (см. Fingerprint),
+// заново задает метрики устройства и заменяет скрипт для новых документов
+// текущей вкладки. Fingerprint, переданный в NewInjector, не меняется.
+// Chrome отправляет странице события resize, orientationchange и change
+// у screen.orientation, а патч "screen" к этому моменту уже возвращает
+// новые размеры экрана и окна.
+//
+// Другие уже подключенные вкладки и iframe сохраняют прежний скрипт;
+// цели, подключенные после поворота, получают новый.
+func (inj *Injector) Rotate(ctx context.Context, landscape bool) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if inj.Fingerprint().Screen == nil || !inj.isMobileDevice() {
+			return fmt.Errorf("rotation requires a mobile fingerprint with screen")
+		}
+
+		// Фоновые подключения auto-attach читают fingerprint одновременно,
+		// поэтому он заменяется целиком, а не меняется на месте
+		inj.mu.Lock()
+		fp := inj.fingerprint.rotated(landscape)
+		if fp != nil {
+			inj.fingerprint = fp
+		}
+		inj.mu.Unlock()
+		if fp == nil {
+			return nil
+		}
+
+		if err := inj.SetDeviceMetrics(ctx).Do(ctx); err != nil {
+			return fmt.Errorf("failed to set device metrics: %w", err)
+		}
+		if err := inj.updateScript(ctx); err != nil {
+			return fmt.Errorf("failed to update script: %w", err)
+		}
+		return nil
+	})
+}
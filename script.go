@@ -0,0 +1,332 @@
+package fingerprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// scriptData данные для шаблона инжектируемого скрипта.
+// Все значения из Fingerprint попадают в скрипт только через Config,
+// сам шаблон и тела патчей содержат статический JavaScript.
+type scriptData struct {
+	Config        string // JSON-объект с параметрами fingerprint
+	Utils         string // общие функции для патчей
+	WorkerPrelude string // JSON-строка с прелюдией для воркеров; пусто без патча "workers"
+	Patches       []Patch
+	Workers       []WorkerPatch
+}
+
+var (
+	injectionTemplate = template.Must(template.New("injection").Parse(injectionScriptTemplate))
+	workerTemplate    = template.Must(template.New("worker").Parse(workerPreludeTemplate))
+)
+
+// renderInjectionScript собирает скрипт из шаблона, конфига fingerprint и патчей
+func renderInjectionScript(fp *Fingerprint, patches []Patch) (string, error) {
+	data, err := newScriptData(fp, patches)
+	if err != nil {
+		return "", err
+	}
+
+	// Прелюдия нужна только обертке Worker, а без нее лишь увеличивает
+	// скрипт каждого документа
+	if hasPatch(patches, PatchWorkers) {
+		prelude, err := renderTemplate(workerTemplate, data)
+		if err != nil {
+			return "", err
+		}
+		if data.WorkerPrelude, err = jsLiteral(prelude); err != nil {
+			return "", err
+		}
+	}
+
+	return renderTemplate(injectionTemplate, data)
+}
+
+// hasPatch сообщает, есть ли патч name среди patches
+func hasPatch(patches []Patch, name string) bool {
+	for _, p := range patches {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderWorkerScript собирает скрипт для выполнения внутри воркера
+func renderWorkerScript(fp *Fingerprint, patches []Patch) (string, error) {
+	data, err := newScriptData(fp, patches)
+	if err != nil {
+		return "", err
+	}
+
+	prelude, err := renderTemplate(workerTemplate, data)
+	if err != nil {
+		return "", err
+	}
+	self, err := jsLiteral(prelude)
+	if err != nil {
+		return "", err
+	}
+
+	// Прелюдия получает собственный исходный код, чтобы оборачивать
+	// вложенные воркеры
+	return prelude + "(" + self + ", null);\n", nil
+}
+
+// scriptConfig конфиг скрипта: поля Fingerprint и производные от них
+// данные, которые не хранятся в профиле
+type scriptConfig struct {
+	*Fingerprint
+	// TimezoneTransitions переходы зоны Timezone.ID по данным IANA;
+	// пусто, если зона неизвестна
+	TimezoneTransitions []zoneTransition `json:"timezoneTransitions,omitempty"`
+}
+
+// newScriptConfig вычисляет производные данные конфига
+func newScriptConfig(fp *Fingerprint) *scriptConfig {
+	config := &scriptConfig{Fingerprint: fp}
+	if fp.Timezone != nil {
+		// Для неизвестной зоны патч использует постоянное Timezone.Offset
+		config.TimezoneTransitions, _ = fp.Timezone.transitions()
+	}
+	return config
+}
+
+// newScriptData подготавливает данные шаблона
+func newScriptData(fp *Fingerprint, patches []Patch) (*scriptData, error) {
+	config, err := jsLiteral(newScriptConfig(fp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fingerprint config: %w", err)
+	}
+
+	data := &scriptData{
+		Config:  config,
+		Utils:   nativeUtilsScript,
+		Patches: patches,
+	}
+	for _, p := range patches {
+		if wp, ok := p.(WorkerPatch); ok && wp.WorkerScript() != "" {
+			data.Workers = append(data.Workers, wp)
+		}
+	}
+	return data, nil
+}
+
+// renderTemplate выполняет шаблон скрипта
+func renderTemplate(tmpl *template.Template, data *scriptData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s script: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// jsLiteral кодирует значение в JSON, пригодный для вставки в JavaScript.
+// encoding/json экранирует кавычки, обратные слэши, переводы строк,
+// символы <, >, & и U+2028/U+2029, поэтому значение не может выйти
+// за пределы литерала. Ключи map сортируются, так что результат
+// детерминирован.
+func jsLiteral(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// injectionScriptTemplate каркас скрипта.
+// Каждый патч выполняется в своем блоке, чтобы ошибка в одном
+// не мешала остальным. Патчи устанавливают переопределения через
+// объект utils (см. nativeUtilsScript).
+const injectionScriptTemplate = `
+(function() {
+	'use strict';
+
+	const cfg = {{.Config}};
+{{- if .WorkerPrelude}}
+	const workerPrelude = {{.WorkerPrelude}};
+	const workerURL = null;
+{{- end}}
+{{.Utils}}{{range .Patches}}
+	// patch {{printf "%q" .Name}}
+	try {
+{{.Script}}
+	} catch (e) {}
+{{end}}})();
+`
+
+// workerPreludeTemplate каркас прелюдии для воркеров.
+// Это функциональное выражение: оно вызывается с собственным исходным
+// кодом (для вложенных воркеров) и исходным URL скрипта воркера.
+// В теле выполняются только патчи, реализующие WorkerPatch.
+const workerPreludeTemplate = `(function(workerPrelude, workerURL) {
+	'use strict';
+
+	const cfg = {{.Config}};
+{{.Utils}}{{range .Workers}}
+	// patch {{printf "%q" .Name}}
+	try {
+{{.WorkerScript}}
+	} catch (e) {}
+{{end}}})`
+
+// nativeUtilsScript общие функции, через которые патчи устанавливают
+// переопределения. Подмененные функции и геттеры выглядят как нативные:
+// Function.prototype.toString возвращает исходный "[native code]",
+// name и length совпадают с оригиналом, дескрипторы свойств сохраняют
+// enumerable/configurable/set, а у методов нет prototype и конструктора.
+const nativeUtilsScript = `
+	const utils = (function() {
+		const nativeToString = Function.prototype.toString;
+		const sources = new WeakMap();
+
+		// sourceOf возвращает "нативный" исходный код функции,
+		// в том числе уже подмененной ранее
+		const sourceOf = function(fn) {
+			return sources.has(fn) ? sources.get(fn) : nativeToString.call(fn);
+		};
+
+		// mask придает подмененной функции вид оригинала
+		const mask = function(fake, original, name, length) {
+			if (typeof original === 'function') {
+				name = original.name;
+				length = original.length;
+				sources.set(fake, sourceOf(original));
+			} else {
+				sources.set(fake, 'function ' + name + '() { [native code] }');
+			}
+			Object.defineProperty(fake, 'name', { value: name, configurable: true });
+			Object.defineProperty(fake, 'length', { value: length, configurable: true });
+			return fake;
+		};
+
+		// replaceMethod заменяет метод obj[name] функцией, которую возвращает
+		// makeImpl(original). Дескриптор свойства сохраняется.
+		const replaceMethod = function(obj, name, makeImpl) {
+			const desc = obj && Object.getOwnPropertyDescriptor(obj, name);
+			if (!desc || typeof desc.value !== 'function') {
+				return false;
+			}
+			const original = desc.value;
+			const impl = makeImpl(original);
+			const fake = ({ [name]() { return impl.apply(this, arguments); } })[name];
+			mask(fake, original);
+			Object.defineProperty(obj, name, Object.assign({}, desc, { value: fake }));
+			return true;
+		};
+
+		// replaceGetter заменяет геттер obj[prop]; impl вызывается с this
+		// и оригинальным геттером. Если оригинал есть, он вызывается первым,
+		// чтобы чужой this по-прежнему приводил к "Illegal invocation".
+		const replaceGetter = function(obj, prop, impl) {
+			const desc = Object.getOwnPropertyDescriptor(obj, prop);
+			const original = desc && desc.get;
+			const fake = Object.getOwnPropertyDescriptor({
+				get [prop]() {
+					if (original) {
+						original.call(this);
+					}
+					return impl.call(this, original);
+				}
+			}, prop).get;
+			mask(fake, original, 'get ' + prop, 0);
+			Object.defineProperty(obj, prop, {
+				get: fake,
+				set: desc ? desc.set : undefined,
+				enumerable: desc ? desc.enumerable : true,
+				configurable: desc ? desc.configurable : true
+			});
+			return true;
+		};
+
+		// replaceConstructor заменяет конструктор obj[name]. makeImpl(original)
+		// возвращает функцию (args, newTarget), создающую объект. Прототип,
+		// constructor и статические свойства сохраняются. Вызов без new
+		// передается оригиналу (например, ошибка "Illegal constructor"),
+		// если не задан makeCall(original), возвращающий функцию (args, thisArg).
+		const replaceConstructor = function(obj, name, makeImpl, makeCall) {
+			const desc = Object.getOwnPropertyDescriptor(obj, name);
+			if (!desc || typeof desc.value !== 'function') {
+				return false;
+			}
+			const original = desc.value;
+			const impl = makeImpl(original);
+			const call = makeCall ? makeCall(original) : null;
+			const fake = function() {
+				if (!new.target) {
+					return call ? call(arguments, this) : original.apply(this, arguments);
+				}
+				return impl(arguments, new.target === fake ? original : new.target);
+			};
+			mask(fake, original);
+			Object.setPrototypeOf(fake, Object.getPrototypeOf(original));
+			Reflect.ownKeys(original).forEach(function(key) {
+				if (key !== 'prototype' && !Object.prototype.hasOwnProperty.call(fake, key)) {
+					Object.defineProperty(fake, key, Object.getOwnPropertyDescriptor(original, key));
+				}
+			});
+			Object.defineProperty(fake, 'prototype', {
+				value: original.prototype,
+				writable: false,
+				enumerable: false,
+				configurable: false
+			});
+			const ctorDesc = Object.getOwnPropertyDescriptor(original.prototype, 'constructor');
+			if (ctorDesc) {
+				Object.defineProperty(original.prototype, 'constructor', Object.assign({}, ctorDesc, { value: fake }));
+			}
+			Object.defineProperty(obj, name, Object.assign({}, desc, { value: fake }));
+			return true;
+		};
+
+		// ownerOf возвращает объект в цепочке прототипов, которому
+		// принадлежит свойство (у глобальных объектов атрибуты и методы
+		// находятся на самом объекте, а не на прототипе)
+		const ownerOf = function(obj, prop) {
+			while (obj && !Object.prototype.hasOwnProperty.call(obj, prop)) {
+				obj = Object.getPrototypeOf(obj);
+			}
+			return obj;
+		};
+
+		// hash возвращает 32-битный хеш строки (FNV-1a), используется
+		// как seed для детерминированного шума
+		const hash = function(str) {
+			let h = 0x811c9dc5;
+			for (let i = 0; i < str.length; i++) {
+				h ^= str.charCodeAt(i);
+				h = Math.imul(h, 0x01000193);
+			}
+			return h >>> 0;
+		};
+
+		// noise возвращает детерминированное число из [0, 1) для пары
+		// (seed, index): один и тот же fingerprint всегда дает один шум
+		const noise = function(seed, index) {
+			let t = (seed + Math.imul(index, 0x9e3779b1)) | 0;
+			t = Math.imul(t ^ (t >>> 15), t | 1);
+			t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+			return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+		};
+
+		// Function.prototype.toString отдает исходники оригиналов
+		replaceMethod(Function.prototype, 'toString', function() {
+			return function toString() {
+				return sourceOf(this);
+			};
+		});
+
+		return {
+			mask: mask,
+			replaceMethod: replaceMethod,
+			replaceGetter: replaceGetter,
+			replaceConstructor: replaceConstructor,
+			ownerOf: ownerOf,
+			hash: hash,
+			noise: noise
+		};
+	})();
+`
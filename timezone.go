@@ -0,0 +1,101 @@
+package fingerprint
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	_ "time/tzdata" // смещения зон не зависят от базы зон в системе
+)
+
+// Диапазон, для которого в скрипт передаются переходы временной зоны.
+// Для дат вне диапазона действует ближайшее смещение.
+var (
+	zoneTransitionsFrom = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	zoneTransitionsTo   = time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// zoneTransition смена смещения зоны: начиная с момента [0] (миллисекунды
+// Unix) действует смещение [1] в минутах со знаком Date.getTimezoneOffset
+type zoneTransition [2]int64
+
+// Location загружает IANA-зону Timezone.ID
+func (tz *Timezone) Location() (*time.Location, error) {
+	if tz.ID == "" || tz.ID == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", tz.ID)
+	}
+	loc, err := time.LoadLocation(tz.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", tz.ID, err)
+	}
+	return loc, nil
+}
+
+// OffsetAt возвращает смещение зоны в момент t по данным IANA с учетом
+// летнего времени, в минутах со знаком Date.getTimezoneOffset
+func (tz *Timezone) OffsetAt(t time.Time) (int, error) {
+	loc, err := tz.Location()
+	if err != nil {
+		return 0, err
+	}
+	return zoneOffset(t.In(loc)), nil
+}
+
+// zoneOffsets возвращает смещения зоны для стандартного и летнего времени
+// в текущем году
+func zoneOffsets(loc *time.Location) []int {
+	year := time.Now().Year()
+	var offsets []int
+	for _, month := range []time.Month{time.January, time.July} {
+		offsets = append(offsets, zoneOffset(time.Date(year, month, 1, 12, 0, 0, 0, loc)))
+	}
+	return offsets
+}
+
+// zoneOffset возвращает смещение t в минутах, положительное к западу от UTC
+func zoneOffset(t time.Time) int {
+	_, seconds := t.Zone()
+	return -seconds / 60
+}
+
+// transitions возвращает переходы зоны в диапазоне
+// [zoneTransitionsFrom, zoneTransitionsTo). Первый элемент задает
+// смещение на начало диапазона.
+func (tz *Timezone) transitions() ([]zoneTransition, error) {
+	loc, err := tz.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	t := zoneTransitionsFrom.In(loc)
+	result := []zoneTransition{{t.UnixMilli(), int64(zoneOffset(t))}}
+	for {
+		_, end := t.ZoneBounds()
+		if end.IsZero() {
+			break
+		}
+		if !end.After(t) {
+			// Для правил после последнего перехода в базе ZoneBounds
+			// на границе високосного года может вернуть тот же момент
+			end = t.Add(time.Minute)
+		}
+		if !end.Before(zoneTransitionsTo) {
+			break
+		}
+		t = end
+		// Смена только аббревиатуры зоны не меняет смещение
+		if offset := int64(zoneOffset(t)); offset != result[len(result)-1][1] {
+			result = append(result, zoneTransition{t.UnixMilli(), offset})
+		}
+	}
+	return result, nil
+}
+
+// zoneOffsetAt ищет смещение для момента ms в таблице переходов так же,
+// как патч timezone в браузере
+func zoneOffsetAt(transitions []zoneTransition, ms int64) int64 {
+	i := sort.Search(len(transitions), func(i int) bool { return transitions[i][0] > ms })
+	if i == 0 {
+		return transitions[0][1]
+	}
+	return transitions[i-1][1]
+}
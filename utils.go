@@ -3,7 +3,9 @@ package fingerprint
 import (
 	"crypto/rand"
 	"fmt"
+	"math"
 	"math/big"
+	mathrand "math/rand"
 )
 
 // RandomUserAgent генерирует случайный User-Agent на основе preset
@@ -34,23 +36,28 @@ func RandomizeFingerprint(base *Fingerprint) *Fingerprint {
 	cores := []int{4, 6, 8, 12, 16}
 	fp.HardwareConcurrency = cores[randomInt(len(cores))]
 
-	// Варьируем Device Memory (4, 8, 16, 32)
-	memory := []int{4, 8, 16, 32}
+	// Варьируем Device Memory (Chrome ограничивает значение 8)
+	memory := []int{2, 4, 8}
 	fp.DeviceMemory = memory[randomInt(len(memory))]
 
-	// Варьируем Canvas noise
+	// Варьируем Canvas noise и seed, не затрагивая base
 	if fp.Canvas != nil {
-		fp.Canvas.Noise = 0.01 + float64(randomInt(50))/1000.0
+		canvas := *fp.Canvas
+		canvas.Noise = 0.01 + float64(randomInt(50))/1000.0
+		canvas.Seed = randomSeed()
+		fp.Canvas = &canvas
 	}
 
-	// Варьируем Audio noise
+	// Варьируем Audio noise, не затрагивая base
 	if fp.Audio != nil {
-		fp.Audio.Noise = 0.01 + float64(randomInt(50))/1000.0
+		audio := *fp.Audio
+		audio.Noise = 0.01 + float64(randomInt(50))/1000.0
+		fp.Audio = &audio
 	}
 
-	// Варьируем Battery level
+	// Варьируем Battery level, не затрагивая base
 	if fp.Battery != nil {
-		fp.Battery.Level = 0.5 + float64(randomInt(50))/100.0
+		fp.Battery = fp.Battery.withLevel(float64(50+randomInt(50)) / 100.0)
 	}
 
 	return &fp
@@ -85,13 +92,18 @@ func RandomResolution() *Screen {
 
 // RandomTimezone возвращает случайную временную зону
 func RandomTimezone() *Timezone {
+	return randomTimezone(nil)
+}
+
+// randomTimezone выбирает временную зону с помощью rnd
+func randomTimezone(rnd *random) *Timezone {
 	timezones := []struct {
 		id     string
 		offset int
 	}{
-		{"America/New_York", -240},
-		{"America/Los_Angeles", -420},
-		{"America/Chicago", -300},
+		{"America/New_York", 240},
+		{"America/Los_Angeles", 420},
+		{"America/Chicago", 300},
 		{"Europe/London", 0},
 		{"Europe/Paris", -60},
 		{"Europe/Moscow", -180},
@@ -100,7 +112,7 @@ func RandomTimezone() *Timezone {
 		{"Australia/Sydney", -600},
 	}
 
-	tz := timezones[randomInt(len(timezones))]
+	tz := timezones[rnd.intn(len(timezones))]
 
 	return &Timezone{
 		ID:     tz.id,
@@ -120,6 +132,47 @@ func randomInt(max int) int {
 	return int(n.Int64())
 }
 
+// randomSeed возвращает случайный положительный seed, который точно
+// представим числом в JavaScript
+func randomSeed() int64 {
+	return (*random)(nil).seed()
+}
+
+// random источник случайных чисел генератора.
+// nil или random без rand использует crypto/rand.
+type random struct {
+	rand *mathrand.Rand
+}
+
+// newRandom создает источник из src или seed.
+// Без src и с нулевым seed возвращает nil (crypto/rand).
+func newRandom(src mathrand.Source, seed int64) *random {
+	if src == nil && seed == 0 {
+		return nil
+	}
+	if src == nil {
+		src = mathrand.NewSource(seed)
+	}
+	return &random{rand: mathrand.New(src)}
+}
+
+// intn возвращает случайное число от 0 до max (не включая max)
+func (r *random) intn(max int) int {
+	if max <= 0 {
+		return 0
+	}
+	if r == nil || r.rand == nil {
+		return randomInt(max)
+	}
+	return r.rand.Intn(max)
+}
+
+// seed возвращает положительный seed, который точно представим
+// числом в JavaScript
+func (r *random) seed() int64 {
+	return int64(r.intn(math.MaxInt32)) + 1
+}
+
 // GenerateRandomFingerprint создает полностью случайный fingerprint
 // Использует умный генератор с базой данных реальных устройств
 func GenerateRandomFingerprint() *Fingerprint {
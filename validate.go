@@ -0,0 +1,722 @@
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Severity важность нарушения
+type Severity int
+
+const (
+	// SeverityWarning редкое, но возможное сочетание значений
+	SeverityWarning Severity = iota
+	// SeverityError сочетание, которого не бывает у реальных браузеров
+	SeverityError
+)
+
+// String возвращает название важности
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Имена встроенных правил
+const (
+	RuleUserAgentPlatform = "ua-platform"
+	RuleUserAgentVendor   = "ua-vendor"
+	RuleGPUPlatform       = "gpu-platform"
+	RuleWebGLBrowser      = "webgl-browser"
+	RuleWebGPU            = "webgpu"
+	RuleWebRTC            = "webrtc"
+	RuleScreenDevice      = "screen-device"
+	RuleScreenAvail       = "screen-avail"
+	RuleWindow            = "window"
+	RuleMediaFeatures     = "media-features"
+	RuleLanguages         = "languages"
+	RuleTimezone          = "timezone"
+	RuleHardware          = "hardware"
+	RuleBattery           = "battery"
+	RuleConnection        = "connection"
+	RuleClientHints       = "client-hints"
+)
+
+// Violation нарушение правила согласованности
+type Violation struct {
+	Rule     string // имя правила
+	Field    string // поле fingerprint в нотации JSON, например "screen.width"
+	Severity Severity
+	Message  string
+}
+
+// String возвращает описание нарушения
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", v.Severity, v.Field, v.Message, v.Rule)
+}
+
+// Violations результат проверки fingerprint
+type Violations []Violation
+
+// HasErrors сообщает, есть ли нарушения с SeverityError
+func (vs Violations) HasErrors() bool {
+	for _, v := range vs {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err возвращает ошибку со всеми нарушениями SeverityError
+// или nil, если их нет
+func (vs Violations) Err() error {
+	var errs []error
+	for _, v := range vs {
+		if v.Severity == SeverityError {
+			errs = append(errs, errors.New(v.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rule именованное правило проверки fingerprint
+type Rule struct {
+	Name  string
+	Check func(fp *Fingerprint) []Violation
+}
+
+// DefaultRules возвращает встроенные правила проверки
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: RuleUserAgentPlatform, Check: checkUserAgentPlatform},
+		{Name: RuleUserAgentVendor, Check: checkUserAgentVendor},
+		{Name: RuleGPUPlatform, Check: checkGPUPlatform},
+		{Name: RuleWebGLBrowser, Check: checkWebGLBrowser},
+		{Name: RuleWebGPU, Check: checkWebGPU},
+		{Name: RuleWebRTC, Check: checkWebRTC},
+		{Name: RuleScreenDevice, Check: checkScreenDevice},
+		{Name: RuleScreenAvail, Check: checkScreenAvail},
+		{Name: RuleWindow, Check: checkWindow},
+		{Name: RuleMediaFeatures, Check: checkMediaFeatures},
+		{Name: RuleLanguages, Check: checkLanguages},
+		{Name: RuleTimezone, Check: checkTimezone},
+		{Name: RuleHardware, Check: checkHardware},
+		{Name: RuleBattery, Check: checkBattery},
+		{Name: RuleConnection, Check: checkConnection},
+		{Name: RuleClientHints, Check: checkClientHints},
+	}
+}
+
+// Validate проверяет согласованность fingerprint.
+// Без аргументов используются DefaultRules. Поле Rule нарушений
+// заполняется именем правила, если правило его не указало.
+func (f *Fingerprint) Validate(rules ...Rule) Violations {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	var result Violations
+	for _, rule := range rules {
+		for _, v := range rule.Check(f) {
+			if v.Rule == "" {
+				v.Rule = rule.Name
+			}
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// violation создает нарушение
+func violation(rule, field string, severity Severity, format string, args ...interface{}) Violation {
+	return Violation{
+		Rule:     rule,
+		Field:    field,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// platformOS возвращает семейство ОС по navigator.platform
+func platformOS(platform string) string {
+	switch {
+	case strings.HasPrefix(platform, "Win"):
+		return "windows"
+	case platform == "MacIntel":
+		return "macos"
+	case platform == "iPhone" || platform == "iPad" || platform == "iPod":
+		return "ios"
+	case strings.HasPrefix(platform, "Linux arm") || platform == "Linux aarch64":
+		return "android"
+	case strings.HasPrefix(platform, "Linux"):
+		return "linux"
+	}
+	return ""
+}
+
+// userAgentOS возвращает семейство ОС по User-Agent
+func userAgentOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "Windows NT"):
+		return "windows"
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		return "ios"
+	case strings.Contains(ua, "Android"):
+		return "android"
+	case strings.Contains(ua, "Macintosh"):
+		return "macos"
+	case strings.Contains(ua, "CrOS"):
+		return "chromeos"
+	case strings.Contains(ua, "Linux"):
+		return "linux"
+	}
+	return ""
+}
+
+// deviceType возвращает тип устройства fingerprint: "desktop", "mobile" или "tablet"
+func deviceType(fp *Fingerprint) string {
+	switch platformOS(fp.Platform) {
+	case "ios":
+		if fp.Platform == "iPad" {
+			return "tablet"
+		}
+		return "mobile"
+	case "android":
+		// Chrome на Android-планшетах не добавляет "Mobile" в User-Agent
+		if strings.Contains(fp.UserAgent, "Mobile") {
+			return "mobile"
+		}
+		return "tablet"
+	}
+	return "desktop"
+}
+
+// checkUserAgentPlatform сверяет ОС в User-Agent и navigator.platform
+func checkUserAgentPlatform(fp *Fingerprint) []Violation {
+	uaOS := userAgentOS(fp.UserAgent)
+	os := platformOS(fp.Platform)
+	if uaOS == "" || os == "" || uaOS == os {
+		return nil
+	}
+	return []Violation{violation(RuleUserAgentPlatform, "platform", SeverityError,
+		"platform %q does not match %s User-Agent", fp.Platform, uaOS)}
+}
+
+// expectedVendor возвращает navigator.vendor для браузера из User-Agent.
+// Все браузеры на iOS используют WebKit и отдают vendor Apple.
+func expectedVendor(ua string) (string, bool) {
+	switch {
+	case strings.Contains(ua, "FxiOS/") || strings.Contains(ua, "CriOS/"):
+		return "Apple Computer, Inc.", true
+	case strings.Contains(ua, "Firefox/"):
+		return "", true
+	case strings.Contains(ua, "Chrome/"):
+		return "Google Inc.", true
+	case strings.Contains(ua, "Version/") && strings.Contains(ua, "Safari/"):
+		return "Apple Computer, Inc.", true
+	}
+	return "", false
+}
+
+// checkUserAgentVendor сверяет браузер в User-Agent и navigator.vendor
+func checkUserAgentVendor(fp *Fingerprint) []Violation {
+	vendor, ok := expectedVendor(fp.UserAgent)
+	if !ok || vendor == fp.Vendor {
+		return nil
+	}
+	return []Violation{violation(RuleUserAgentVendor, "vendor", SeverityError,
+		"vendor %q does not match User-Agent, expected %q", fp.Vendor, vendor)}
+}
+
+// checkGPUPlatform проверяет, что GPU встречается на платформе
+func checkGPUPlatform(fp *Fingerprint) []Violation {
+	if fp.WebGL == nil {
+		return nil
+	}
+
+	gpu := strings.ToLower(fp.WebGL.UnmaskedVendor + " " + fp.WebGL.UnmaskedRenderer)
+	containsAny := func(names ...string) bool {
+		for _, name := range names {
+			if strings.Contains(gpu, name) {
+				return true
+			}
+		}
+		return false
+	}
+	apple := containsAny("apple")
+	mobile := containsAny("qualcomm", "adreno", "mali", "powervr", "imagination")
+	nvidia := containsAny("nvidia", "geforce")
+	desktop := nvidia || containsAny("radeon", "amd", "intel")
+
+	const field = "webgl.unmaskedRenderer"
+	var result []Violation
+	add := func(severity Severity, format string, args ...interface{}) {
+		result = append(result, violation(RuleGPUPlatform, field, severity, format, args...))
+	}
+
+	switch platformOS(fp.Platform) {
+	case "ios":
+		if !apple {
+			add(SeverityError, "%s GPU is not an Apple GPU", fp.Platform)
+		}
+	case "macos":
+		switch {
+		case mobile:
+			add(SeverityError, "mobile GPU %q on macOS", fp.WebGL.UnmaskedRenderer)
+		case nvidia:
+			add(SeverityWarning, "NVIDIA GPU on macOS is found only on old Macs")
+		}
+	case "android":
+		if apple || desktop {
+			add(SeverityError, "GPU %q is not used in Android devices", fp.WebGL.UnmaskedRenderer)
+		}
+	case "windows", "linux":
+		switch {
+		case apple:
+			add(SeverityError, "Apple GPU on %s", fp.Platform)
+		case mobile:
+			add(SeverityWarning, "mobile GPU %q on %s", fp.WebGL.UnmaskedRenderer, fp.Platform)
+		}
+	}
+	return result
+}
+
+// checkWebGLBrowser проверяет, что VENDOR и RENDERER WebGL совпадают
+// со строками движка браузера из User-Agent
+func checkWebGLBrowser(fp *Fingerprint) []Violation {
+	if fp.WebGL == nil || fp.UserAgent == "" {
+		return nil
+	}
+
+	strs := browserWebGLStrings(fp.UserAgent)
+	var result []Violation
+	if fp.WebGL.Vendor != "" && fp.WebGL.Vendor != strs.vendor {
+		result = append(result, violation(RuleWebGLBrowser, "webgl.vendor", SeverityError,
+			"WebGL vendor %q does not match User-Agent, expected %q", fp.WebGL.Vendor, strs.vendor))
+	}
+	if fp.WebGL.Renderer != "" && fp.WebGL.Renderer != strs.renderer {
+		result = append(result, violation(RuleWebGLBrowser, "webgl.renderer", SeverityError,
+			"WebGL renderer %q does not match User-Agent, expected %q", fp.WebGL.Renderer, strs.renderer))
+	}
+	return result
+}
+
+// checkWebGPU проверяет, что адаптер WebGPU - та же видеокарта, что и в WebGL
+func checkWebGPU(fp *Fingerprint) []Violation {
+	if fp.WebGPU == nil || fp.WebGPU.Disable || fp.WebGPU.Vendor == "" || fp.WebGL == nil {
+		return nil
+	}
+
+	gpu := strings.ToLower(fp.WebGL.UnmaskedVendor + " " + fp.WebGL.UnmaskedRenderer)
+	if strings.Contains(gpu, strings.ToLower(fp.WebGPU.Vendor)) {
+		return nil
+	}
+	return []Violation{violation(RuleWebGPU, "webgpu.vendor", SeverityError,
+		"WebGPU adapter vendor %q does not match WebGL renderer %q", fp.WebGPU.Vendor, fp.WebGL.UnmaskedRenderer)}
+}
+
+// checkWebRTC проверяет режим WebRTC и адреса для маскировки
+func checkWebRTC(fp *Fingerprint) []Violation {
+	w := fp.WebRTC
+	if w == nil {
+		return nil
+	}
+
+	var result []Violation
+	switch w.Mode {
+	case "", WebRTCModeDisable, WebRTCModeMask, WebRTCModeProxyOnly:
+	default:
+		result = append(result, violation(RuleWebRTC, "webrtc.mode", SeverityError,
+			"unknown WebRTC mode %q", w.Mode))
+	}
+	if w.Disable && w.Mode != "" && w.Mode != WebRTCModeDisable {
+		result = append(result, violation(RuleWebRTC, "webrtc.disable", SeverityWarning,
+			"disable is ignored in WebRTC mode %q", w.Mode))
+	}
+	for _, ip := range []struct{ field, value string }{
+		{"webrtc.localIp", w.LocalIP},
+		{"webrtc.publicIp", w.PublicIP},
+	} {
+		if ip.value != "" && net.ParseIP(ip.value) == nil {
+			result = append(result, violation(RuleWebRTC, ip.field, SeverityError,
+				"invalid IP address %q", ip.value))
+		}
+	}
+	return result
+}
+
+// checkScreenDevice проверяет размеры экрана (в CSS-пикселях) и
+// devicePixelRatio для типа устройства
+func checkScreenDevice(fp *Fingerprint) []Violation {
+	s := fp.Screen
+	if s == nil {
+		return nil
+	}
+	if s.Width <= 0 || s.Height <= 0 {
+		return []Violation{violation(RuleScreenDevice, "screen.width", SeverityError,
+			"screen size %dx%d must be positive", s.Width, s.Height)}
+	}
+	if s.DevicePixelRatio <= 0 {
+		return []Violation{violation(RuleScreenDevice, "screen.devicePixelRatio", SeverityError,
+			"devicePixelRatio %v must be positive", s.DevicePixelRatio)}
+	}
+
+	// Короткая сторона не зависит от ориентации
+	short := s.Width
+	if s.Height < short {
+		short = s.Height
+	}
+
+	var result []Violation
+	switch kind := deviceType(fp); kind {
+	case "mobile":
+		if short < 320 || short > 600 {
+			result = append(result, violation(RuleScreenDevice, "screen.width", SeverityError,
+				"screen %dx%d is not a phone screen in CSS pixels", s.Width, s.Height))
+		}
+		if s.DevicePixelRatio < 1.5 {
+			result = append(result, violation(RuleScreenDevice, "screen.devicePixelRatio", SeverityError,
+				"devicePixelRatio %v is too low for a phone", s.DevicePixelRatio))
+		}
+	case "tablet":
+		if short < 600 || short > 1400 {
+			result = append(result, violation(RuleScreenDevice, "screen.width", SeverityWarning,
+				"screen %dx%d is unusual for a tablet", s.Width, s.Height))
+		}
+		if s.DevicePixelRatio < 1 {
+			result = append(result, violation(RuleScreenDevice, "screen.devicePixelRatio", SeverityError,
+				"devicePixelRatio %v is too low for a tablet", s.DevicePixelRatio))
+		}
+	default:
+		if s.Width < 1024 {
+			result = append(result, violation(RuleScreenDevice, "screen.width", SeverityWarning,
+				"screen width %d is unusual for a desktop", s.Width))
+		}
+		if s.DevicePixelRatio < 1 || s.DevicePixelRatio > 4 {
+			result = append(result, violation(RuleScreenDevice, "screen.devicePixelRatio", SeverityWarning,
+				"devicePixelRatio %v is unusual for a desktop", s.DevicePixelRatio))
+		}
+	}
+	return result
+}
+
+// checkScreenAvail проверяет, что доступная область не больше экрана
+func checkScreenAvail(fp *Fingerprint) []Violation {
+	s := fp.Screen
+	if s == nil {
+		return nil
+	}
+
+	var result []Violation
+	if s.AvailWidth <= 0 || s.AvailWidth > s.Width {
+		result = append(result, violation(RuleScreenAvail, "screen.availWidth", SeverityError,
+			"availWidth %d must be in (0, %d]", s.AvailWidth, s.Width))
+	}
+	if s.AvailHeight <= 0 || s.AvailHeight > s.Height {
+		result = append(result, violation(RuleScreenAvail, "screen.availHeight", SeverityError,
+			"availHeight %d must be in (0, %d]", s.AvailHeight, s.Height))
+	}
+	return result
+}
+
+// checkWindow проверяет, что viewport помещается в окно, а окно - в экран
+func checkWindow(fp *Fingerprint) []Violation {
+	w := fp.Window
+	if w == nil {
+		return nil
+	}
+
+	var result []Violation
+	if w.InnerWidth <= 0 || w.InnerHeight <= 0 || w.InnerWidth > w.OuterWidth || w.InnerHeight > w.OuterHeight {
+		result = append(result, violation(RuleWindow, "window.innerWidth", SeverityError,
+			"viewport %dx%d must fit in window %dx%d", w.InnerWidth, w.InnerHeight, w.OuterWidth, w.OuterHeight))
+	}
+	if s := fp.Screen; s != nil && (w.ScreenX < 0 || w.ScreenY < 0 ||
+		w.ScreenX+w.OuterWidth > s.Width || w.ScreenY+w.OuterHeight > s.Height) {
+		result = append(result, violation(RuleWindow, "window.outerWidth", SeverityWarning,
+			"window %dx%d at (%d, %d) does not fit in screen %dx%d",
+			w.OuterWidth, w.OuterHeight, w.ScreenX, w.ScreenY, s.Width, s.Height))
+	}
+	if w.ScrollbarWidth < 0 || w.ScrollbarWidth > 30 {
+		result = append(result, violation(RuleWindow, "window.scrollbarWidth", SeverityError,
+			"scrollbar width %d must be in [0, 30]", w.ScrollbarWidth))
+	}
+	return result
+}
+
+// mediaFeatureValues допустимые значения медиа-функций
+var mediaFeatureValues = map[string]map[string]bool{
+	"colorScheme":   {"light": true, "dark": true},
+	"reducedMotion": {"no-preference": true, "reduce": true},
+	"pointer":       {"fine": true, "coarse": true, "none": true},
+	"hover":         {"hover": true, "none": true},
+	"colorGamut":    {"srgb": true, "p3": true, "rec2020": true},
+	"dynamicRange":  {"standard": true, "high": true},
+}
+
+// checkMediaFeatures сверяет ориентацию экрана с его размерами, а
+// pointer и hover - с типом устройства
+func checkMediaFeatures(fp *Fingerprint) []Violation {
+	var result []Violation
+	if s := fp.Screen; s != nil {
+		orientation, angle := s.orientation()
+		portrait := orientation == OrientationPortraitPrimary || orientation == OrientationPortraitSecondary
+		switch {
+		case cdpOrientationTypes[orientation] == "":
+			result = append(result, violation(RuleMediaFeatures, "screen.orientation", SeverityError,
+				"unknown screen orientation %q", orientation))
+		case s.Width != s.Height && portrait != (s.Height > s.Width):
+			result = append(result, violation(RuleMediaFeatures, "screen.orientation", SeverityError,
+				"orientation %q does not match screen %dx%d", orientation, s.Width, s.Height))
+		}
+		if angle%90 != 0 || angle < 0 || angle >= 360 {
+			result = append(result, violation(RuleMediaFeatures, "screen.orientationAngle", SeverityError,
+				"orientation angle %d must be 0, 90, 180 or 270", angle))
+		}
+	}
+
+	m := fp.MediaFeatures
+	if m == nil {
+		return result
+	}
+	for _, f := range []struct{ field, value string }{
+		{"colorScheme", m.ColorScheme},
+		{"reducedMotion", m.ReducedMotion},
+		{"pointer", m.Pointer},
+		{"hover", m.Hover},
+		{"colorGamut", m.ColorGamut},
+		{"dynamicRange", m.DynamicRange},
+	} {
+		if f.value != "" && !mediaFeatureValues[f.field][f.value] {
+			result = append(result, violation(RuleMediaFeatures, "mediaFeatures."+f.field, SeverityError,
+				"unknown %s value %q", f.field, f.value))
+		}
+	}
+	touch := deviceType(fp) != "desktop"
+	if m.Pointer != "" && touch != (m.Pointer == "coarse") {
+		result = append(result, violation(RuleMediaFeatures, "mediaFeatures.pointer", SeverityWarning,
+			"pointer %q is unusual for a %s", m.Pointer, deviceType(fp)))
+	}
+	if m.Hover != "" && touch != (m.Hover == "none") {
+		result = append(result, violation(RuleMediaFeatures, "mediaFeatures.hover", SeverityWarning,
+			"hover %q is unusual for a %s", m.Hover, deviceType(fp)))
+	}
+	return result
+}
+
+// checkLanguages сверяет navigator.language и navigator.languages
+func checkLanguages(fp *Fingerprint) []Violation {
+	switch {
+	case fp.Language == "":
+		return []Violation{violation(RuleLanguages, "language", SeverityError, "language is empty")}
+	case len(fp.Languages) == 0:
+		return []Violation{violation(RuleLanguages, "languages", SeverityError, "languages is empty")}
+	case fp.Languages[0] != fp.Language:
+		return []Violation{violation(RuleLanguages, "languages", SeverityError,
+			"languages[0] %q does not match language %q", fp.Languages[0], fp.Language)}
+	}
+
+	var result []Violation
+	seen := make(map[string]bool, len(fp.Languages))
+	for i, lang := range fp.Languages {
+		field := fmt.Sprintf("languages[%d]", i)
+		switch {
+		case !languageTag.MatchString(lang):
+			result = append(result, violation(RuleLanguages, field, SeverityError,
+				"%q is not a valid language tag", lang))
+		case seen[strings.ToLower(lang)]:
+			result = append(result, violation(RuleLanguages, field, SeverityWarning,
+				"duplicate language %q", lang))
+		}
+		seen[strings.ToLower(lang)] = true
+	}
+	return result
+}
+
+// languageTag упрощенная форма тега BCP 47: язык и необязательные
+// подтеги (скрипт, регион, вариант)
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// checkTimezone проверяет, что смещение совпадает со стандартным
+// или летним временем зоны в текущем году
+func checkTimezone(fp *Fingerprint) []Violation {
+	tz := fp.Timezone
+	if tz == nil {
+		return nil
+	}
+
+	loc, err := tz.Location()
+	if err != nil {
+		return []Violation{violation(RuleTimezone, "timezone.id", SeverityError,
+			"unknown timezone %q", tz.ID)}
+	}
+
+	offsets := zoneOffsets(loc)
+	for _, offset := range offsets {
+		if offset == tz.Offset {
+			return nil
+		}
+	}
+	return []Violation{violation(RuleTimezone, "timezone.offset", SeverityError,
+		"offset %d does not match %s (expected one of %v)", tz.Offset, tz.ID, offsets)}
+}
+
+// hardwareConcurrencyValues типичные значения navigator.hardwareConcurrency
+var hardwareConcurrencyValues = map[int]bool{
+	1: true, 2: true, 4: true, 6: true, 8: true, 10: true, 12: true, 14: true,
+	16: true, 18: true, 20: true, 24: true, 28: true, 32: true, 36: true,
+	48: true, 64: true, 96: true, 128: true,
+}
+
+// deviceMemoryValues значения navigator.deviceMemory: Chrome округляет
+// объем памяти до степени двойки и ограничивает его 8 ГБ
+var deviceMemoryValues = map[int]bool{1: true, 2: true, 4: true, 8: true}
+
+// checkHardware проверяет hardwareConcurrency и deviceMemory
+func checkHardware(fp *Fingerprint) []Violation {
+	var result []Violation
+
+	switch {
+	case fp.HardwareConcurrency <= 0:
+		result = append(result, violation(RuleHardware, "hardwareConcurrency", SeverityError,
+			"hardwareConcurrency %d must be positive", fp.HardwareConcurrency))
+	case !hardwareConcurrencyValues[fp.HardwareConcurrency]:
+		result = append(result, violation(RuleHardware, "hardwareConcurrency", SeverityWarning,
+			"hardwareConcurrency %d is unusual", fp.HardwareConcurrency))
+	}
+
+	if !deviceMemoryValues[fp.DeviceMemory] {
+		result = append(result, violation(RuleHardware, "deviceMemory", SeverityError,
+			"deviceMemory %d is not one of 1, 2, 4, 8", fp.DeviceMemory))
+	}
+	return result
+}
+
+// checkBattery проверяет уровень батареи и время зарядки и разрядки
+func checkBattery(fp *Fingerprint) []Violation {
+	b := fp.Battery
+	if b == nil {
+		return nil
+	}
+
+	var result []Violation
+	if b.Level <= 0 || b.Level > 1 {
+		result = append(result, violation(RuleBattery, "battery.level", SeverityError,
+			"battery level %g must be in (0, 1]", b.Level))
+	}
+	for _, f := range []struct {
+		field string
+		value float64
+	}{
+		{"battery.chargingTime", b.ChargingTime},
+		{"battery.dischargingTime", b.DischargingTime},
+		{"battery.speed", b.Speed},
+	} {
+		if f.value < 0 {
+			result = append(result, violation(RuleBattery, f.field, SeverityError,
+				"%s %g must not be negative", f.field, f.value))
+		}
+	}
+	if b.Charging && b.Level >= 1 && b.ChargingTime > 0 {
+		result = append(result, violation(RuleBattery, "battery.chargingTime", SeverityWarning,
+			"fully charged battery reports chargingTime 0, not %g", b.ChargingTime))
+	}
+	return result
+}
+
+// checkConnection сверяет navigator.connection с браузером и платформой
+// и effectiveType с rtt и downlink
+func checkConnection(fp *Fingerprint) []Violation {
+	c := fp.Connection
+	if c == nil {
+		return nil
+	}
+
+	var result []Violation
+	chrome := strings.Contains(fp.UserAgent, "Chrome/") && userAgentOS(fp.UserAgent) != "ios"
+	switch {
+	case c.Disable:
+		if chrome {
+			result = append(result, violation(RuleConnection, "connection.disable", SeverityWarning,
+				"Chrome always has navigator.connection"))
+		}
+		return result
+	case !chrome && fp.UserAgent != "":
+		result = append(result, violation(RuleConnection, "connection.disable", SeverityError,
+			"navigator.connection exists only in Chrome"))
+	}
+
+	if c.Type != "" && !connectionTypes[c.Type] {
+		result = append(result, violation(RuleConnection, "connection.type", SeverityError,
+			"unknown connection type %q", c.Type))
+	}
+	if c.Type != "" && platformOS(fp.Platform) != "android" {
+		result = append(result, violation(RuleConnection, "connection.type", SeverityError,
+			"connection type is only exposed on Android, not %q", fp.Platform))
+	}
+	if c.RTT < 0 || c.RTT > connectionMaxRTT || c.Downlink < 0 || c.Downlink > connectionMaxDownlink {
+		result = append(result, violation(RuleConnection, "connection.rtt", SeverityError,
+			"rtt %d and downlink %g must be within 0-%d ms and 0-%g Mbps",
+			c.RTT, c.Downlink, connectionMaxRTT, connectionMaxDownlink))
+	}
+	if expected := effectiveConnectionType(c.RTT, c.Downlink); c.EffectiveType != expected {
+		result = append(result, violation(RuleConnection, "connection.effectiveType", SeverityWarning,
+			"effectiveType %q does not match rtt %d and downlink %g, expected %q",
+			c.EffectiveType, c.RTT, c.Downlink, expected))
+	}
+	return result
+}
+
+// clientHintsPlatforms значения Sec-CH-UA-Platform для семейств ОС
+var clientHintsPlatforms = map[string]string{
+	"windows": "Windows",
+	"macos":   "macOS",
+	"linux":   "Linux",
+	"android": "Android",
+}
+
+// checkClientHints сверяет Client Hints с User-Agent и платформой
+func checkClientHints(fp *Fingerprint) []Violation {
+	ch := fp.ClientHints
+	if ch == nil {
+		return nil
+	}
+	if !strings.Contains(fp.UserAgent, "Chrome/") {
+		return []Violation{violation(RuleClientHints, "clientHints", SeverityError,
+			"Client Hints are sent only by Chromium browsers")}
+	}
+
+	var result []Violation
+	if platform, ok := clientHintsPlatforms[platformOS(fp.Platform)]; ok && ch.Platform != platform {
+		result = append(result, violation(RuleClientHints, "clientHints.platform", SeverityError,
+			"platform %q does not match %q, expected %q", ch.Platform, fp.Platform, platform))
+	}
+	if ch.Mobile != (deviceType(fp) == "mobile") {
+		result = append(result, violation(RuleClientHints, "clientHints.mobile", SeverityError,
+			"mobile %v does not match User-Agent", ch.Mobile))
+	}
+
+	// Мажорная версия брендов совпадает с версией Chrome в User-Agent
+	major := fp.UserAgent[strings.Index(fp.UserAgent, "Chrome/")+len("Chrome/"):]
+	if i := strings.Index(major, "."); i >= 0 {
+		major = major[:i]
+	}
+	for _, b := range ch.Brands {
+		if (b.Brand == "Google Chrome" || b.Brand == "Chromium") && b.Version != major {
+			result = append(result, violation(RuleClientHints, "clientHints.brands", SeverityError,
+				"brand %q version %s does not match User-Agent version %s", b.Brand, b.Version, major))
+		}
+	}
+	for _, b := range ch.FullVersionList {
+		if (b.Brand == "Google Chrome" || b.Brand == "Chromium") && !strings.HasPrefix(b.Version, major+".") {
+			result = append(result, violation(RuleClientHints, "clientHints.fullVersionList", SeverityError,
+				"brand %q full version %s does not match User-Agent version %s", b.Brand, b.Version, major))
+		}
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package fingerprint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WebGLCaps возможности WebGL видеокарты: лимиты getParameter,
+// расширения и точность шейдеров
+type WebGLCaps struct {
+	Parameters        map[string]int
+	ArrayParameters   map[string][]int
+	Extensions        []string
+	WebGL2Extensions  []string
+	ShaderPrecision   map[string]ShaderPrecision
+	ContextAttributes map[string]bool
+}
+
+// applyTo копирует возможности в WebGL и возвращает его.
+// Карты и списки копируются, чтобы изменения fingerprint
+// не затрагивали базу устройств.
+func (c *WebGLCaps) applyTo(w *WebGL) *WebGL {
+	if c == nil {
+		return w
+	}
+
+	w.Parameters = make(map[string]int, len(c.Parameters))
+	for name, value := range c.Parameters {
+		w.Parameters[name] = value
+	}
+	w.ArrayParameters = make(map[string][]int, len(c.ArrayParameters))
+	for name, value := range c.ArrayParameters {
+		w.ArrayParameters[name] = append([]int(nil), value...)
+	}
+	w.Extensions = append([]string(nil), c.Extensions...)
+	w.WebGL2Extensions = append([]string(nil), c.WebGL2Extensions...)
+	w.ShaderPrecision = make(map[string]ShaderPrecision, len(c.ShaderPrecision))
+	for name, precision := range c.ShaderPrecision {
+		w.ShaderPrecision[name] = precision
+	}
+	if c.ContextAttributes != nil {
+		w.ContextAttributes = make(map[string]bool, len(c.ContextAttributes))
+		for name, value := range c.ContextAttributes {
+			w.ContextAttributes[name] = value
+		}
+	}
+	return w
+}
+
+// webglStrings значения VENDOR, RENDERER, VERSION и SHADING_LANGUAGE_VERSION
+// контекста WebGL 1, которые зависят только от движка браузера
+type webglStrings struct {
+	vendor, renderer, version, shadingLanguageVersion string
+}
+
+// browserWebGLStrings возвращает строки WebGL для браузера из User-Agent.
+// Все браузеры на iOS используют WebKit.
+func browserWebGLStrings(userAgent string) webglStrings {
+	switch {
+	case strings.Contains(userAgent, "Firefox/"):
+		return webglStrings{"Mozilla", "Mozilla", "WebGL 1.0", "WebGL GLSL ES 1.0"}
+	case strings.Contains(userAgent, "Chrome/"):
+		return webglStrings{"WebKit", "WebKit WebGL", "WebGL 1.0 (OpenGL ES 2.0 Chromium)",
+			"WebGL GLSL ES 1.0 (OpenGL ES GLSL ES 1.0 Chromium)"}
+	default:
+		return webglStrings{"WebKit", "WebKit WebGL", "WebGL 1.0", "WebGL GLSL ES 1.0 (1.0)"}
+	}
+}
+
+// gpuVendorNames короткие имена производителей в строках ANGLE
+var gpuVendorNames = map[string]string{
+	"NVIDIA Corporation": "NVIDIA",
+	"AMD":                "AMD",
+	"Intel Inc.":         "Intel",
+	"Apple Inc.":         "Apple",
+	"Qualcomm":           "Qualcomm",
+	"ARM":                "ARM",
+}
+
+// unmaskedWebGL возвращает UNMASKED_VENDOR_WEBGL и UNMASKED_RENDERER_WEBGL
+// видеокарты так, как их отдает браузер на платформе. Chrome и Firefox
+// рисуют через ANGLE с бэкендом платформы, браузеры на WebKit скрывают
+// модель видеокарты Apple.
+func unmaskedWebGL(gpu *GPUSpec, platform, userAgent string) (vendor, renderer string) {
+	short := gpuVendorNames[gpu.Vendor]
+	if short == "" {
+		short = gpu.Vendor
+	}
+	webkit := !strings.Contains(userAgent, "Chrome/") && !strings.Contains(userAgent, "Firefox/")
+
+	switch platformOS(platform) {
+	case "ios":
+		return "Apple Inc.", "Apple GPU"
+	case "macos":
+		if webkit {
+			return "Apple Inc.", "Apple GPU"
+		}
+		return fmt.Sprintf("Google Inc. (%s)", short),
+			fmt.Sprintf("ANGLE (%s, ANGLE Metal Renderer: %s, Unspecified Version)", short, gpu.Renderer)
+	case "windows":
+		return fmt.Sprintf("Google Inc. (%s)", short),
+			fmt.Sprintf("ANGLE (%s, %s Direct3D11 vs_5_0 ps_5_0, D3D11)", short, gpu.Renderer)
+	case "android":
+		return fmt.Sprintf("Google Inc. (%s)", short),
+			fmt.Sprintf("ANGLE (%s, %s, OpenGL ES 3.2)", short, gpu.Renderer)
+	default:
+		return fmt.Sprintf("Google Inc. (%s)", short),
+			fmt.Sprintf("ANGLE (%s, %s, OpenGL 4.6)", short, gpu.Renderer)
+	}
+}
@@ -0,0 +1,54 @@
+package fingerprint
+
+import "strings"
+
+// WebGPUCaps возможности адаптера WebGPU видеокарты
+type WebGPUCaps struct {
+	Features              []string
+	Limits                map[string]int64
+	PreferredCanvasFormat string
+}
+
+// adapter возвращает WebGPU с адаптером vendor/architecture.
+// Списки и карты копируются, чтобы изменения fingerprint не затрагивали
+// базу устройств.
+func (c *WebGPUCaps) adapter(vendor, architecture string) *WebGPU {
+	w := &WebGPU{
+		Vendor:                vendor,
+		Architecture:          architecture,
+		Features:              append([]string(nil), c.Features...),
+		Limits:                make(map[string]int64, len(c.Limits)),
+		PreferredCanvasFormat: c.PreferredCanvasFormat,
+	}
+	for name, value := range c.Limits {
+		w.Limits[name] = value
+	}
+	return w
+}
+
+// webgpuVendor возвращает GPUAdapterInfo.vendor для производителя GPUSpec
+func webgpuVendor(vendor string) string {
+	if short := gpuVendorNames[vendor]; short != "" {
+		vendor = short
+	}
+	return strings.ToLower(vendor)
+}
+
+// webgpuSupported сообщает, есть ли navigator.gpu в версии браузера
+// на платформе. Chrome включил WebGPU в 113, на Android в 121; все
+// браузеры на iOS используют WebKit без WebGPU до Safari 26.
+func webgpuSupported(browser string, major int, platform string) bool {
+	os := platformOS(platform)
+	switch browser {
+	case "Chrome":
+		if os == "android" {
+			return major >= 121
+		}
+		return os != "ios" && major >= 113
+	case "Firefox":
+		return os == "windows" && major >= 141
+	case "Safari":
+		return major >= 26
+	}
+	return false
+}
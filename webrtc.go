@@ -0,0 +1,36 @@
+package fingerprint
+
+// Режимы WebRTC
+const (
+	// WebRTCModeDisable удаляет RTCPeerConnection
+	WebRTCModeDisable = "disable"
+	// WebRTCModeMask заменяет адреса ICE-кандидатов и SDP: host - на
+	// LocalIP или имя mDNS, srflx - на PublicIP
+	WebRTCModeMask = "mask"
+	// WebRTCModeProxyOnly оставляет только relay-кандидаты (TURN),
+	// адреса в них маскируются так же, как в WebRTCModeMask
+	WebRTCModeProxyOnly = "proxy-only"
+)
+
+// mode возвращает режим WebRTC; Disable без Mode означает WebRTCModeDisable
+func (w *WebRTC) mode() string {
+	if w == nil {
+		return ""
+	}
+	if w.Mode == "" && w.Disable {
+		return WebRTCModeDisable
+	}
+	return w.Mode
+}
+
+// ipHandlingPolicy возвращает значение флага Chrome
+// --force-webrtc-ip-handling-policy для режима
+func (w *WebRTC) ipHandlingPolicy() string {
+	switch w.mode() {
+	case WebRTCModeDisable:
+		return "disable_non_proxied_udp"
+	case WebRTCModeMask, WebRTCModeProxyOnly:
+		return "default_public_interface_only"
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package fingerprint
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// browserToolbarHeight возвращает высоту панелей браузера над страницей:
+// вкладки и адресная строка на компьютере, адресная строка и нижняя
+// панель на телефоне
+func browserToolbarHeight(browserName, platform string) int {
+	switch os := platformOS(platform); {
+	case os == "ios":
+		return 130
+	case os == "android":
+		return 56
+	case browserName == "Firefox":
+		return 85
+	case browserName == "Safari":
+		return 78
+	case os == "macos":
+		return 79
+	}
+	return 71
+}
+
+// browserScrollbarWidth возвращает ширину полосы прокрутки. На macOS
+// и телефонах полосы прокрутки рисуются поверх страницы.
+func browserScrollbarWidth(platform string) int {
+	switch platformOS(platform) {
+	case "windows":
+		return 17
+	case "linux":
+		return 15
+	}
+	return 0
+}
+
+// availTop возвращает отступ доступной области от верха экрана: строка
+// меню macOS и верхняя панель GNOME, в отличие от панели задач Windows,
+// находятся сверху
+func availTop(s *Screen, platform string) int {
+	switch platformOS(platform) {
+	case "macos", "linux":
+		return s.Height - s.AvailHeight
+	}
+	return 0
+}
+
+// newWindow возвращает окно, развернутое на всю доступную область экрана
+// (на телефоне - на весь экран)
+func newWindow(s *Screen, browserName, platform string) *Window {
+	return &Window{
+		InnerWidth:     s.AvailWidth,
+		InnerHeight:    s.AvailHeight - browserToolbarHeight(browserName, platform),
+		OuterWidth:     s.AvailWidth,
+		OuterHeight:    s.AvailHeight,
+		ScreenY:        availTop(s, platform),
+		ScrollbarWidth: browserScrollbarWidth(platform),
+	}
+}
+
+// viewport возвращает размер viewport: внутренний размер окна или,
+// без Window, размер экрана
+func (f *Fingerprint) viewport() (int, int) {
+	if f.Window != nil {
+		return f.Window.InnerWidth, f.Window.InnerHeight
+	}
+	return f.Screen.Width, f.Screen.Height
+}
+
+// windowBoundsParams параметры Browser.setWindowBounds. В cdproto нулевые
+// left и top не передаются, и окно осталось бы на прежнем месте.
+type windowBoundsParams struct {
+	WindowID browser.WindowID `json:"windowId"`
+	Bounds   windowBounds     `json:"bounds"`
+}
+
+type windowBounds struct {
+	Left        int64               `json:"left"`
+	Top         int64               `json:"top"`
+	Width       int64               `json:"width"`
+	Height      int64               `json:"height"`
+	WindowState browser.WindowState `json:"windowState"`
+}
+
+// SetWindowBounds задает положение и внешний размер окна браузера через
+// Browser.setWindowBounds. Окно общее для всех вкладок, поэтому действие
+// не повторяется для целей auto-attach.
+func (inj *Injector) SetWindowBounds(ctx context.Context) chromedp.Action {
+	fp := inj.Fingerprint()
+	w := fp.Window
+	if w == nil || fp.Screen == nil || inj.isMobileDevice() {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		windowID, _, err := browser.GetWindowForTarget().Do(ctx)
+		if err != nil {
+			return err
+		}
+		// Развернутое окно не меняет размер, пока не станет обычным
+		if err := browser.SetWindowBounds(windowID, &browser.Bounds{WindowState: browser.WindowStateNormal}).Do(ctx); err != nil {
+			return err
+		}
+		return cdp.Execute(ctx, browser.CommandSetWindowBounds, &windowBoundsParams{
+			WindowID: windowID,
+			Bounds: windowBounds{
+				Left:        int64(w.ScreenX),
+				Top:         int64(w.ScreenY),
+				Width:       int64(w.OuterWidth),
+				Height:      int64(w.OuterHeight),
+				WindowState: browser.WindowStateNormal,
+			},
+		}, nil)
+	})
+}
+
+// rotate меняет местами внешние ширину и высоту окна при повороте
+// экрана. Панели браузера и рамка остаются прежними, поэтому viewport
+// получает оставшееся место.
+func (w *Window) rotate() {
+	frame := w.OuterWidth - w.InnerWidth
+	toolbar := w.OuterHeight - w.InnerHeight
+	w.OuterWidth, w.OuterHeight = w.OuterHeight, w.OuterWidth
+	w.InnerWidth = w.OuterWidth - frame
+	w.InnerHeight = w.OuterHeight - toolbar
+}